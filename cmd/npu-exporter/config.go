@@ -0,0 +1,460 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package main
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	"huawei.com/npu-exporter/v5/collector"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/limiter"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+const (
+	configFlag = "config"
+	// configEnvPrefix the prefix of the environment variables, eg: NPU_EXPORTER_LIMIT_IP_REQ overrides limitIPReq
+	configEnvPrefix = "NPU_EXPORTER_"
+	// maxConfigFileSize the max size of the config file, in megabytes
+	maxConfigFileSize = 1
+	oneMegabytes      = 1024 * 1024
+
+	sourceCmdLine = "command line"
+	sourceEnv     = "environment"
+	sourceFile    = "config file"
+	sourceDefault = "default"
+)
+
+// unconfigurableOptions the options which are only accepted from the command line
+var unconfigurableOptions = map[string]bool{configFlag: true, "version": true, "hashPasswordFor": true,
+	"hashToken": true}
+
+// reloadableOptions the options which take effect without restart when the config file changes
+var reloadableOptions = []string{"logLevel", "updateTime", "staticInterval", "fastInterval", "networkInterval",
+	"containerInterval", "limitIPReq"}
+
+var (
+	configFile string
+	labels     string
+	// optionSources where the value of each set option comes from
+	optionSources = map[string]string{}
+	// cmdLineOptions the options which are set on the command line, they are never overridden
+	cmdLineOptions = map[string]bool{}
+)
+
+// configError the invalid value of an option
+type configError struct {
+	Option string
+	Value  string
+	Reason string
+	// Source where the value comes from, eg: the config file
+	Source string
+}
+
+// Error implements error
+func (e *configError) Error() string {
+	return fmt.Sprintf("option %s=%q (%s): %s", e.Option, e.Value, e.Source, e.Reason)
+}
+
+// configErrors all the invalid options, so that they can be fixed at once
+type configErrors []*configError
+
+// Error implements error
+func (e configErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add the invalid option, whose source is recorded by loadConfig
+func (e *configErrors) add(option string, value interface{}, reason string) {
+	source, ok := optionSources[option]
+	if !ok {
+		source = sourceDefault
+	}
+	*e = append(*e, &configError{Option: option, Value: fmt.Sprint(value), Reason: reason, Source: source})
+}
+
+func (e configErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// envName convert the option name to the environment variable name, eg: limitIPReq to NPU_EXPORTER_LIMIT_IP_REQ
+func envName(option string) string {
+	runes := []rune(option)
+	var builder strings.Builder
+	builder.WriteString(configEnvPrefix)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (!unicode.IsUpper(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			builder.WriteByte('_')
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+	return builder.String()
+}
+
+// loadConfig set the options which are not given on the command line, the environment variables take precedence
+// over the config file, and the config file takes precedence over the defaults
+func loadConfig(fs *flag.FlagSet) error {
+	fs.Visit(func(f *flag.Flag) {
+		cmdLineOptions[f.Name] = true
+		optionSources[f.Name] = sourceCmdLine
+	})
+	if path, ok := os.LookupEnv(envName(configFlag)); ok && !cmdLineOptions[configFlag] {
+		configFile = path
+	}
+	values, sources, err := readOptions(fs)
+	if err != nil {
+		return err
+	}
+	var errs configErrors
+	for name, value := range values {
+		optionSources[name] = sources[name]
+		if err = fs.Set(name, value); err != nil {
+			errs.add(name, value, err.Error())
+		}
+	}
+	return errs.err()
+}
+
+// readOptions read the options of the config file and the environment variables, the options which are set on
+// the command line are skipped
+func readOptions(fs *flag.FlagSet) (map[string]string, map[string]string, error) {
+	values, sources := make(map[string]string), make(map[string]string)
+	if configFile != "" {
+		fileValues, err := readConfigFile(fs, configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, value := range fileValues {
+			values[name], sources[name] = value, sourceFile
+		}
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if value, ok := os.LookupEnv(envName(f.Name)); ok && !unconfigurableOptions[f.Name] {
+			values[f.Name], sources[f.Name] = value, sourceEnv
+		}
+	})
+	for name := range cmdLineOptions {
+		delete(values, name)
+		delete(sources, name)
+	}
+	return values, sources, nil
+}
+
+// readConfigFile read the yaml config file whose keys are the option names. The list value is joined by comma and
+// the map value is joined as k=v pairs, eg: the metricGroups list and the labels map
+func readConfigFile(fs *flag.FlagSet, path string) (map[string]string, error) {
+	absPath, err := utils.RealFileChecker(path, true, true, maxConfigFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("check config file failed: %v", err)
+	}
+	// the file mounted from the kubernetes configmap is a symlink
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve config file failed: %v", err)
+	}
+	data, err := utils.ReadLimitBytes(realPath, maxConfigFileSize*oneMegabytes)
+	if err != nil {
+		return nil, fmt.Errorf("read config file failed: %v", err)
+	}
+	var raw map[string]interface{}
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal config file failed: %v", err)
+	}
+	var errs configErrors
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if fs.Lookup(name) == nil {
+			errs = append(errs, &configError{Option: name, Value: fmt.Sprint(value), Reason: "unknown option",
+				Source: sourceFile})
+			continue
+		}
+		if unconfigurableOptions[name] {
+			errs = append(errs, &configError{Option: name, Value: fmt.Sprint(value),
+				Reason: "only supported on the command line", Source: sourceFile})
+			continue
+		}
+		values[name] = optionString(value)
+	}
+	if err = errs.err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func optionString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, item))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseLabels parse the comma separated k=v pairs of the constant labels
+func parseLabels(s string) (prometheus.Labels, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	parsed := make(prometheus.Labels)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("the label %q is not a k=v pair", pair)
+		}
+		if !model.LabelName(kv[0]).IsValid() || strings.HasPrefix(kv[0], model.ReservedLabelPrefix) {
+			return nil, fmt.Errorf("the label name %q is invalid", kv[0])
+		}
+		if _, ok := parsed[kv[0]]; ok {
+			return nil, fmt.Errorf("the label %q is duplicated", kv[0])
+		}
+		parsed[kv[0]] = kv[1]
+	}
+	return parsed, nil
+}
+
+// reloadableConfig the options which take effect without restart
+type reloadableConfig struct {
+	logLevel   int
+	updateTime int
+	intervals  collectIntervals
+	limitIPReq string
+}
+
+func currentReloadableConfig() reloadableConfig {
+	return reloadableConfig{logLevel: hwLogConfig.LogLevel, updateTime: updateTime, intervals: intervals,
+		limitIPReq: limitIPReq}
+}
+
+func (r *reloadableConfig) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	fs.IntVar(&r.logLevel, "logLevel", 0, "")
+	fs.IntVar(&r.updateTime, "updateTime", 0, "")
+	fs.IntVar(&r.intervals.static, "staticInterval", 0, "")
+	fs.IntVar(&r.intervals.fast, "fastInterval", 0, "")
+	fs.IntVar(&r.intervals.network, "networkInterval", 0, "")
+	fs.IntVar(&r.intervals.container, "containerInterval", 0, "")
+	fs.StringVar(&r.limitIPReq, "limitIPReq", "", "")
+	return fs
+}
+
+func (r reloadableConfig) validate(errs *configErrors) {
+	if r.logLevel < -1 || r.logLevel > 3 {
+		errs.add("logLevel", r.logLevel, "the range is [-1, 3]")
+	}
+	if r.updateTime > oneMinute || r.updateTime < 1 {
+		errs.add("updateTime", r.updateTime, "the range is [1, 60]")
+	}
+	r.intervals.validate(errs)
+	if maxDataAge != 0 && (maxDataAge <= r.intervals.longest(r.updateTime) || maxDataAge > oneHour) {
+		errs.add("maxDataAge", maxDataAge, "it should be longer than the collect intervals and not exceed 3600")
+	}
+	if !regexp.MustCompile(limiter.IPReqLimitReg).MatchString(r.limitIPReq) {
+		errs.add("limitIPReq", r.limitIPReq, "the format should be like 20/1")
+	}
+}
+
+// reloadConfig read the reloadable options again, the options which are set on the command line are kept. The
+// changed options which need restart are returned as well
+func reloadConfig(previous map[string]string) (reloadableConfig, map[string]string, []string, error) {
+	var reloaded reloadableConfig
+	fs := reloaded.flagSet()
+	values, _, err := readOptions(flag.CommandLine)
+	if err != nil {
+		return reloaded, nil, nil, err
+	}
+	var errs configErrors
+	for _, name := range reloadableOptions {
+		value := flag.Lookup(name).DefValue
+		if cmdLineOptions[name] {
+			value = flag.Lookup(name).Value.String()
+		} else if configured, ok := values[name]; ok {
+			value = configured
+		}
+		if err = fs.Set(name, value); err != nil {
+			errs.add(name, value, err.Error())
+		}
+	}
+	reloaded.validate(&errs)
+	if err = errs.err(); err != nil {
+		return reloaded, nil, nil, err
+	}
+	reloadable := make(map[string]bool, len(reloadableOptions))
+	for _, name := range reloadableOptions {
+		reloadable[name] = true
+	}
+	var needRestart []string
+	for _, name := range changedOptions(previous, values) {
+		if !reloadable[name] {
+			needRestart = append(needRestart, name)
+		}
+	}
+	return reloaded, values, needRestart, nil
+}
+
+func changedOptions(previous, current map[string]string) []string {
+	var changed []string
+	for name, value := range current {
+		if old, ok := previous[name]; !ok || old != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// configReloader apply the changed reloadable options to the running exporter
+type configReloader struct {
+	current   reloadableConfig
+	values    map[string]string
+	collector collector.ManagedCollector
+	limiter   limiter.ReloadableHandler
+}
+
+func (c *configReloader) reload() {
+	reloaded, values, needRestart, err := reloadConfig(c.values)
+	if err != nil {
+		hwlog.RunLog.Warnf("reload the config file failed, keep the current options: %v", err)
+		return
+	}
+	c.values = values
+	if len(needRestart) != 0 {
+		hwlog.RunLog.Warnf("the changed options %v take effect after restart", needRestart)
+	}
+	if reloaded == c.current {
+		return
+	}
+	if reloaded.logLevel != c.current.logLevel {
+		c.setLogLevel(reloaded.logLevel)
+	}
+	if reloaded.updateTime != c.current.updateTime || reloaded.intervals != c.current.intervals {
+		c.collector.SetIntervals(reloaded.intervals.toCollector(), time.Duration(reloaded.updateTime)*time.Second)
+	}
+	if reloaded.limitIPReq != c.current.limitIPReq && c.limiter != nil {
+		if err = c.limiter.SetIPConCurrency(reloaded.limitIPReq); err != nil {
+			hwlog.RunLog.Warnf("reload the limitIPReq failed: %v", err)
+			reloaded.limitIPReq = c.current.limitIPReq
+		} else {
+			hwlog.RunLog.Infof("the limitIPReq is changed to %s", reloaded.limitIPReq)
+		}
+	}
+	c.current = reloaded
+}
+
+func (c *configReloader) setLogLevel(level int) {
+	if err := hwlog.RunLog.SetLevel(level); err != nil {
+		hwlog.RunLog.Warnf("reload the logLevel failed: %v", err)
+		return
+	}
+	if hwlog.SecLog != nil {
+		if err := hwlog.SecLog.SetLevel(level); err != nil {
+			hwlog.RunLog.Warnf("reload the logLevel of the security log failed: %v", err)
+		}
+	}
+	hwlog.RunLog.Infof("the logLevel is changed to %d", level)
+}
+
+// watchConfig reload the config file when it changes until the ctx is done, the directory is watched so that the
+// configmap which is updated by replacing the symlink is reloaded as well
+func watchConfig(ctx context.Context, c collector.ManagedCollector, handler interface{}) error {
+	if configFile == "" {
+		return nil
+	}
+	values, _, err := readOptions(flag.CommandLine)
+	if err != nil {
+		return err
+	}
+	reloader := &configReloader{current: currentReloadableConfig(), values: values, collector: c}
+	if h, ok := handler.(limiter.ReloadableHandler); ok {
+		reloader.limiter = h
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create the config watcher failed: %v", err)
+	}
+	if err = watcher.Add(filepath.Dir(configFile)); err != nil {
+		closeConfigWatcher(watcher)
+		return fmt.Errorf("watch the config directory failed: %v", err)
+	}
+	go func() {
+		defer closeConfigWatcher(watcher)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				reloader.reload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				hwlog.RunLog.Warnf("the config watcher failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func closeConfigWatcher(watcher *fsnotify.Watcher) {
+	if err := watcher.Close(); err != nil {
+		hwlog.RunLog.Warnf("close the config watcher failed: %v", err)
+	}
+}
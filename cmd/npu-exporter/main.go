@@ -16,46 +16,76 @@
 package main
 
 import (
+	"bufio"
 	"context"
-	"errors"
+	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"regexp"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/influxdata/telegraf/plugins/common/shim"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"huawei.com/npu-exporter/v5/collector"
 	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/collector/otlp"
+	"huawei.com/npu-exporter/v5/common-utils/auth"
 	"huawei.com/npu-exporter/v5/common-utils/hwlog"
 	"huawei.com/npu-exporter/v5/common-utils/limiter"
+	"huawei.com/npu-exporter/v5/common-utils/remotewrite"
+	"huawei.com/npu-exporter/v5/common-utils/textfile"
+	"huawei.com/npu-exporter/v5/common-utils/tlsconfig"
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/faultcode"
 	_ "huawei.com/npu-exporter/v5/plugins/inputs/npu"
 	"huawei.com/npu-exporter/v5/versions"
 )
 
 var (
-	port           int
-	updateTime     int
-	ip             string
-	version        bool
-	concurrency    int
-	containerMode  string
-	containerd     string
-	endpoint       string
-	limitIPReq     string
-	platform       string
-	limitIPConn    int
-	limitTotalConn int
-	cacheSize      int
-	pollInterval   time.Duration
+	port             int
+	updateTime       int
+	intervals        collectIntervals
+	ip               string
+	version          bool
+	concurrency      int
+	containerMode    string
+	containerd       string
+	endpoint         string
+	limitIPReq       string
+	platform         string
+	limitIPConn      int
+	limitTotalConn   int
+	cacheSize        int
+	pollInterval     time.Duration
+	faultCodeFile    string
+	faultJournalFile string
+	metricGroups     string
+	queryErrorMode   string
+	maxDataAge       int
+	driverTimeout    int
+	collectWorkers   int
+	tlsCertFile      string
+	tlsKeyFile       string
+	tlsClientCAFile  string
+	tlsMinVersion    string
+	tlsCipherSuites  string
+	authFile         string
+	hashPasswordFor  string
+	hashToken        bool
+	legacyStatGauges bool
+	metricNaming     string
 )
 
 const (
@@ -65,30 +95,122 @@ const (
 	portLeft                = 1025
 	portRight               = 40000
 	oneMinute               = 60
+	oneHour                 = 3600
+	staticIntervalConst     = 300
+	driverTimeoutConst      = 5
+	maxCollectWorkers       = 64
 	defaultConcurrency      = 5
 	defaultLogFile          = "/var/log/mindx-dl/npu-exporter/npu-exporter.log"
+	defaultSecLogFile       = "/var/log/mindx-dl/npu-exporter/npu-exporter-security.log"
 	containerModeDocker     = "docker"
 	containerModeContainerd = "containerd"
 	containerModeIsula      = "isula"
 	unixPre                 = "unix://"
 	timeout                 = 10
 	maxHeaderBytes          = 1024
+	healthzPath             = "/healthz"
 	// tenDays ten days
 	tenDays           = 10
 	maxIPConnLimit    = 128
 	maxConcurrency    = 512
 	defaultConnection = 20
+	selfMetricPrefix  = "npu_exporter_"
+	// shutdownTimeout the deadline of the http server shutdown and of stopping the collector
+	shutdownTimeout = 10 * time.Second
 )
 
+// collectIntervals the intervals of the data sources in seconds, 0 means to use the updateTime
+type collectIntervals struct {
+	static    int
+	fast      int
+	network   int
+	container int
+}
+
+func (c collectIntervals) toCollector() collector.CollectIntervals {
+	return collector.CollectIntervals{
+		Static:    time.Duration(c.static) * time.Second,
+		Fast:      time.Duration(c.fast) * time.Second,
+		Network:   time.Duration(c.network) * time.Second,
+		Container: time.Duration(c.container) * time.Second,
+	}
+}
+
+func (c collectIntervals) validate(errs *configErrors) {
+	if c.static != 0 && (c.static < oneMinute || c.static > oneHour) {
+		errs.add("staticInterval", c.static, "the range is [60, 3600]")
+	}
+	for name, interval := range map[string]int{"fastInterval": c.fast, "networkInterval": c.network,
+		"containerInterval": c.container} {
+		if interval < 0 || interval > oneMinute {
+			errs.add(name, interval, "the range is [1, 60], or 0 to use the updateTime")
+		}
+	}
+}
+
+// longest get the longest interval of the polled data sources, the zero interval falls back to the updateTime
+func (c collectIntervals) longest(updateTime int) int {
+	longest := updateTime
+	for _, interval := range []int{c.fast, c.network, c.container} {
+		if interval > longest {
+			longest = interval
+		}
+	}
+	return longest
+}
+
 const (
 	prometheusPlatform  = "Prometheus"
 	telegrafPlatform    = "Telegraf"
 	pollIntervalStr     = "poll_interval"
+	queryErrorModeNaN   = "nan"
+	queryErrorModeOmit  = "omit"
 	maxTelegrafParamLen = 2
 	minTelegrafParamLen = 1
 	maxLogLineLength    = 1024
 )
 
+// telegrafOptions the options which are supported in Telegraf
+var telegrafOptions = map[string]bool{"platform": true, pollIntervalStr: true, configFlag: true}
+
+// serverOptions the options of the http server, which are only supported in Prometheus
+var serverOptions = []string{"port", "ip", "concurrency", "limitIPConn", "limitTotalConn", "limitIPReq",
+	"cacheSize", "tlsCertFile", "tlsKeyFile", "tlsClientCAFile", "tlsMinVersion", "tlsCipherSuites", "authFile"}
+
+// platformOptions the options which are only supported in each platform, in the order of the errors
+var platformOptions = []struct {
+	platform string
+	options  []string
+}{
+	{platform: prometheusPlatform, options: serverOptions},
+	{platform: remoteWritePlatform, options: remoteWriteOptions},
+	{platform: otlpPlatform, options: otlpOptions},
+	{platform: textfilePlatform, options: textfileOptions},
+}
+
+// rejectOtherPlatformOptions reject the options of the other platforms which are set
+func rejectOtherPlatformOptions(errs *configErrors, current string) {
+	for _, other := range platformOptions {
+		if other.platform != current {
+			rejectOptions(errs, other.options, other.platform)
+		}
+	}
+}
+
+// rejectOptions reject the options which are set but not supported on the platform
+func rejectOptions(errs *configErrors, options []string, platformName string) {
+	for _, option := range options {
+		if _, ok := optionSources[option]; !ok {
+			continue
+		}
+		value := ""
+		if f := flag.Lookup(option); f != nil {
+			value = f.Value.String()
+		}
+		errs.add(option, value, "only supported in "+platformName)
+	}
+}
+
 var hwLogConfig = &hwlog.LogConfig{LogFileName: defaultLogFile, ExpiredTime: hwlog.DefaultExpiredTime,
 	CacheSize: hwlog.DefaultCacheSize, MaxLineLength: maxLogLineLength}
 
@@ -98,12 +220,29 @@ func main() {
 		fmt.Printf("NPU-exporter version: %s \n", versions.BuildVersion)
 		return
 	}
+	if hashPasswordFor != "" || hashToken {
+		if err := hashCredential(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Err hash credential: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := loadConfig(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Err config: %s\n", err)
+		os.Exit(1)
+	}
 
 	switch platform {
 	case prometheusPlatform:
 		prometheusProcess()
 	case telegrafPlatform:
 		telegrafProcess()
+	case remoteWritePlatform:
+		remoteWriteProcess()
+	case otlpPlatform:
+		otlpProcess()
+	case textfilePlatform:
+		textfileProcess()
 	default:
 		fmt.Fprintf(os.Stderr, "err platform input")
 		os.Exit(1)
@@ -122,8 +261,69 @@ func initConfig() *limiter.HandlerConfig {
 	return conf
 }
 
-func newServerAndListener(conf *limiter.HandlerConfig) (*http.Server, net.Listener) {
-	handler, err := limiter.NewLimitHandlerV2(http.DefaultServeMux, conf)
+// hashCredential read the password of the user or the token from the reader, and print the hashed credential
+// which can be put into the authFile
+func hashCredential(r io.Reader) error {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	secret := []byte(strings.TrimRight(line, "\r\n"))
+	var credential interface{}
+	if hashToken {
+		tokenHash, err := auth.HashToken(secret)
+		if err != nil {
+			return err
+		}
+		credential = auth.Credentials{Tokens: []string{tokenHash}}
+	} else {
+		passwordHash, err := auth.HashPassword(hashPasswordFor, secret)
+		if err != nil {
+			return err
+		}
+		credential = auth.Credentials{Users: []auth.User{{Name: hashPasswordFor, PasswordHash: passwordHash}}}
+	}
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// newAuthHandler authenticate the requests when the authFile is given, the failures are recorded in the security
+// log
+func newAuthHandler(handler http.Handler) (http.Handler, error) {
+	if authFile == "" {
+		return handler, nil
+	}
+	secLogConfig := *hwLogConfig
+	secLogConfig.LogFileName = defaultSecLogFile
+	if err := hwlog.InitSecurityLogger(&secLogConfig, context.Background()); err != nil {
+		return nil, fmt.Errorf("init security logger failed: %v", err)
+	}
+	credentials, err := auth.LoadCredentials(authFile)
+	if err != nil {
+		return nil, err
+	}
+	hwlog.RunLog.Infof("enable authentication with %d users and %d tokens", len(credentials.Users),
+		len(credentials.Tokens))
+	return auth.NewAuthHandler(handler, &auth.HandlerConfig{Credentials: credentials})
+}
+
+// newServerAndListener create the server whose requests are limited, the probes are not authenticated since the
+// kubelet does not carry the credentials
+func newServerAndListener(conf *limiter.HandlerConfig, readiness http.Handler) (*http.Server, net.Listener) {
+	authHandler, err := newAuthHandler(http.DefaultServeMux)
+	if err != nil {
+		hwlog.RunLog.Errorf("enable authentication failed: %v", err)
+		return nil, nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(healthzPath, http.HandlerFunc(healthzHandler))
+	mux.Handle(collector.ReadyzPath, readiness)
+	mux.Handle("/", authHandler)
+	handler, err := limiter.NewLimitHandlerV2(mux, conf)
 	if err != nil {
 		hwlog.RunLog.Error(err)
 		return nil, nil
@@ -181,63 +381,147 @@ func readCntMonitoringFlags() container.CntNpuMonitorOpts {
 	return opts
 }
 
-func regPrometheus(opts container.CntNpuMonitorOpts) (*prometheus.Registry, error) {
+func regPrometheus(ctx context.Context, opts container.CntNpuMonitorOpts,
+	journal *collector.FaultJournal) (*prometheus.Registry, collector.ManagedCollector, error) {
 	deviceParser := container.MakeDevicesParser(opts)
 	reg := prometheus.NewRegistry()
-	c, err := collector.NewNpuCollector(context.Background(), cacheTime, time.Duration(updateTime)*time.Second,
-		deviceParser)
+	groups, err := collector.ParseMetricGroups(metricGroups)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	naming, err := collector.ParseMetricNaming(metricNaming)
+	if err != nil {
+		return nil, nil, err
+	}
+	collectorOpts := collector.NpuCollectorOpts{
+		CacheTime:        cacheTime,
+		UpdateTime:       time.Duration(updateTime) * time.Second,
+		FaultJournal:     journal,
+		Intervals:        intervals.toCollector(),
+		OmitFailedValues: queryErrorMode == queryErrorModeOmit,
+		MaxAge:           time.Duration(maxDataAge) * time.Second,
+		CollectWorkers:   collectWorkers,
+		DriverGuard:      devmanager.GuardOpts{CallTimeout: time.Duration(driverTimeout) * time.Second},
+		MetricGroups:     groups,
+		Naming:           naming,
+	}
+	collector.SetLegacyStatGauges(legacyStatGauges)
+	c, err := collector.NewNpuCollector(ctx, deviceParser, collectorOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	constLabels, err := parseLabels(labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = prometheus.WrapRegistererWith(constLabels, reg).Register(c); err != nil {
+		return nil, nil, err
 	}
-	reg.MustRegister(c)
-	return reg, nil
+	// the goroutine, memory and process stats of the exporter itself
+	prometheus.WrapRegistererWithPrefix(selfMetricPrefix, reg).MustRegister(collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return reg, c, nil
 }
 
 func paramValidInPrometheus() error {
+	var errs configErrors
 	if port < portLeft || port > portRight {
-		return errors.New("the port is invalid")
+		errs.add("port", port, "the range is [1025, 40000]")
 	}
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return errors.New("the listen ip is invalid")
-	}
-	ip = parsedIP.String()
-	hwlog.RunLog.Infof("listen on: %s", ip)
-	if updateTime > oneMinute || updateTime < 1 {
-		return errors.New("the updateTime is invalid")
-	}
-	if err := containerSockCheck(); err != nil {
-		return err
-	}
-	reg := regexp.MustCompile(limiter.IPReqLimitReg)
-	if !reg.Match([]byte(limitIPReq)) {
-		return errors.New("limitIPReq format error")
+	if parsedIP := net.ParseIP(ip); parsedIP != nil {
+		ip = parsedIP.String()
+		hwlog.RunLog.Infof("listen on: %s", ip)
+	} else {
+		errs.add("ip", ip, "not a valid ip")
 	}
+	collectorParamValid(&errs)
+	rejectOtherPlatformOptions(&errs, prometheusPlatform)
+	tlsParamValid(&errs)
 	if limitIPConn < 1 || limitIPConn > maxIPConnLimit {
-		return errors.New("limitIPConn is invalid")
+		errs.add("limitIPConn", limitIPConn, "the range is [1, 128]")
 	}
 	if limitTotalConn < 1 || limitTotalConn > maxConcurrency {
-		return errors.New("limitTotalConn is invalid")
+		errs.add("limitTotalConn", limitTotalConn, "the range is [1, 512]")
 	}
 	if cacheSize < 1 || cacheSize > limiter.DefaultCacheSize*tenDays {
-		return errors.New("cacheSize is invalid")
+		errs.add("cacheSize", cacheSize, "the range is [1, 1024000]")
 	}
 	if concurrency < 1 || concurrency > maxConcurrency {
-		return errors.New("concurrency is invalid")
+		errs.add("concurrency", concurrency, "the range is [1, 512]")
 	}
-	cmdLine := strings.Join(os.Args[1:], "")
-	if strings.Contains(cmdLine, pollIntervalStr) {
-		return fmt.Errorf("%s is not support this scene", pollIntervalStr)
+	return errs.err()
+}
+
+// collectorParamValid check the options of the npu collector, which are shared by all the platforms except Telegraf
+func collectorParamValid(errs *configErrors) {
+	currentReloadableConfig().validate(errs)
+	if collectWorkers < 1 || collectWorkers > maxCollectWorkers {
+		errs.add("collectWorkers", collectWorkers, "the range is [1, 64]")
+	}
+	if driverTimeout < 1 || driverTimeout > oneMinute {
+		errs.add("driverTimeout", driverTimeout, "the range is [1, 60]")
+	}
+	if queryErrorMode != queryErrorModeNaN && queryErrorMode != queryErrorModeOmit {
+		errs.add("queryErrorMode", queryErrorMode, "only support nan and omit")
+	}
+	if _, err := collector.ParseMetricGroups(metricGroups); err != nil {
+		errs.add("metricGroups", metricGroups, err.Error())
+	}
+	if _, err := collector.ParseMetricNaming(metricNaming); err != nil {
+		errs.add("metricNaming", metricNaming, err.Error())
+	}
+	if _, err := parseLabels(labels); err != nil {
+		errs.add("labels", labels, err.Error())
+	}
+	containerSockCheck(errs)
+	if _, ok := optionSources[pollIntervalStr]; ok {
+		errs.add(pollIntervalStr, pollInterval, "only supported in Telegraf")
 	}
-	return nil
 }
 
-func containerSockCheck() error {
+func tlsParamValid(errs *configErrors) {
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		errs.add("tlsKeyFile", tlsKeyFile, "the tlsCertFile and tlsKeyFile should be given together")
+	}
+	if tlsCertFile == "" && tlsClientCAFile != "" {
+		errs.add("tlsClientCAFile", tlsClientCAFile, "the tlsCertFile and tlsKeyFile are needed")
+	}
+	if _, err := tlsconfig.ParseMinVersion(tlsMinVersion); err != nil {
+		errs.add("tlsMinVersion", tlsMinVersion, err.Error())
+	}
+	if _, err := tlsconfig.ParseCipherSuites(tlsCipherSuites); err != nil {
+		errs.add("tlsCipherSuites", tlsCipherSuites, err.Error())
+	}
+}
+
+// newTLSListener serve tls on the listener, the certificates are reloaded when the files change until the ctx
+// is done
+func newTLSListener(ctx context.Context, ln net.Listener) (net.Listener, error) {
+	minVersion, err := tlsconfig.ParseMinVersion(tlsMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := tlsconfig.ParseCipherSuites(tlsCipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	reloader, err := tlsconfig.NewReloader(tlsconfig.Options{CertFile: tlsCertFile, KeyFile: tlsKeyFile,
+		ClientCAFile: tlsClientCAFile, MinVersion: minVersion, CipherSuites: cipherSuites})
+	if err != nil {
+		return nil, err
+	}
+	if err = reloader.Watch(ctx); err != nil {
+		hwlog.RunLog.Warnf("the certificates will not be reloaded: %v", err)
+	}
+	return tls.NewListener(ln, reloader.TLSConfig()), nil
+}
+
+func containerSockCheck(errs *configErrors) {
 	if endpoint != "" && !strings.Contains(endpoint, ".sock") {
-		return errors.New("endpoint file is not sock address")
+		errs.add("endpoint", endpoint, "not a sock address")
 	}
 	if containerd != "" && !strings.Contains(containerd, ".sock") {
-		return errors.New("containerd file is not sock address")
+		errs.add("containerd", containerd, "not a sock address")
 	}
 	if endpoint != "" && !strings.Contains(endpoint, unixPre) {
 		endpoint = unixPre + endpoint
@@ -245,7 +529,6 @@ func containerSockCheck() error {
 	if containerd != "" && !strings.Contains(containerd, unixPre) {
 		containerd = unixPre + containerd
 	}
-	return nil
 }
 
 func init() {
@@ -283,14 +566,104 @@ func init() {
 	flag.StringVar(&limitIPReq, "limitIPReq", "20/1",
 		"the http request limit counts for each Ip,20/1 means allow 20 request in 1 seconds")
 	flag.StringVar(&platform, "platform", "Prometheus", "the data reporting platform, "+
-		"just support Prometheus and Telegraf")
+		"just support Prometheus, Telegraf, RemoteWrite which pushes the metrics to the remoteWriteURL, OTLP "+
+		"which exports the metrics to the otlpEndpoint and Textfile which writes the metrics to the textfilePath "+
+		"for the textfile collector of node_exporter")
 	flag.DurationVar(&pollInterval, pollIntervalStr, 1*time.Second,
 		"how often to send metrics when use Telegraf plugin, "+
 			"needs to be used with -platform=Telegraf, otherwise, it does not take effect")
+	flag.StringVar(&faultCodeFile, "faultCodeFile", "",
+		"the JSON or YAML fault code file which overrides the built-in fault code knowledge base")
+	flag.StringVar(&faultJournalFile, "faultJournalFile", "",
+		"the file which persists the npu fault events, the events can be queried by "+collector.FaultJournalPath+
+			", default empty means not to persist the fault events")
+	flag.IntVar(&intervals.static, "staticInterval", staticIntervalConst,
+		"Interval (seconds) to re-discover the npu, the static info of the known npu is cached, range [60, 3600]")
+	flag.IntVar(&intervals.fast, "fastInterval", 0,
+		"Interval (seconds) to update the fast-telemetry such as utilization and power, range [1, 60], "+
+			"0 means to use the updateTime")
+	flag.IntVar(&intervals.network, "networkInterval", 0,
+		"Interval (seconds) to update the network and optical info, range [1, 60], 0 means to use the updateTime")
+	flag.IntVar(&intervals.container, "containerInterval", 0,
+		"Interval (seconds) to update the container info, range [1, 60], 0 means to use the updateTime")
+	flag.IntVar(&maxDataAge, "maxDataAge", 0,
+		"the max age (seconds) of the cached data, the metric groups whose data is not updated within the max age "+
+			"are dropped from the scrape, range (the longest collect interval, 3600], 0 means never drop them")
+	flag.IntVar(&collectWorkers, "collectWorkers", collector.DefaultCollectWorkers,
+		"the number of the workers which query the chips concurrently, range [1, 64]")
+	flag.IntVar(&driverTimeout, "driverTimeout", driverTimeoutConst,
+		"the deadline (seconds) of each driver call of a chip, the chip whose calls keep timing out is backed off "+
+			"so that the other chips keep reporting, range [1, 60]")
+	flag.StringVar(&queryErrorMode, "queryErrorMode", queryErrorModeNaN,
+		"how to export the metric whose device query failed, 'nan' exports it as NaN and 'omit' omits it")
+	flag.StringVar(&metricGroups, "metricGroups", strings.Join(collector.RegisteredMetricGroups(), ","),
+		"the comma separated metric groups to be collected, only support "+
+			strings.Join(collector.RegisteredMetricGroups(), ","))
+	flag.StringVar(&tlsCertFile, "tlsCertFile", "",
+		"the server certificate file, the https server is enabled when it is given with the tlsKeyFile, "+
+			"the certificate files are reloaded when they change")
+	flag.StringVar(&tlsKeyFile, "tlsKeyFile", "", "the server private key file")
+	flag.StringVar(&tlsClientCAFile, "tlsClientCAFile", "",
+		"the CA file which verifies the client certificates, the mutual tls is enabled when it is given")
+	flag.StringVar(&tlsMinVersion, "tlsMinVersion", tlsconfig.TLS12,
+		"the min tls version, only support "+tlsconfig.TLS12+" and "+tlsconfig.TLS13)
+	flag.StringVar(&tlsCipherSuites, "tlsCipherSuites", "",
+		"the comma separated cipher suites of TLS 1.2, eg: TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, "+
+			"empty means the forward secrecy AEAD suites")
+	flag.StringVar(&authFile, "authFile", "",
+		"the JSON credential file which contains the hashed users and tokens, the requests are authenticated by "+
+			"the basic auth or bearer token when it is given")
+	flag.StringVar(&hashPasswordFor, "hashPasswordFor", "",
+		"the user name whose password is read from stdin, eg: -hashPasswordFor=admin, "+
+			"print the hashed user for the authFile and exit")
+	flag.BoolVar(&hashToken, "hashToken", false,
+		"read the bearer token from stdin, print the hashed token for the authFile and exit")
+	flag.BoolVar(&legacyStatGauges, "legacyStatGauges", true,
+		"export the mac and roce statistics as the legacy *_num gauges besides the *_total counters, "+
+			"set false after the dashboards are migrated to the counters")
+	flag.StringVar(&metricNaming, "metricNaming", string(collector.NamingV1),
+		"the naming scheme of the metrics, 'v1' keeps the legacy names and units, 'v2' follows the prometheus "+
+			"convention with the base units, eg: _bytes, _celsius, _watts and _hertz, and 'dual' exports both "+
+			"so that the dashboards can be migrated gradually")
+	flag.StringVar(&configFile, configFlag, "",
+		"the YAML config file whose keys are the option names, the options on the command line and in the "+
+			configEnvPrefix+"<OPTION> environment variables take precedence. The logLevel, updateTime, "+
+			"the collect intervals and limitIPReq are reloaded when the file changes")
+	flag.StringVar(&labels, "labels", "",
+		"the comma separated constant labels which are added to the npu metrics, eg: cluster=a,zone=b")
+	flag.StringVar(&remoteWriteURL, "remoteWriteURL", "",
+		"the url of the prometheus remote write endpoint, needs to be used with -platform=RemoteWrite")
+	flag.IntVar(&remoteWriteInterval, "remoteWriteInterval", defaultRemoteWriteInterval,
+		"Interval (seconds) to push the metrics to the remote write endpoint, range [1, 3600]")
+	flag.IntVar(&remoteWriteTimeout, "remoteWriteTimeout", int(remotewrite.DefaultTimeout/time.Second),
+		"the deadline (seconds) of each push to the remote write endpoint, range [1, 60]")
+	flag.StringVar(&remoteWriteWALDir, "remoteWriteWALDir", defaultRemoteWriteWALDir,
+		"the directory which buffers the metrics when the remote write endpoint is unreachable, the buffered "+
+			"metrics are replayed in order when it recovers, empty means to drop them")
+	flag.IntVar(&remoteWriteWALSize, "remoteWriteWALSize", defaultRemoteWriteWALSize,
+		"the max size (megabytes) of the buffered metrics, the oldest ones are dropped when it is exceeded, "+
+			"range [1, 10240]")
+	flag.StringVar(&otlpEndpoint, "otlpEndpoint", "",
+		"the host:port of the OTLP gRPC receiver, or the url of the OTLP HTTP receiver whose default path is "+
+			"/v1/metrics, needs to be used with -platform=OTLP. Each chip is a resource, and the k8s.node.name "+
+			"attribute is read from the "+nodeNameEnv+" environment variable")
+	flag.StringVar(&otlpProtocol, "otlpProtocol", otlp.ProtocolGRPC,
+		"the protocol of the OTLP receiver, just support grpc and http/protobuf")
+	flag.BoolVar(&otlpInsecure, "otlpInsecure", false,
+		"export to the OTLP gRPC receiver without TLS, the HTTP receiver follows the scheme of the url")
+	flag.IntVar(&otlpTimeout, "otlpTimeout", int(otlp.DefaultTimeout/time.Second),
+		"the deadline (seconds) of each export to the OTLP receiver, range [1, 60]")
+	flag.StringVar(&textfilePath, "textfilePath", "",
+		"the absolute path of the "+textfile.FileSuffix+" file which is written on every update cycle for the "+
+			"textfile collector of node_exporter, needs to be used with -platform=Textfile. The directory "+
+			"should exist and be writable only by its owner")
 }
 
-func indexHandler(w http.ResponseWriter, _ *http.Request) {
+func indexHandler(w http.ResponseWriter, r *http.Request) {
 	var proposal = "http"
+	if r.TLS != nil {
+		proposal = "https"
+	}
 	_, err := w.Write([]byte(
 		`<html>
 			<head><title>NPU-Exporter</title></head>
@@ -305,6 +678,14 @@ func indexHandler(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// healthzHandler the liveness probe, the process is alive as long as it is serving
+func healthzHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write([]byte(`{"status":"ok"}`)); err != nil {
+		hwlog.RunLog.Errorf("write healthz response failed: %v", err)
+	}
+}
+
 func initHwLogger() error {
 	if err := hwlog.InitRunLogger(hwLogConfig, context.Background()); err != nil {
 		fmt.Printf("hwlog init failed, error is %v\n", err)
@@ -313,50 +694,134 @@ func initHwLogger() error {
 	return nil
 }
 
+// flushHwLogger flush the run logger and the security logger, the latter records the last auth failures and
+// lockouts before the exit
+func flushHwLogger() {
+	if err := hwlog.RunLog.FlushMem(); err != nil {
+		fmt.Printf("flush hwlog failed, error is %v\n", err)
+	}
+	if hwlog.SecLog == nil {
+		return
+	}
+	if err := hwlog.SecLog.FlushMem(); err != nil {
+		fmt.Printf("flush security hwlog failed, error is %v\n", err)
+	}
+}
+
+// stopCollector cancel the collection and wait until the driver is shut down, or the deadline is exceeded
+func stopCollector(stop context.CancelFunc, c collector.ManagedCollector) {
+	stop()
+	select {
+	case <-c.Stopped():
+		hwlog.RunLog.Info("the npu collector is stopped")
+	case <-time.After(shutdownTimeout):
+		hwlog.RunLog.Warnf("the npu collector is not stopped in %v", shutdownTimeout)
+	}
+}
+
+// serveUntilStopped serve until the server failed or the ctx is done, the server is shut down gracefully
+// in the latter case
+func serveUntilStopped(ctx context.Context, s *http.Server, ln net.Listener) {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(ln)
+	}()
+	select {
+	case err := <-serveErr:
+		hwlog.RunLog.Errorf("Http server error: %v and stopped", err)
+		return
+	case <-ctx.Done():
+	}
+	hwlog.RunLog.Info("received the stop signal, shutting down the http server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		hwlog.RunLog.Errorf("shutdown the http server failed: %v", err)
+	}
+}
+
+// newFaultJournal create the fault journal when the faultJournalFile is given, nil means not to persist the events
+func newFaultJournal() (*collector.FaultJournal, error) {
+	if faultJournalFile == "" {
+		return nil, nil
+	}
+	return collector.NewFaultJournal(faultJournalFile)
+}
+
+// startCollector load the fault code knowledge base, start the npu collector and register it in the registry
+func startCollector(ctx context.Context, journal *collector.FaultJournal) (*prometheus.Registry,
+	collector.ManagedCollector, error) {
+	if err := faultcode.InitDecoder(faultCodeFile); err != nil {
+		return nil, nil, fmt.Errorf("init fault code knowledge base failed: %v", err)
+	}
+	opts := readCntMonitoringFlags()
+	reg, c, err := regPrometheus(ctx, opts, journal)
+	if err != nil {
+		return nil, nil, fmt.Errorf("register prometheus failed: %v", err)
+	}
+	return reg, c, nil
+}
+
 func prometheusProcess() {
 	if err := initHwLogger(); err != nil {
 		return
 	}
+	defer flushHwLogger()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 	if err := paramValidInPrometheus(); err != nil {
 		hwlog.RunLog.Error(err)
 		return
 	}
 
 	hwlog.RunLog.Infof("npu exporter starting and the version is %s", versions.BuildVersion)
-	opts := readCntMonitoringFlags()
-	reg, err := regPrometheus(opts)
+	journal, err := newFaultJournal()
 	if err != nil {
-		hwlog.RunLog.Errorf("register prometheus failed: %v", err)
+		hwlog.RunLog.Errorf("init fault journal failed: %v", err)
+		return
+	}
+	reg, c, err := startCollector(ctx, journal)
+	if err != nil {
+		hwlog.RunLog.Error(err)
 		return
 	}
+	defer stopCollector(stop, c)
 	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}))
+	if journal != nil {
+		http.Handle(collector.FaultJournalPath, journal)
+	}
+	http.Handle(collector.InventoryAPIPath, collector.NewInventoryAPI(c))
 	http.Handle("/", http.HandlerFunc(indexHandler))
 	conf := initConfig()
-	s, limitLs := newServerAndListener(conf)
+	s, limitLs := newServerAndListener(conf, collector.NewReadinessHandler(c))
 	if s == nil || limitLs == nil {
 		return
 	}
-	hwlog.RunLog.Warn("enable unsafe http server")
-	if err := s.Serve(limitLs); err != nil {
-		hwlog.RunLog.Error("Http server error: %v and stopped", err)
+	if err = watchConfig(ctx, c, s.Handler); err != nil {
+		hwlog.RunLog.Warnf("the config file will not be reloaded: %v", err)
+	}
+	if tlsCertFile == "" {
+		hwlog.RunLog.Warn("enable unsafe http server")
+		serveUntilStopped(ctx, s, limitLs)
+		return
+	}
+	tlsLs, err := newTLSListener(ctx, limitLs)
+	if err != nil {
+		hwlog.RunLog.Errorf("enable https server failed: %v", err)
+		return
 	}
+	hwlog.RunLog.Info("enable https server")
+	serveUntilStopped(ctx, s, tlsLs)
 }
 
 func paramValidInTelegraf() error {
-	// cmdLine here must contain "-platfor=Telegraf", otherwise, it will enter the Prometheus process
-	cmdLine := os.Args[1:]
-	switch len(cmdLine) {
-	case minTelegrafParamLen:
-		return nil
-	case maxTelegrafParamLen:
-		cmdLineStr := strings.Join(cmdLine, "")
-		if strings.Contains(cmdLineStr, pollIntervalStr) {
-			return nil
+	var errs configErrors
+	flag.Visit(func(f *flag.Flag) {
+		if !telegrafOptions[f.Name] {
+			errs.add(f.Name, f.Value, fmt.Sprintf("only support %s in Telegraf", pollIntervalStr))
 		}
-		return fmt.Errorf("only support %s in Telegraf", pollIntervalStr)
-	default:
-		return errors.New("too many parameters")
-	}
+	})
+	return errs.err()
 }
 
 func telegrafProcess() {
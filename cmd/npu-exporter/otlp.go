@@ -0,0 +1,116 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package main
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"huawei.com/npu-exporter/v5/collector/otlp"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/versions"
+)
+
+const (
+	otlpPlatform = "OTLP"
+	// nodeNameEnv the env of the kubernetes node name, which is set by the downward api
+	nodeNameEnv = "NODE_NAME"
+)
+
+// the options of the OTLP platform
+var (
+	otlpEndpoint string
+	otlpProtocol string
+	otlpInsecure bool
+	otlpTimeout  int
+)
+
+// otlpOptions the options which are only supported in OTLP
+var otlpOptions = []string{"otlpEndpoint", "otlpProtocol", "otlpInsecure", "otlpTimeout"}
+
+func paramValidInOTLP() error {
+	var errs configErrors
+	collectorParamValid(&errs)
+	rejectOtherPlatformOptions(&errs, otlpPlatform)
+	if err := otlp.CheckEndpoint(otlpProtocol, otlpEndpoint); err != nil {
+		errs.add("otlpEndpoint", otlpEndpoint, err.Error())
+	}
+	if otlpTimeout < 1 || otlpTimeout > oneMinute {
+		errs.add("otlpTimeout", otlpTimeout, "the range is [1, 60]")
+	}
+	return errs.err()
+}
+
+func otlpProcess() {
+	if err := initHwLogger(); err != nil {
+		return
+	}
+	defer flushHwLogger()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	if err := paramValidInOTLP(); err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+
+	hwlog.RunLog.Infof("npu exporter starting and the version is %s", versions.BuildVersion)
+	journal, err := newFaultJournal()
+	if err != nil {
+		hwlog.RunLog.Errorf("init fault journal failed: %v", err)
+		return
+	}
+	_, c, err := startCollector(ctx, journal)
+	if err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+	defer stopCollector(stop, c)
+	// the constant labels are the resource attributes, since the samples are not gathered by the registry
+	attributes, err := parseLabels(labels)
+	if err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+	hostName, err := os.Hostname()
+	if err != nil {
+		hwlog.RunLog.Warnf("get the host name failed: %v", err)
+	}
+	e, err := otlp.NewExporter(c, otlp.Options{
+		Protocol:   otlpProtocol,
+		Endpoint:   otlpEndpoint,
+		Insecure:   otlpInsecure,
+		Timeout:    time.Duration(otlpTimeout) * time.Second,
+		HostName:   hostName,
+		NodeName:   os.Getenv(nodeNameEnv),
+		Attributes: attributes,
+	})
+	if err != nil {
+		hwlog.RunLog.Errorf("create the otlp exporter failed: %v", err)
+		return
+	}
+	if err = watchConfig(ctx, c, nil); err != nil {
+		hwlog.RunLog.Warnf("the config file will not be reloaded: %v", err)
+	}
+	if (otlpProtocol == otlp.ProtocolGRPC && otlpInsecure) || strings.HasPrefix(otlpEndpoint, "http://") {
+		hwlog.RunLog.Warn("export the metrics over unsafe plaintext")
+	}
+	hwlog.RunLog.Infof("export the metrics to the otlp endpoint by %s on the update cadence", otlpProtocol)
+	e.Run(ctx)
+}
@@ -0,0 +1,115 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package main
+package main
+
+import (
+	"context"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/remotewrite"
+	"huawei.com/npu-exporter/v5/versions"
+)
+
+const (
+	remoteWritePlatform = "RemoteWrite"
+
+	defaultRemoteWriteInterval = 15
+	defaultRemoteWriteWALDir   = "/var/log/mindx-dl/npu-exporter/remote-write-wal"
+	// defaultRemoteWriteWALSize the default max size of the remote write wal, in megabytes
+	defaultRemoteWriteWALSize = 100
+	maxRemoteWriteWALSize     = 10240
+)
+
+// the options of the remote write platform
+var (
+	remoteWriteURL      string
+	remoteWriteInterval int
+	remoteWriteTimeout  int
+	remoteWriteWALDir   string
+	remoteWriteWALSize  int
+)
+
+// remoteWriteOptions the options which are only supported in RemoteWrite
+var remoteWriteOptions = []string{"remoteWriteURL", "remoteWriteInterval", "remoteWriteTimeout",
+	"remoteWriteWALDir", "remoteWriteWALSize"}
+
+func paramValidInRemoteWrite() error {
+	var errs configErrors
+	collectorParamValid(&errs)
+	rejectOtherPlatformOptions(&errs, remoteWritePlatform)
+	if err := remotewrite.CheckURL(remoteWriteURL); err != nil {
+		errs.add("remoteWriteURL", remoteWriteURL, err.Error())
+	}
+	if remoteWriteInterval < 1 || remoteWriteInterval > oneHour {
+		errs.add("remoteWriteInterval", remoteWriteInterval, "the range is [1, 3600]")
+	}
+	if remoteWriteTimeout < 1 || remoteWriteTimeout > oneMinute {
+		errs.add("remoteWriteTimeout", remoteWriteTimeout, "the range is [1, 60]")
+	}
+	if remoteWriteWALSize < 1 || remoteWriteWALSize > maxRemoteWriteWALSize {
+		errs.add("remoteWriteWALSize", remoteWriteWALSize, "the range is [1, 10240]")
+	}
+	return errs.err()
+}
+
+func remoteWriteProcess() {
+	if err := initHwLogger(); err != nil {
+		return
+	}
+	defer flushHwLogger()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	if err := paramValidInRemoteWrite(); err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+
+	hwlog.RunLog.Infof("npu exporter starting and the version is %s", versions.BuildVersion)
+	journal, err := newFaultJournal()
+	if err != nil {
+		hwlog.RunLog.Errorf("init fault journal failed: %v", err)
+		return
+	}
+	reg, c, err := startCollector(ctx, journal)
+	if err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+	defer stopCollector(stop, c)
+	w, err := remotewrite.NewWriter(reg, remotewrite.Options{
+		URL:      remoteWriteURL,
+		Interval: time.Duration(remoteWriteInterval) * time.Second,
+		Timeout:  time.Duration(remoteWriteTimeout) * time.Second,
+		WALDir:   remoteWriteWALDir,
+		WALSize:  int64(remoteWriteWALSize) * oneMegabytes,
+	})
+	if err != nil {
+		hwlog.RunLog.Errorf("create the remote writer failed: %v", err)
+		return
+	}
+	if err = watchConfig(ctx, c, nil); err != nil {
+		hwlog.RunLog.Warnf("the config file will not be reloaded: %v", err)
+	}
+	if strings.HasPrefix(remoteWriteURL, "http://") {
+		hwlog.RunLog.Warn("push the metrics over unsafe http")
+	}
+	hwlog.RunLog.Infof("push the metrics to the remote write endpoint every %d seconds", remoteWriteInterval)
+	w.Run(ctx)
+}
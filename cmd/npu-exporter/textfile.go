@@ -0,0 +1,80 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package main
+package main
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/textfile"
+	"huawei.com/npu-exporter/v5/versions"
+)
+
+const textfilePlatform = "Textfile"
+
+// textfilePath the option of the textfile platform
+var textfilePath string
+
+// textfileOptions the options which are only supported in Textfile
+var textfileOptions = []string{"textfilePath"}
+
+func paramValidInTextfile() error {
+	var errs configErrors
+	collectorParamValid(&errs)
+	rejectOtherPlatformOptions(&errs, textfilePlatform)
+	if _, err := textfile.CheckPath(textfilePath); err != nil {
+		errs.add("textfilePath", textfilePath, err.Error())
+	}
+	return errs.err()
+}
+
+func textfileProcess() {
+	if err := initHwLogger(); err != nil {
+		return
+	}
+	defer flushHwLogger()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	if err := paramValidInTextfile(); err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+
+	hwlog.RunLog.Infof("npu exporter starting and the version is %s", versions.BuildVersion)
+	journal, err := newFaultJournal()
+	if err != nil {
+		hwlog.RunLog.Errorf("init fault journal failed: %v", err)
+		return
+	}
+	reg, c, err := startCollector(ctx, journal)
+	if err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+	defer stopCollector(stop, c)
+	w, err := textfile.NewWriter(reg, textfilePath)
+	if err != nil {
+		hwlog.RunLog.Errorf("create the textfile writer failed: %v", err)
+		return
+	}
+	if err = watchConfig(ctx, c, nil); err != nil {
+		hwlog.RunLog.Warnf("the config file will not be reloaded: %v", err)
+	}
+	hwlog.RunLog.Infof("write the metrics to %s on every update cycle", textfilePath)
+	w.Run(ctx, c.UpdateInterval)
+}
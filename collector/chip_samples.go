@@ -0,0 +1,140 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+// Sample a sample of the metric group, which is named as the prometheus metric
+type Sample struct {
+	Name    string
+	Help    string
+	Counter bool
+	// Labels all the labels of the metric, including the labels which identify the chip
+	Labels map[string]string
+	Value  float64
+	// Timestamp the time when the chip is queried, zero means unknown
+	Timestamp time.Time
+}
+
+// ChipSamples the samples of the enabled metric groups of a chip
+type ChipSamples struct {
+	CardID  int
+	Chip    *HuaWeiAIChip
+	Samples []Sample
+}
+
+// CollectChips implements ManagedCollector
+func (n *npuCollector) CollectChips() []ChipSamples {
+	cached := n.loadCache()
+	groups := n.freshness.freshGroups(n.metricGroups.Groups())
+	chips := make([]ChipSamples, 0, initSize)
+	n.visitChips(cached, func(card *HuaWeiNPUCard, chip *HuaWeiAIChip, devInfo container.DevicesInfo) {
+		metrics := n.collectMetrics(func(ch chan<- prometheus.Metric) {
+			for _, group := range groups {
+				group.Collect(ch, card, chip, devInfo)
+			}
+		})
+		samples := make([]Sample, 0, len(metrics))
+		for _, metric := range metrics {
+			sample, err := toSample(metric)
+			if err != nil {
+				hwlog.RunLog.Warnf("convert the metric to the sample failed: %v", err)
+				continue
+			}
+			samples = append(samples, sample)
+		}
+		chips = append(chips, ChipSamples{CardID: card.CardID, Chip: chip, Samples: samples})
+	})
+	return chips
+}
+
+// collectMetrics collect the metrics into a slice, the failed values are omitted and the metrics are named as
+// Collect does
+func (n *npuCollector) collectMetrics(collect func(ch chan<- prometheus.Metric)) []prometheus.Metric {
+	collected := make(chan prometheus.Metric, cacheSize)
+	done := make(chan []prometheus.Metric, 1)
+	go func() {
+		metrics := make([]prometheus.Metric, 0, cacheSize)
+		for metric := range collected {
+			metrics = append(metrics, metric)
+		}
+		done <- metrics
+	}()
+	var ch chan<- prometheus.Metric = collected
+	flushes := make([]func(), 0, 1)
+	if n.omitFailedValues {
+		var flush func()
+		ch, flush = omitNaNMetrics(ch)
+		flushes = append(flushes, flush)
+	}
+	if n.naming.v2() {
+		var flush func()
+		ch, flush = collectWithNaming(ch, n.naming)
+		flushes = append(flushes, flush)
+	}
+	collect(ch)
+	// the wrappers are flushed from the outermost one, as the deferred flushes in Collect
+	for i := len(flushes) - 1; i >= 0; i-- {
+		flushes[i]()
+	}
+	close(collected)
+	return <-done
+}
+
+// descInfoOf get the name, help and labels of the group descriptor, or of the v2 descriptor renamed from it
+func descInfoOf(desc *prometheus.Desc) (descInfo, bool) {
+	value, ok := groupDescs.Load(desc)
+	if !ok {
+		value, ok = v2DescInfos.Load(desc)
+	}
+	if !ok {
+		return descInfo{}, false
+	}
+	info, ok := value.(descInfo)
+	return info, ok
+}
+
+func toSample(metric prometheus.Metric) (Sample, error) {
+	info, ok := descInfoOf(metric.Desc())
+	if !ok {
+		return Sample{}, fmt.Errorf("the descriptor %s is not created by the metric group", metric.Desc())
+	}
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		return Sample{}, fmt.Errorf("write the metric %s failed: %v", info.name, err)
+	}
+	sample := Sample{Name: info.name, Help: info.help, Value: m.GetGauge().GetValue(),
+		Labels: make(map[string]string, len(m.Label))}
+	if m.Counter != nil {
+		sample.Counter, sample.Value = true, m.GetCounter().GetValue()
+	}
+	for _, pair := range m.Label {
+		sample.Labels[pair.GetName()] = pair.GetValue()
+	}
+	if m.TimestampMs != nil {
+		sample.Timestamp = time.UnixMilli(m.GetTimestampMs())
+	}
+	return sample, nil
+}
@@ -0,0 +1,83 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+func samplesByName(samples []Sample) map[string]Sample {
+	byName := make(map[string]Sample, len(samples))
+	for _, sample := range samples {
+		byName[sample.Name] = sample
+	}
+	return byName
+}
+
+// TestCollectChips test the samples are collected chip by chip and converted as the prometheus metrics
+func TestCollectChips(t *testing.T) {
+	groups, err := ParseMetricGroups(MemoryGroup)
+	assert.Nil(t, err)
+	for _, tc := range []struct {
+		naming MetricNaming
+		omit   bool
+		// the names of the hbm total memory and the failed memory
+		hbmName    string
+		failedName string
+		hbmValue   float64
+	}{
+		{naming: NamingV1, hbmName: "npu_chip_info_hbm_total_memory", failedName: "npu_chip_info_total_memory",
+			hbmValue: 2},
+		{naming: NamingV2, hbmName: "npu_chip_hbm_total_bytes", failedName: "npu_chip_memory_total_bytes",
+			hbmValue: 2 * mega},
+		{naming: NamingV2, omit: true, hbmName: "npu_chip_hbm_total_bytes", failedName: "npu_chip_memory_total_bytes",
+			hbmValue: 2 * mega},
+	} {
+		n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups, Naming: tc.naming,
+			OmitFailedValues: tc.omit})
+		chip := &HuaWeiAIChip{DeviceID: 1, ChipIfo: &common.ChipInfo{Name: "910"},
+			HbmInfo: &common.HbmInfo{MemorySize: 2}, Meminf: &common.MemoryInfo{}}
+		chip.setQueryError(fieldMemory, apiGetDeviceMemoryInfo, errors.New("failed"))
+		assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip}, CardID: 3,
+			Timestamp: time.UnixMilli(scrapeTimestamp)}}, time.Minute))
+
+		chips := n.CollectChips()
+		assert.Len(t, chips, 1)
+		assert.Equal(t, 3, chips[0].CardID)
+		assert.NotSame(t, chip, chips[0].Chip)
+		assert.Equal(t, chip.DeviceID, chips[0].Chip.DeviceID)
+		assert.Nil(t, chip.NetInfo)
+		samples := samplesByName(chips[0].Samples)
+		hbm, ok := samples[tc.hbmName]
+		assert.True(t, ok)
+		assert.Equal(t, tc.hbmValue, hbm.Value)
+		assert.False(t, hbm.Counter)
+		assert.Equal(t, "1", hbm.Labels[npuID])
+		assert.Equal(t, time.UnixMilli(scrapeTimestamp), hbm.Timestamp)
+		failed, ok := samples[tc.failedName]
+		assert.Equal(t, !tc.omit, ok)
+		assert.Equal(t, !tc.omit, math.IsNaN(failed.Value))
+		_, ok = samples["npu_chip_info_hbm_total_memory"]
+		assert.Equal(t, tc.naming.v1(), ok)
+	}
+}
@@ -142,7 +142,9 @@ func (dp *DevicesParser) RecvErr() <-chan error {
 
 // Close closes all connections and channels established during initializing
 func (dp *DevicesParser) Close() {
-	_ = dp.RuntimeOperator.Close()
+	if err := dp.RuntimeOperator.Close(); err != nil {
+		hwlog.RunLog.Warnf("close the connections of container runtime failed: %v", err)
+	}
 }
 
 func (dp *DevicesParser) parseDevices(ctx context.Context, c *CommonContainer, rs chan<- DevicesInfo) error {
@@ -180,15 +180,21 @@ func sockCheck(operator *RuntimeOperatorTool) error {
 	return nil
 }
 
-// Close closes container runtime operator
+// Close closes container runtime operator, both the OCI and CRI connections are closed even if one of them failed
 func (operator *RuntimeOperatorTool) Close() error {
-	err := operator.conn.Close()
-	if err != nil {
-		return err
+	var errs []string
+	for name, conn := range map[string]*grpc.ClientConn{"OCI": operator.conn, "CRI": operator.criConn} {
+		if conn == nil {
+			continue
+		}
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("close %s connection failed: %v", name, err))
+		}
 	}
-	err = operator.criConn.Close()
-	if err != nil {
-		return err
+	operator.conn = nil
+	operator.criConn = nil
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
 	}
 	return nil
 }
@@ -266,10 +272,7 @@ func (operator *RuntimeOperatorTool) GetContainerType() string {
 	return DefaultContainer
 }
 
-type nsKey struct{}
-
 func setGrpcNamespaceHeader(ctx context.Context, namespace string) context.Context {
-	context.WithValue(ctx, nsKey{}, namespace)
 	ns := metadata.Pairs(grpcHeader, namespace)
 	md, ok := metadata.FromOutgoingContext(ctx)
 	if !ok {
@@ -0,0 +1,194 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// fault event label name
+const (
+	faultEventID   = "event_id"
+	faultSeverity  = "severity"
+	faultAssertion = "assertion"
+)
+
+const (
+	faultAssertionRecover = "recover"
+	faultAssertionOccur   = "occur"
+	faultAssertionOnce    = "once"
+
+	faultEventChanSize = 128
+)
+
+var (
+	npuChipFaultEventTotal = prometheus.NewDesc("npu_chip_fault_event_total",
+		"the total number of fault events received from the npu fault subscription",
+		[]string{npuID, faultEventID, faultSeverity, faultAssertion}, nil)
+	npuChipFaultAsserted = prometheus.NewDesc("npu_chip_fault_asserted",
+		"the fault which is currently asserted on the npu with value '1'",
+		[]string{npuID, faultEventID, faultSeverity}, nil)
+)
+
+type faultEventKey struct {
+	phyID     int32
+	eventID   int64
+	severity  int8
+	assertion int8
+}
+
+type assertedFaultKey struct {
+	phyID   int32
+	eventID int64
+}
+
+// faultEventRecorder receives the events of the dcmi fault subscription, so that the faults which occur and
+// recover between two updates are still visible
+type faultEventRecorder struct {
+	mutex          sync.RWMutex
+	events         chan common.DevFaultInfo
+	eventCounts    map[faultEventKey]uint64
+	assertedFaults map[assertedFaultKey]int8
+	journal        *FaultJournal
+}
+
+func newFaultEventRecorder(journal *FaultJournal) *faultEventRecorder {
+	return &faultEventRecorder{
+		events:         make(chan common.DevFaultInfo, faultEventChanSize),
+		eventCounts:    make(map[faultEventKey]uint64, initSize),
+		assertedFaults: make(map[assertedFaultKey]int8, initSize),
+		journal:        journal,
+	}
+}
+
+// receive is the fault event call back func, it is invoked by the driver thread and must not block
+func (r *faultEventRecorder) receive(faultInfo common.DevFaultInfo) {
+	select {
+	case r.events <- faultInfo:
+	default:
+		hwlog.RunLog.Warnf("fault event channel is full, drop event %d of logic id %d", faultInfo.EventID,
+			faultInfo.LogicID)
+	}
+}
+
+func (r *faultEventRecorder) run(ctx context.Context, dmgr devmanager.DeviceInterface) {
+	for {
+		select {
+		case _, ok := <-ctx.Done():
+			if !ok {
+				hwlog.RunLog.Info("stop recording fault events")
+			}
+			return
+		case faultInfo := <-r.events:
+			phyID, err := dmgr.GetPhysicIDFromLogicID(faultInfo.LogicID)
+			if err != nil {
+				hwlog.RunLog.Warnf("get phy id of logic id %d failed when record fault event: %v",
+					faultInfo.LogicID, err)
+				continue
+			}
+			hwlog.RunLog.Infof("receive fault event %d of npu %d, severity: %d, assertion: %d",
+				faultInfo.EventID, phyID, faultInfo.Severity, faultInfo.Assertion)
+			r.record(phyID, faultInfo)
+			if r.journal == nil {
+				continue
+			}
+			if err = r.journal.record(phyID, faultInfo); err != nil {
+				hwlog.RunLog.Warnf("write fault event %d of npu %d to journal failed: %v", faultInfo.EventID, phyID,
+					err)
+			}
+		}
+	}
+}
+
+func (r *faultEventRecorder) record(phyID int32, faultInfo common.DevFaultInfo) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.eventCounts[faultEventKey{
+		phyID:     phyID,
+		eventID:   faultInfo.EventID,
+		severity:  faultInfo.Severity,
+		assertion: faultInfo.Assertion,
+	}]++
+	assertedKey := assertedFaultKey{phyID: phyID, eventID: faultInfo.EventID}
+	switch faultInfo.Assertion {
+	case common.FaultOccur:
+		r.assertedFaults[assertedKey] = faultInfo.Severity
+	case common.FaultRecover:
+		delete(r.assertedFaults, assertedKey)
+	default:
+	}
+}
+
+// Describe implements prometheus.Collector
+func (r *faultEventRecorder) Describe(ch chan<- *prometheus.Desc) {
+	ch <- npuChipFaultEventTotal
+	ch <- npuChipFaultAsserted
+}
+
+// Collect implements prometheus.Collector
+func (r *faultEventRecorder) Collect(ch chan<- prometheus.Metric) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for key, count := range r.eventCounts {
+		ch <- prometheus.MustNewConstMetric(npuChipFaultEventTotal, prometheus.CounterValue, float64(count),
+			[]string{strconv.Itoa(int(key.phyID)), common.GetErrorCodeName(key.eventID),
+				strconv.Itoa(int(key.severity)), getAssertionName(key.assertion)}...)
+	}
+	for key, faultSeverity := range r.assertedFaults {
+		ch <- prometheus.MustNewConstMetric(npuChipFaultAsserted, prometheus.GaugeValue, 1,
+			[]string{strconv.Itoa(int(key.phyID)), common.GetErrorCodeName(key.eventID),
+				strconv.Itoa(int(faultSeverity))}...)
+	}
+}
+
+func subscribeFaultEvent(ctx context.Context, group *sync.WaitGroup, r *faultEventRecorder,
+	dmgr devmanager.DeviceInterface) {
+	if err := dmgr.SetFaultEventCallFunc(r.receive); err != nil {
+		hwlog.RunLog.Errorf("set fault event call back func failed: %v", err)
+		return
+	}
+	if err := dmgr.SubscribeDeviceFaultEvent(common.SubscribeAllDevice); err != nil {
+		hwlog.RunLog.Errorf("subscribe fault event of all devices failed: %v", err)
+		return
+	}
+	hwlog.RunLog.Info("subscribe fault event of all devices successfully")
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		r.run(ctx, dmgr)
+	}()
+}
+
+func getAssertionName(assertion int8) string {
+	switch assertion {
+	case common.FaultRecover:
+		return faultAssertionRecover
+	case common.FaultOccur:
+		return faultAssertionOccur
+	case common.FaultOnce:
+		return faultAssertionOnce
+	default:
+		return strconv.Itoa(int(assertion))
+	}
+}
@@ -0,0 +1,71 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+const (
+	hbmFaultEventID  = 0x80E01801
+	linkFaultEventID = 0x81078603
+)
+
+// TestFaultEventRecorder test the counters and asserted faults of faultEventRecorder
+func TestFaultEventRecorder(t *testing.T) {
+	r := newFaultEventRecorder(nil)
+	r.record(0, common.DevFaultInfo{EventID: hbmFaultEventID, Severity: 2, Assertion: common.FaultOccur})
+	r.record(0, common.DevFaultInfo{EventID: hbmFaultEventID, Severity: 2, Assertion: common.FaultRecover})
+	r.record(1, common.DevFaultInfo{EventID: linkFaultEventID, Severity: 1, Assertion: common.FaultOccur})
+	r.record(1, common.DevFaultInfo{EventID: linkFaultEventID, Severity: 1, Assertion: common.FaultOnce})
+
+	expected := `
+# HELP npu_chip_fault_asserted the fault which is currently asserted on the npu with value '1'
+# TYPE npu_chip_fault_asserted gauge
+npu_chip_fault_asserted{event_id="0x81078603",id="1",severity="1"} 1
+# HELP npu_chip_fault_event_total the total number of fault events received from the npu fault subscription
+# TYPE npu_chip_fault_event_total counter
+npu_chip_fault_event_total{assertion="occur",event_id="0x80E01801",id="0",severity="2"} 1
+npu_chip_fault_event_total{assertion="recover",event_id="0x80E01801",id="0",severity="2"} 1
+npu_chip_fault_event_total{assertion="occur",event_id="0x81078603",id="1",severity="1"} 1
+npu_chip_fault_event_total{assertion="once",event_id="0x81078603",id="1",severity="1"} 1
+`
+	if err := testutil.CollectAndCompare(r, strings.NewReader(expected)); err != nil {
+		t.Fatal("Unexpected metrics returned:", err)
+	}
+}
+
+// TestSubscribeFaultEvent test the fault events are recorded after subscription
+func TestSubscribeFaultEvent(t *testing.T) {
+	r := newFaultEventRecorder(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subscribeFaultEvent(ctx, &sync.WaitGroup{}, r, &devmanager.DeviceManagerMock{})
+	r.receive(common.DevFaultInfo{EventID: hbmFaultEventID, LogicID: 0, Severity: 2, Assertion: common.FaultOnce})
+	assert.Eventually(t, func() bool {
+		return testutil.CollectAndCount(r, "npu_chip_fault_event_total") == 1
+	}, waitTime, time.Millisecond*10)
+}
@@ -0,0 +1,264 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+const (
+	// FaultJournalPath the url path of the fault journal query
+	FaultJournalPath = "/faultEvents"
+
+	faultJournalCapacity   = 10 // in megabytes
+	faultJournalSaveVolume = 10
+	faultJournalSaveTime   = 30 // in days
+
+	defaultQueryLimit = 1000
+	maxQueryLimit     = 10000
+
+	queryID       = "id"
+	queryStart    = "start"
+	queryEnd      = "end"
+	querySeverity = "severity"
+	queryLimit    = "limit"
+)
+
+// FaultJournalRecord the fault event which is persisted in the fault journal
+type FaultJournalRecord struct {
+	// the physic id of the chip
+	ID      int32 `json:"id"`
+	LogicID int32 `json:"logic_id"`
+	EventID int64 `json:"event_id"`
+	// the hex error code of the event, eg: 0x80E01801
+	EventCode       string `json:"event_code"`
+	Severity        int8   `json:"severity"`
+	Assertion       string `json:"assertion"`
+	AlarmRaisedTime int64  `json:"alarm_raised_time"`
+	// the time in milliseconds when the exporter receives the event
+	ReceiveTime int64 `json:"receive_time"`
+}
+
+// FaultJournalFilter the filter of the fault journal query, the zero value matches all the records
+type FaultJournalFilter struct {
+	ID       *int32
+	Severity *int8
+	// start and end of the receive time, in milliseconds
+	Start int64
+	End   int64
+	Limit int
+}
+
+// FaultJournal persists the subscribed fault events to a rotating file, so that the history survives restarts
+type FaultJournal struct {
+	logs *hwlog.Logs
+}
+
+// NewFaultJournal create a fault journal which is written to the file
+func NewFaultJournal(fileName string) (*FaultJournal, error) {
+	if fileName == "" {
+		return nil, errors.New("the fault journal file is empty")
+	}
+	realPath, err := utils.CheckPath(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("check fault journal file failed: %v", err)
+	}
+	return &FaultJournal{logs: &hwlog.Logs{
+		FileName:   realPath,
+		Capacity:   faultJournalCapacity,
+		SaveTime:   faultJournalSaveTime,
+		SaveVolume: faultJournalSaveVolume,
+		LocalOrUTC: true,
+	}}, nil
+}
+
+func (j *FaultJournal) record(phyID int32, faultInfo common.DevFaultInfo) error {
+	data, err := json.Marshal(FaultJournalRecord{
+		ID:              phyID,
+		LogicID:         faultInfo.LogicID,
+		EventID:         faultInfo.EventID,
+		EventCode:       common.GetErrorCodeName(faultInfo.EventID),
+		Severity:        faultInfo.Severity,
+		Assertion:       getAssertionName(faultInfo.Assertion),
+		AlarmRaisedTime: faultInfo.AlarmRaisedTime,
+		ReceiveTime:     time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = j.logs.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return j.logs.Flush()
+}
+
+// Query get the records which match the filter, the records are sorted by the receive time
+func (j *FaultJournal) Query(filter FaultJournalFilter) ([]FaultJournalRecord, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultQueryLimit
+	}
+	fileList, err := j.logs.FileList()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]FaultJournalRecord, 0, initSize)
+	// the newer file comes first, stop reading the older files when the limit is reached
+	for _, fileName := range fileList {
+		fileRecords, err := readFaultJournalFile(fileName, filter)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, fileRecords...)
+		if len(records) >= filter.Limit {
+			break
+		}
+	}
+	sort.SliceStable(records, func(i, k int) bool {
+		return records[i].ReceiveTime < records[k].ReceiveTime
+	})
+	if len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+	return records, nil
+}
+
+func readFaultJournalFile(fileName string, filter FaultJournalFilter) ([]FaultJournalRecord, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("open fault journal file failed: %v", err)
+	}
+	defer closeFaultJournalFile(file)
+	var records []FaultJournalRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record FaultJournalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			hwlog.RunLog.Warnf("skip the invalid record of fault journal: %v", err)
+			continue
+		}
+		if filter.match(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read fault journal file failed: %v", err)
+	}
+	return records, nil
+}
+
+func closeFaultJournalFile(file *os.File) {
+	if err := file.Close(); err != nil {
+		hwlog.RunLog.Warnf("close fault journal file failed: %v", err)
+	}
+}
+
+func (f FaultJournalFilter) match(record FaultJournalRecord) bool {
+	if f.ID != nil && *f.ID != record.ID {
+		return false
+	}
+	if f.Severity != nil && *f.Severity != record.Severity {
+		return false
+	}
+	if f.Start > 0 && record.ReceiveTime < f.Start {
+		return false
+	}
+	if f.End > 0 && record.ReceiveTime > f.End {
+		return false
+	}
+	return true
+}
+
+// ServeHTTP query the fault journal, eg: /faultEvents?id=0&severity=2&start=1690000000000&end=1690086400000
+func (j *FaultJournal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFaultJournalFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	records, err := j.Query(filter)
+	if err != nil {
+		hwlog.RunLog.Errorf("query fault journal failed: %v", err)
+		http.Error(w, "query fault journal failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(records); err != nil {
+		hwlog.RunLog.Errorf("write fault journal response failed: %v", err)
+	}
+}
+
+func parseFaultJournalFilter(r *http.Request) (FaultJournalFilter, error) {
+	var filter FaultJournalFilter
+	query := r.URL.Query()
+	if value := query.Get(queryID); value != "" {
+		id, err := strconv.ParseInt(value, base, bitSize32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %s", queryID, value)
+		}
+		phyID := int32(id)
+		filter.ID = &phyID
+	}
+	if value := query.Get(querySeverity); value != "" {
+		severity, err := strconv.ParseInt(value, base, bitSize8)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %s", querySeverity, value)
+		}
+		faultSeverity := int8(severity)
+		filter.Severity = &faultSeverity
+	}
+	var err error
+	if filter.Start, err = parseQueryTime(query.Get(queryStart)); err != nil {
+		return filter, fmt.Errorf("invalid %s: %v", queryStart, err)
+	}
+	if filter.End, err = parseQueryTime(query.Get(queryEnd)); err != nil {
+		return filter, fmt.Errorf("invalid %s: %v", queryEnd, err)
+	}
+	if value := query.Get(queryLimit); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxQueryLimit {
+			return filter, fmt.Errorf("invalid %s, the range is [1,%d]", queryLimit, maxQueryLimit)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// parseQueryTime parse the time in milliseconds or in RFC3339 format
+func parseQueryTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if milli, err := strconv.ParseInt(value, base, bitSize64); err == nil {
+		return milli, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}
@@ -0,0 +1,89 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+func newTestFaultJournal(t *testing.T) *FaultJournal {
+	journal, err := NewFaultJournal(filepath.Join(t.TempDir(), "fault-journal.log"))
+	if err != nil {
+		t.Fatalf("create fault journal failed: %v", err)
+	}
+	assert.Nil(t, journal.record(0, common.DevFaultInfo{EventID: hbmFaultEventID, Severity: 2,
+		Assertion: common.FaultOccur}))
+	assert.Nil(t, journal.record(1, common.DevFaultInfo{EventID: linkFaultEventID, LogicID: 1, Severity: 1,
+		Assertion: common.FaultOccur}))
+	assert.Nil(t, journal.record(0, common.DevFaultInfo{EventID: hbmFaultEventID, Severity: 2,
+		Assertion: common.FaultRecover}))
+	return journal
+}
+
+// TestFaultJournalQuery test query the fault journal with filter
+func TestFaultJournalQuery(t *testing.T) {
+	journal := newTestFaultJournal(t)
+	var phyID int32 = 0
+	var severity int8 = 1
+	tests := []struct {
+		name   string
+		filter FaultJournalFilter
+		want   int
+	}{
+		{name: "should return all the records when filter is empty", filter: FaultJournalFilter{}, want: 3},
+		{name: "should return the records of the chip when filter by id", filter: FaultJournalFilter{ID: &phyID},
+			want: 2},
+		{name: "should return the records of the severity when filter by severity",
+			filter: FaultJournalFilter{Severity: &severity}, want: 1},
+		{name: "should return the latest records when filter by limit", filter: FaultJournalFilter{Limit: 1},
+			want: 1},
+		{name: "should return nothing when the time range does not match",
+			filter: FaultJournalFilter{Start: 1, End: 2}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := journal.Query(tt.filter)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, len(records))
+		})
+	}
+}
+
+// TestFaultJournalServeHTTP test query the fault journal by http
+func TestFaultJournalServeHTTP(t *testing.T) {
+	journal := newTestFaultJournal(t)
+	recorder := httptest.NewRecorder()
+	journal.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, FaultJournalPath+"?id=0&severity=2", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var records []FaultJournalRecord
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &records))
+	if assert.Equal(t, 2, len(records)) {
+		assert.Equal(t, faultAssertionOccur, records[0].Assertion)
+		assert.Equal(t, "0x80E01801", records[0].EventCode)
+	}
+
+	recorder = httptest.NewRecorder()
+	journal.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, FaultJournalPath+"?start=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,145 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+// freshnessTracker tracks the last successful update of each data source, the groups whose data source is not
+// updated within the max age are stale
+type freshnessTracker struct {
+	lock    sync.RWMutex
+	updated map[Cadence]time.Time
+	// the cadences which are refreshed by a worker per chip, they are updated when all the workers have refreshed
+	rounds map[Cadence]*refreshRound
+	// 0 means the stale groups are still served
+	maxAge time.Duration
+	desc   *prometheus.Desc
+}
+
+// refreshRound the chips which are expected to refresh and the ones which have refreshed in the current round
+type refreshRound struct {
+	expected  map[int32]struct{}
+	refreshed map[int32]struct{}
+}
+
+func newFreshnessTracker(maxAge time.Duration) *freshnessTracker {
+	return &freshnessTracker{
+		updated: make(map[Cadence]time.Time),
+		rounds:  make(map[Cadence]*refreshRound),
+		maxAge:  maxAge,
+		desc: prometheus.NewDesc("npu_exporter_last_update_timestamp_seconds",
+			"the unix time in seconds when the data of the metric group was last updated successfully",
+			[]string{"group"}, nil),
+	}
+}
+
+// update record the data source is updated successfully just now
+func (f *freshnessTracker) update(cadence Cadence) {
+	f.lock.Lock()
+	f.updated[cadence] = time.Now()
+	f.lock.Unlock()
+}
+
+// expectChips set the chips whose workers refresh the cadence, a new round is started
+func (f *freshnessTracker) expectChips(cadence Cadence, phyIDs []int32) {
+	round := &refreshRound{
+		expected:  make(map[int32]struct{}, len(phyIDs)),
+		refreshed: make(map[int32]struct{}, len(phyIDs)),
+	}
+	for _, phyID := range phyIDs {
+		round.expected[phyID] = struct{}{}
+	}
+	f.lock.Lock()
+	f.rounds[cadence] = round
+	f.lock.Unlock()
+}
+
+// updateChip record the worker of the chip has refreshed, the cadence is updated when all the expected chips
+// have refreshed since the last update
+func (f *freshnessTracker) updateChip(cadence Cadence, phyID int32) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	round, ok := f.rounds[cadence]
+	if !ok {
+		f.updated[cadence] = time.Now()
+		return
+	}
+	if _, ok := round.expected[phyID]; !ok {
+		return
+	}
+	round.refreshed[phyID] = struct{}{}
+	if len(round.refreshed) < len(round.expected) {
+		return
+	}
+	f.updated[cadence] = time.Now()
+	round.refreshed = make(map[int32]struct{}, len(round.expected))
+}
+
+func (f *freshnessTracker) lastUpdate(cadence Cadence) time.Time {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.updated[cadence]
+}
+
+// isStale the data source which has never been updated is stale as well
+func (f *freshnessTracker) isStale(cadence Cadence, now time.Time) bool {
+	if f.maxAge <= 0 {
+		return false
+	}
+	updated := f.lastUpdate(cadence)
+	return updated.IsZero() || now.Sub(updated) > f.maxAge
+}
+
+// freshGroups filter out the stale groups
+func (f *freshnessTracker) freshGroups(groups []MetricGroup) []MetricGroup {
+	if f.maxAge <= 0 {
+		return groups
+	}
+	now := time.Now()
+	fresh := make([]MetricGroup, 0, len(groups))
+	for _, group := range groups {
+		if f.isStale(group.Cadence(), now) {
+			hwlog.RunLog.Warnf("the data of metric group %s is not updated within %v, drop it from the scrape",
+				group.Name(), f.maxAge)
+			continue
+		}
+		fresh = append(fresh, group)
+	}
+	return fresh
+}
+
+// Describe implements prometheus.Collector
+func (f *freshnessTracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- f.desc
+}
+
+func (f *freshnessTracker) collect(ch chan<- prometheus.Metric, groups []MetricGroup) {
+	for _, group := range groups {
+		updated := f.lastUpdate(group.Cadence())
+		if updated.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(f.desc, prometheus.GaugeValue,
+			float64(updated.UnixNano())/float64(time.Second), group.Name())
+	}
+}
@@ -0,0 +1,111 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// TestFreshGroups test the groups whose data source is not updated within the max age are dropped
+func TestFreshGroups(t *testing.T) {
+	groups, err := ParseMetricGroups("base,network,container")
+	assert.Nil(t, err)
+	f := newFreshnessTracker(time.Minute)
+	assert.Empty(t, f.freshGroups(groups.Groups()))
+
+	f.update(ChipCadence)
+	f.update(NetworkCadence)
+	f.lock.Lock()
+	f.updated[NetworkCadence] = time.Now().Add(-time.Hour)
+	f.lock.Unlock()
+	fresh := f.freshGroups(groups.Groups())
+	if assert.Equal(t, 1, len(fresh)) {
+		assert.Equal(t, BaseGroup, fresh[0].Name())
+	}
+
+	f = newFreshnessTracker(0)
+	assert.Equal(t, len(groups.Groups()), len(f.freshGroups(groups.Groups())))
+}
+
+// TestCollectStaleGroups test the stale groups are dropped from the scrape and the update time is exported
+func TestCollectStaleGroups(t *testing.T) {
+	groups, err := ParseMetricGroups("base,memory")
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups, MaxAge: time.Minute})
+	chip := &HuaWeiAIChip{ChipIfo: &common.ChipInfo{Name: "910"}, HbmInfo: &common.HbmInfo{},
+		Meminf: &common.MemoryInfo{}}
+	assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip},
+		Timestamp: time.Now()}}, time.Minute))
+	assert.Equal(t, 0, testutil.CollectAndCount(n, "npu_chip_info_temperature"))
+	assert.Equal(t, 0, testutil.CollectAndCount(n, "npu_exporter_last_update_timestamp_seconds"))
+
+	n.freshness.update(ChipCadence)
+	assert.Equal(t, 1, testutil.CollectAndCount(n, "npu_chip_info_temperature"))
+	assert.Equal(t, len(groups.Groups()), testutil.CollectAndCount(n, "npu_exporter_last_update_timestamp_seconds"))
+}
+
+// TestFreshChipGroups test the process and vnpu groups are judged by the chip query which fills them, not by the
+// container runtime which only labels them
+func TestFreshChipGroups(t *testing.T) {
+	groups, err := ParseMetricGroups("process,vnpu,container")
+	assert.Nil(t, err)
+	assert.True(t, groups.needContainerInfo())
+	f := newFreshnessTracker(time.Minute)
+	f.update(ChipCadence)
+	var names []string
+	for _, group := range f.freshGroups(groups.Groups()) {
+		names = append(names, group.Name())
+	}
+	assert.ElementsMatch(t, []string{ProcessGroup, VNPUGroup}, names)
+
+	f.update(ContainerCadence)
+	f.lock.Lock()
+	f.updated[ChipCadence] = time.Now().Add(-time.Hour)
+	f.lock.Unlock()
+	names = nil
+	for _, group := range f.freshGroups(groups.Groups()) {
+		names = append(names, group.Name())
+	}
+	assert.Equal(t, []string{ContainerGroup}, names)
+
+	processOnly, err := ParseMetricGroups(ProcessGroup)
+	assert.Nil(t, err)
+	assert.True(t, processOnly.needContainerInfo())
+}
+
+// TestFreshnessOfChipWorkers test the cadence refreshed by a worker per chip is updated only when all the workers
+// have refreshed
+func TestFreshnessOfChipWorkers(t *testing.T) {
+	f := newFreshnessTracker(time.Minute)
+	f.expectChips(NetworkCadence, []int32{0, 1})
+	f.updateChip(NetworkCadence, 0)
+	f.updateChip(NetworkCadence, 0)
+	assert.True(t, f.lastUpdate(NetworkCadence).IsZero())
+	f.updateChip(NetworkCadence, 1)
+	updated := f.lastUpdate(NetworkCadence)
+	assert.False(t, updated.IsZero())
+
+	f.updateChip(NetworkCadence, 1)
+	assert.Equal(t, updated, f.lastUpdate(NetworkCadence))
+	f.updateChip(NetworkCadence, 0)
+	assert.False(t, f.lastUpdate(NetworkCadence).Before(updated))
+}
@@ -0,0 +1,132 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+	"huawei.com/npu-exporter/v5/devmanager/faultcode"
+)
+
+// baseGroup the common info of the chip
+type baseGroup struct {
+	name            *prometheus.Desc
+	utilization     *prometheus.Desc
+	temperature     *prometheus.Desc
+	power           *prometheus.Desc
+	voltage         *prometheus.Desc
+	healthStatus    *prometheus.Desc
+	errorCode       *prometheus.Desc
+	errorCodeCount  *prometheus.Desc
+	errorCodeActive *prometheus.Desc
+	errorCodeInfo   *prometheus.Desc
+	aiCoreFreq      *prometheus.Desc
+}
+
+func newBaseGroup() *baseGroup {
+	return &baseGroup{
+		name: newDesc("npu_chip_info_name",
+			"the Ascend npu name with value '1'", []string{npuID, "name", npuUUID, npuPCIEInfo}),
+		utilization:  newChipDesc("npu_chip_info_utilization", "the ai core utilization"),
+		temperature:  newChipDesc("npu_chip_info_temperature", "the npu temperature"),
+		power:        newChipDesc("npu_chip_info_power", "the npu power"),
+		voltage:      newChipDesc("npu_chip_info_voltage", "the npu voltage"),
+		healthStatus: newChipDesc("npu_chip_info_health_status", "the npu health status"),
+		errorCode:    newChipDesc("npu_chip_info_error_code", "the npu error code"),
+		errorCodeCount: newChipDesc("npu_chip_info_error_code_count",
+			"the number of the npu error codes"),
+		errorCodeActive: newChipDesc("npu_chip_info_error_code_active",
+			"the npu error code which is currently active with value '1'", errorCode),
+		errorCodeInfo: newChipDesc("npu_chip_error_code_info",
+			"the decoded information of the active npu error code with value '1'", errorCode, faultModule,
+			faultSeverity, faultDescription, faultSuggestion),
+		aiCoreFreq: newChipDesc("npu_chip_info_aicore_current_freq",
+			"the npu ai core current frequency, unit is 'MHz'"),
+	}
+}
+
+// Name implements MetricGroup
+func (g *baseGroup) Name() string {
+	return BaseGroup
+}
+
+// Cadence implements MetricGroup
+func (g *baseGroup) Cadence() Cadence {
+	return ChipCadence
+}
+
+// Describe implements MetricGroup
+func (g *baseGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.utilization
+	ch <- g.temperature
+	ch <- g.power
+	ch <- g.voltage
+	ch <- g.healthStatus
+	ch <- g.errorCode
+	ch <- g.errorCodeCount
+	ch <- g.errorCodeActive
+	ch <- g.errorCodeInfo
+	ch <- g.name
+	ch <- g.aiCoreFreq
+}
+
+// Collect implements MetricGroup
+func (g *baseGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	_ container.DevicesInfo) {
+	if !validate(ch, npu, chip, chip.ChipIfo) {
+		hwlog.RunLog.Error("Invalid param in function updateNpuCommonInfo")
+		return
+	}
+	labels := chipLabelValues(chip)
+	sendGauge(ch, npu, g.utilization, chip.valueOf(fieldUtilization, float64(chip.Utilization)), labels)
+	sendGauge(ch, npu, g.temperature, chip.valueOf(fieldTemperature, float64(chip.Temperature)), labels)
+	sendGauge(ch, npu, g.power, chip.valueOf(fieldPower, float64(chip.Power)), labels)
+	sendGauge(ch, npu, g.voltage, chip.valueOf(fieldVoltage, float64(chip.Voltage)), labels)
+	sendGauge(ch, npu, g.healthStatus, chip.valueOf(fieldHealthStatus, float64(getHealthCode(chip.HealthStatus))),
+		labels)
+	sendGauge(ch, npu, g.errorCode, chip.valueOf(fieldErrorCode, float64(chip.ErrorCode)), labels)
+	sendGauge(ch, npu, g.name, 1, labels)
+	sendGauge(ch, npu, g.aiCoreFreq, chip.valueOf(fieldAICoreFreq, float64(chip.AICoreCurrentFreq)), labels)
+	g.collectErrorCodes(ch, npu, chip)
+}
+
+func (g *baseGroup) collectErrorCodes(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip) {
+	sendGauge(ch, npu, g.errorCodeCount, chip.valueOf(fieldErrorCode, float64(len(chip.ErrorCodes))),
+		chipLabelValues(chip))
+	for _, code := range chip.ErrorCodes {
+		sendGauge(ch, npu, g.errorCodeActive, 1, chipLabelValues(chip, common.GetErrorCodeName(code)))
+		faultCode := faultcode.Decode(code)
+		sendGauge(ch, npu, g.errorCodeInfo, 1, chipLabelValues(chip, faultCode.Code, faultCode.Module,
+			faultCode.Severity, faultCode.Description, faultCode.Suggestion))
+	}
+}
+
+// Fields implements MetricGroup
+func (g *baseGroup) Fields(chip *HuaWeiAIChip, fields map[string]interface{}) {
+	chip.setField(fields, fieldHealthStatus, "npu_chip_info_health_status", getHealthCode(chip.HealthStatus))
+	chip.setField(fields, fieldTemperature, "npu_chip_info_temperature", float64(chip.Temperature))
+	chip.setField(fields, fieldUtilization, "npu_chip_info_utilization", float64(chip.Utilization))
+	chip.setField(fields, fieldPower, "npu_chip_info_power", chip.Power)
+	chip.setField(fields, fieldErrorCode, "npu_chip_info_error_code_count", len(chip.ErrorCodes))
+	for i, code := range chip.ErrorCodes {
+		fields["npu_chip_info_error_code_"+strconv.Itoa(i)] = code
+	}
+}
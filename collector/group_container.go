@@ -0,0 +1,98 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// containerGroup the npu info of the containers
+type containerGroup struct {
+	info        *prometheus.Desc
+	totalMemory *prometheus.Desc
+	usedMemory  *prometheus.Desc
+	utilization *prometheus.Desc
+}
+
+func newContainerGroup() *containerGroup {
+	containerLabels := []string{npuID, namespace, podName, "container_name", modelName, npuUUID, npuPCIEInfo}
+	return &containerGroup{
+		info: newDesc("npu_container_info",
+			"the container name and deviceID relationship", []string{"containerID", "containerName", "npuID",
+				modelName, npuUUID, npuPCIEInfo}),
+		totalMemory: newDesc("container_npu_total_memory",
+			"the npu total memory in container, unit is 'MB'", containerLabels),
+		usedMemory: newDesc("container_npu_used_memory",
+			"the npu used memory in container, unit is 'MB'", containerLabels),
+		utilization: newDesc("container_npu_utilization",
+			"the npu ai core utilization in container, unit is '%'", containerLabels),
+	}
+}
+
+// Name implements MetricGroup
+func (g *containerGroup) Name() string {
+	return ContainerGroup
+}
+
+// Cadence implements MetricGroup
+func (g *containerGroup) Cadence() Cadence {
+	return ContainerCadence
+}
+
+// Describe implements MetricGroup
+func (g *containerGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.info
+	ch <- g.totalMemory
+	ch <- g.usedMemory
+	ch <- g.utilization
+}
+
+// Collect implements MetricGroup
+func (g *containerGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	devInfo container.DevicesInfo) {
+	containerName := getContainerNameArray(devInfo)
+	if len(containerName) != containerNameLen {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(g.info, prometheus.GaugeValue, 1,
+		[]string{devInfo.ID, strings.Join(containerName, "_"), strconv.Itoa(chip.DeviceID),
+			common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...)
+	if common.IsValidVDevID(chip.VDevActivityInfo.VDevID) {
+		return
+	}
+	labels := []string{strconv.FormatInt(int64(chip.DeviceID), base), containerName[nameSpaceIdx],
+		containerName[podNameIdx], containerName[conNameIdx], common.GetNpuName(*chip.ChipIfo), chip.VDieID,
+		chip.PCIeBusInfo}
+	if strings.Contains(chip.ChipIfo.Name, common.Chip910) {
+		sendGauge(ch, npu, g.totalMemory, chip.valueOf(fieldHbm, float64(chip.HbmInfo.MemorySize)), labels)
+		sendGauge(ch, npu, g.usedMemory, chip.valueOf(fieldHbm, float64(chip.HbmInfo.Usage)), labels)
+	} else {
+		sendGauge(ch, npu, g.totalMemory, chip.valueOf(fieldMemory, float64(chip.Meminf.MemorySize)), labels)
+		sendGauge(ch, npu, g.usedMemory, chip.valueOf(fieldMemory,
+			float64(chip.Meminf.MemorySize-chip.Meminf.MemoryAvailable)), labels)
+	}
+	sendGauge(ch, npu, g.utilization, chip.valueOf(fieldUtilization, float64(chip.Utilization)), labels)
+}
+
+// Fields implements MetricGroup, the container relationship is not reported to telegraf
+func (g *containerGroup) Fields(_ *HuaWeiAIChip, _ map[string]interface{}) {
+}
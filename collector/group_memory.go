@@ -0,0 +1,87 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+const mega = 1024 * 1024
+
+// memoryGroup the memory and hbm info of the chip
+type memoryGroup struct {
+	hbmUsedMemory  *prometheus.Desc
+	hbmTotalMemory *prometheus.Desc
+	hbmUtilization *prometheus.Desc
+	usedMemory     *prometheus.Desc
+	totalMemory    *prometheus.Desc
+}
+
+func newMemoryGroup() *memoryGroup {
+	return &memoryGroup{
+		hbmUsedMemory:  newChipDesc("npu_chip_info_hbm_used_memory", "the npu hbm used memory"),
+		hbmTotalMemory: newChipDesc("npu_chip_info_hbm_total_memory", "the npu hbm total memory"),
+		hbmUtilization: newChipDesc("npu_chip_info_hbm_utilization", "the npu hbm utilization, unit is '%'"),
+		usedMemory:     newChipDesc("npu_chip_info_used_memory", "the npu used memory"),
+		totalMemory:    newChipDesc("npu_chip_info_total_memory", "the npu total memory"),
+	}
+}
+
+// Name implements MetricGroup
+func (g *memoryGroup) Name() string {
+	return MemoryGroup
+}
+
+// Cadence implements MetricGroup
+func (g *memoryGroup) Cadence() Cadence {
+	return ChipCadence
+}
+
+// Describe implements MetricGroup
+func (g *memoryGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.hbmUsedMemory
+	ch <- g.hbmTotalMemory
+	ch <- g.hbmUtilization
+	ch <- g.usedMemory
+	ch <- g.totalMemory
+}
+
+// Collect implements MetricGroup
+func (g *memoryGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	_ container.DevicesInfo) {
+	if !validate(ch, npu, chip, chip.HbmInfo, chip.Meminf) {
+		hwlog.RunLog.Error("Invalid param in function updateNPUMemoryInfo")
+		return
+	}
+	labels := chipLabelValues(chip)
+	sendGauge(ch, npu, g.hbmUsedMemory, chip.valueOf(fieldHbm, float64(chip.HbmInfo.Usage)), labels)
+	sendGauge(ch, npu, g.hbmTotalMemory, chip.valueOf(fieldHbm, float64(chip.HbmInfo.MemorySize)), labels)
+	sendGauge(ch, npu, g.hbmUtilization, chip.valueOf(fieldHbmUtilization, float64(chip.HbmUtilization)), labels)
+	sendGauge(ch, npu, g.usedMemory, chip.valueOf(fieldMemory,
+		float64(chip.Meminf.MemorySize-chip.Meminf.MemoryAvailable)), labels)
+	sendGauge(ch, npu, g.totalMemory, chip.valueOf(fieldMemory, float64(chip.Meminf.MemorySize)), labels)
+}
+
+// Fields implements MetricGroup
+func (g *memoryGroup) Fields(chip *HuaWeiAIChip, fields map[string]interface{}) {
+	chip.setField(fields, fieldHbmUtilization, "npu_chip_info_hbm_utilization", float64(chip.HbmUtilization))
+	if chip.HbmInfo != nil {
+		chip.setField(fields, fieldHbm, "npu_chip_info_hbm_used_memory", chip.HbmInfo.Usage*mega)
+	}
+}
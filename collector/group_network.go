@@ -0,0 +1,272 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager/hccn"
+)
+
+const (
+	counterSuffix = "_total"
+	rateSuffix    = "_per_second"
+)
+
+// legacyStatGauges whether the statistics are exported as the legacy gauges besides the counters, 1 means true
+var legacyStatGauges int32 = 1
+
+// SetLegacyStatGauges export the mac and roce statistics as the legacy *_num gauges besides the *_total counters,
+// so that the existing dashboards keep working. It should be called before the collector is registered
+func SetLegacyStatGauges(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&legacyStatGauges, value)
+}
+
+func legacyStatGaugesEnabled() bool {
+	return atomic.LoadInt32(&legacyStatGauges) == 1
+}
+
+// statField a cumulative mac or roce statistic of the driver. The legacy name is used as both the legacy gauge
+// name and the telegraf field, the rate is only computed for the error statistics
+type statField struct {
+	name        string
+	counterName string
+	help        string
+	rateName    string
+	value       func(info *StatInfo) float64
+}
+
+func newStatField(name, counterName, help string, value func(info *StatInfo) float64) statField {
+	return statField{name: name, counterName: counterName, help: help, value: value}
+}
+
+// newErrorStatField the error statistic, whose per-second rate is exported as well
+func newErrorStatField(name, counterName, help string, value func(info *StatInfo) float64) statField {
+	field := newStatField(name, counterName, help, value)
+	field.rateName = strings.TrimSuffix(counterName, counterSuffix) + rateSuffix
+	return field
+}
+
+// statMetric the descriptors of a statistic
+type statMetric struct {
+	statField
+	desc        *prometheus.Desc
+	counterDesc *prometheus.Desc
+	rateDesc    *prometheus.Desc
+}
+
+func newStatMetric(field statField) statMetric {
+	stat := statMetric{
+		statField:   field,
+		desc:        newChipDesc(field.name, "the npu interface receive "+statLegacyHelp(field.name)),
+		counterDesc: newChipDesc(field.counterName, field.help),
+	}
+	if field.rateName != "" {
+		stat.rateDesc = newChipDesc(field.rateName,
+			strings.Replace(field.help, "the total number", "the per-second rate", 1)+" since the last query")
+	}
+	return stat
+}
+
+// statLegacyHelp the legacy help is the hyphenated name, eg: mac-rx-pause-num
+func statLegacyHelp(name string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(name, "npu_chip_"), "_", "-")
+}
+
+// networkGroup the network health, link, bandwidth, mac and roce statistics of the chip
+type networkGroup struct {
+	networkStatus *prometheus.Desc
+	linkStatus    *prometheus.Desc
+	bandwidthTx   *prometheus.Desc
+	bandwidthRx   *prometheus.Desc
+	linkSpeed     *prometheus.Desc
+	linkUpNum     *prometheus.Desc
+	stats         []statMetric
+}
+
+func newNetworkGroup() *networkGroup {
+	return &networkGroup{
+		networkStatus: newChipDesc("npu_chip_info_network_status", "the npu network health status"),
+		linkStatus:    newChipDesc("npu_chip_info_link_status", "the npu link status"),
+		bandwidthTx: newChipDesc("npu_chip_info_bandwidth_tx",
+			"the npu interface transport speed, unit is 'MB/s'"),
+		bandwidthRx: newChipDesc("npu_chip_info_bandwidth_rx",
+			"the npu interface receive speed, unit is 'MB/s'"),
+		linkSpeed: newChipDesc("npu_chip_link_speed", "the npu interface receive link speed, unit is 'Mb/s'"),
+		linkUpNum: newChipDesc("npu_chip_link_up_num", "the npu interface receive link-up num"),
+		stats:     newStatMetrics(),
+	}
+}
+
+// statFields the mac and roce statistics, the descriptors are built by the network group only
+var statFields = []statField{
+	newStatField("npu_chip_mac_rx_pause_num", "npu_chip_mac_rx_pause_frames_total",
+		"the total number of pause frames received by the mac",
+		func(info *StatInfo) float64 { return info.MacRxPauseNum }),
+	newStatField("npu_chip_mac_tx_pause_num", "npu_chip_mac_tx_pause_frames_total",
+		"the total number of pause frames sent by the mac",
+		func(info *StatInfo) float64 { return info.MacTxPauseNum }),
+	newStatField("npu_chip_mac_rx_pfc_pkt_num", "npu_chip_mac_rx_pfc_packets_total",
+		"the total number of pfc frames received by the mac",
+		func(info *StatInfo) float64 { return info.MacRxPfcPktNum }),
+	newStatField("npu_chip_mac_tx_pfc_pkt_num", "npu_chip_mac_tx_pfc_packets_total",
+		"the total number of pfc frames sent by the mac",
+		func(info *StatInfo) float64 { return info.MacTxPfcPktNum }),
+	newErrorStatField("npu_chip_mac_rx_bad_pkt_num", "npu_chip_mac_rx_bad_packets_total",
+		"the total number of bad packets received by the mac",
+		func(info *StatInfo) float64 { return info.MacRxBadPktNum }),
+	newErrorStatField("npu_chip_mac_tx_bad_pkt_num", "npu_chip_mac_tx_bad_packets_total",
+		"the total number of bad packets sent by the mac",
+		func(info *StatInfo) float64 { return info.MacTxBadPktNum }),
+	newErrorStatField("npu_chip_mac_tx_bad_oct_num", "npu_chip_mac_tx_bad_bytes_total",
+		"the total number of bytes of the bad packets sent by the mac",
+		func(info *StatInfo) float64 { return info.MacTxBadOctNum }),
+	newErrorStatField("npu_chip_mac_rx_bad_oct_num", "npu_chip_mac_rx_bad_bytes_total",
+		"the total number of bytes of the bad packets received by the mac",
+		func(info *StatInfo) float64 { return info.MacRxBadOctNum }),
+	newStatField("npu_chip_roce_rx_all_pkt_num", "npu_chip_roce_rx_packets_total",
+		"the total number of packets received by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceRxAllPktNum }),
+	newStatField("npu_chip_roce_tx_all_pkt_num", "npu_chip_roce_tx_packets_total",
+		"the total number of packets sent by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceTxAllPktNum }),
+	newErrorStatField("npu_chip_roce_rx_err_pkt_num", "npu_chip_roce_rx_err_packets_total",
+		"the total number of bad packets received by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceRxErrPktNum }),
+	newErrorStatField("npu_chip_roce_tx_err_pkt_num", "npu_chip_roce_tx_err_packets_total",
+		"the total number of bad packets sent by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceTxErrPktNum }),
+	newStatField("npu_chip_roce_rx_cnp_pkt_num", "npu_chip_roce_rx_cnp_packets_total",
+		"the total number of cnp packets received by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceRxCnpPktNum }),
+	newStatField("npu_chip_roce_tx_cnp_pkt_num", "npu_chip_roce_tx_cnp_packets_total",
+		"the total number of cnp packets sent by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceTxCnpPktNum }),
+	newErrorStatField("npu_chip_roce_new_pkt_rty_num", "npu_chip_roce_new_packet_retries_total",
+		"the total number of retried packets of the roce network card",
+		func(info *StatInfo) float64 { return info.RoceNewPktRtyNum }),
+	newErrorStatField("npu_chip_roce_unexpected_ack_num", "npu_chip_roce_unexpected_acks_total",
+		"the total number of unexpected acks received by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceUnexpectedAckNum }),
+	newErrorStatField("npu_chip_roce_out_of_order_num", "npu_chip_roce_out_of_order_packets_total",
+		"the total number of out-of-order packets received by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceOutOfOrderNum }),
+	newErrorStatField("npu_chip_roce_verification_err_num", "npu_chip_roce_verification_errors_total",
+		"the total number of packets with verification errors received by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceVerificationErrNum }),
+	newErrorStatField("npu_chip_roce_qp_status_err_num", "npu_chip_roce_qp_status_errors_total",
+		"the total number of packets of the abnormal qp connections received by the roce network card",
+		func(info *StatInfo) float64 { return info.RoceQpStatusErrNum }),
+}
+
+func newStatMetrics() []statMetric {
+	stats := make([]statMetric, 0, len(statFields))
+	for _, field := range statFields {
+		stats = append(stats, newStatMetric(field))
+	}
+	return stats
+}
+
+// Name implements MetricGroup
+func (g *networkGroup) Name() string {
+	return NetworkGroup
+}
+
+// Cadence implements MetricGroup
+func (g *networkGroup) Cadence() Cadence {
+	return NetworkCadence
+}
+
+// Describe implements MetricGroup
+func (g *networkGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.networkStatus
+	ch <- g.bandwidthTx
+	ch <- g.bandwidthRx
+	ch <- g.linkStatus
+	ch <- g.linkSpeed
+	ch <- g.linkUpNum
+	for _, stat := range g.stats {
+		ch <- stat.counterDesc
+		if stat.rateDesc != nil {
+			ch <- stat.rateDesc
+		}
+		if legacyStatGaugesEnabled() {
+			ch <- stat.desc
+		}
+	}
+}
+
+// Collect implements MetricGroup
+func (g *networkGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	_ container.DevicesInfo) {
+	if !validate(ch, npu, chip, chip.NetInfo) {
+		hwlog.RunLog.Error("Invalid param in function updateNPUNetworkInfo")
+		return
+	}
+	labels := chipLabelValues(chip)
+	legacy := legacyStatGaugesEnabled()
+	for _, stat := range g.stats {
+		value := stat.value(&chip.NetInfo.StatInfo)
+		if legacy {
+			sendGauge(ch, npu, stat.desc, value, labels)
+		}
+		// the failed query is not exported as zero, which would be taken as a counter reset
+		if !chip.NetInfo.StatQueried {
+			continue
+		}
+		sendCounter(ch, npu, stat.counterDesc, value, labels)
+		if rate, ok := chip.NetInfo.StatRates[stat.rateName]; ok && stat.rateDesc != nil {
+			sendGauge(ch, npu, stat.rateDesc, rate, labels)
+		}
+	}
+	sendGauge(ch, npu, g.linkStatus, float64(hccn.GetLinkStatusCode(chip.LinkStatus)), labels)
+	sendGauge(ch, npu, g.bandwidthTx, chip.NetInfo.BandwidthInfo.TxValue, labels)
+	sendGauge(ch, npu, g.bandwidthRx, chip.NetInfo.BandwidthInfo.RxValue, labels)
+	sendGauge(ch, npu, g.networkStatus, chip.valueOf(fieldNetworkStatus, float64(getHealthCode(chip.NetHealthStatus))),
+		labels)
+	sendGauge(ch, npu, g.linkSpeed, chip.NetInfo.LinkSpeedInfo.Speed, labels)
+	sendGauge(ch, npu, g.linkUpNum, chip.NetInfo.LinkStatInfo.LinkUPNum, labels)
+}
+
+// Fields implements MetricGroup
+func (g *networkGroup) Fields(chip *HuaWeiAIChip, fields map[string]interface{}) {
+	chip.setField(fields, fieldNetworkStatus, "npu_chip_info_network_status", getHealthCode(chip.NetHealthStatus))
+	fields["npu_chip_info_link_status"] = hccn.GetLinkStatusCode(chip.LinkStatus)
+	if chip.NetInfo == nil {
+		return
+	}
+	fields["npu_chip_info_bandwidth_rx"] = chip.NetInfo.BandwidthInfo.RxValue * mega
+	fields["npu_chip_info_bandwidth_tx"] = chip.NetInfo.BandwidthInfo.TxValue * mega
+	fields["npu_chip_link_speed"] = int(chip.NetInfo.LinkSpeedInfo.Speed) * mega
+	fields["npu_chip_link_up_num"] = int(chip.NetInfo.LinkStatInfo.LinkUPNum)
+	// the statistics are integers in telegraf as before
+	for _, stat := range g.stats {
+		fields[stat.name] = int(stat.value(&chip.NetInfo.StatInfo))
+		// the rates are computed by the exporter, because telegraf has no rate function like promql
+		if rate, ok := chip.NetInfo.StatRates[stat.rateName]; ok && stat.rateName != "" {
+			fields[stat.rateName] = rate
+		}
+	}
+}
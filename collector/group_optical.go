@@ -0,0 +1,111 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+// opticalMetric an optical module value, the name is used as both the metric name and the telegraf field
+type opticalMetric struct {
+	name  string
+	desc  *prometheus.Desc
+	value func(info *OpticalInfo) float64
+}
+
+func newOpticalMetric(name, help string, value func(info *OpticalInfo) float64) opticalMetric {
+	return opticalMetric{name: name, desc: newChipDesc(name, help), value: value}
+}
+
+// opticalGroup the optical module info of the chip
+type opticalGroup struct {
+	state   *prometheus.Desc
+	metrics []opticalMetric
+}
+
+func newOpticalGroup() *opticalGroup {
+	return &opticalGroup{
+		state: newChipDesc("npu_chip_optical_state", "the npu interface receive optical-state"),
+		metrics: []opticalMetric{
+			newOpticalMetric("npu_chip_optical_tx_power_0", "the npu interface receive optical-tx-power-0",
+				func(info *OpticalInfo) float64 { return info.OpticalTxPower0 }),
+			newOpticalMetric("npu_chip_optical_tx_power_1", "the npu interface receive optical-tx-power-1",
+				func(info *OpticalInfo) float64 { return info.OpticalTxPower1 }),
+			newOpticalMetric("npu_chip_optical_tx_power_2", "the npu interface receive optical-tx-power-2",
+				func(info *OpticalInfo) float64 { return info.OpticalTxPower2 }),
+			newOpticalMetric("npu_chip_optical_tx_power_3", "the npu interface receive optical-tx-power-3",
+				func(info *OpticalInfo) float64 { return info.OpticalTxPower3 }),
+			newOpticalMetric("npu_chip_optical_rx_power_0", "the npu interface receive optical-rx-power-0",
+				func(info *OpticalInfo) float64 { return info.OpticalRxPower0 }),
+			newOpticalMetric("npu_chip_optical_rx_power_1", "the npu interface receive optical-rx-power-1",
+				func(info *OpticalInfo) float64 { return info.OpticalRxPower1 }),
+			newOpticalMetric("npu_chip_optical_rx_power_2", "the npu interface receive optical-rx-power-2",
+				func(info *OpticalInfo) float64 { return info.OpticalRxPower2 }),
+			newOpticalMetric("npu_chip_optical_rx_power_3", "the npu interface receive optical-rx-power-3",
+				func(info *OpticalInfo) float64 { return info.OpticalRxPower3 }),
+			newOpticalMetric("npu_chip_optical_vcc", "the npu interface receive optical-vcc",
+				func(info *OpticalInfo) float64 { return info.OpticalVcc }),
+			newOpticalMetric("npu_chip_optical_temp", "the npu interface receive optical-temperature",
+				func(info *OpticalInfo) float64 { return info.OpticalTemp }),
+		},
+	}
+}
+
+// Name implements MetricGroup
+func (g *opticalGroup) Name() string {
+	return OpticalGroup
+}
+
+// Cadence implements MetricGroup
+func (g *opticalGroup) Cadence() Cadence {
+	return NetworkCadence
+}
+
+// Describe implements MetricGroup
+func (g *opticalGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.state
+	for _, metric := range g.metrics {
+		ch <- metric.desc
+	}
+}
+
+// Collect implements MetricGroup
+func (g *opticalGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	_ container.DevicesInfo) {
+	if !validate(ch, npu, chip, chip.NetInfo) {
+		hwlog.RunLog.Error("Invalid param in function updateOpticalInfo")
+		return
+	}
+	labels := chipLabelValues(chip)
+	sendGauge(ch, npu, g.state, chip.NetInfo.OpticalInfo.OpticalState, labels)
+	for _, metric := range g.metrics {
+		sendGauge(ch, npu, metric.desc, metric.value(&chip.NetInfo.OpticalInfo), labels)
+	}
+}
+
+// Fields implements MetricGroup
+func (g *opticalGroup) Fields(chip *HuaWeiAIChip, fields map[string]interface{}) {
+	if chip.NetInfo == nil {
+		return
+	}
+	fields["npu_chip_optical_state"] = int(chip.NetInfo.OpticalInfo.OpticalState)
+	for _, metric := range g.metrics {
+		fields[metric.name] = metric.value(&chip.NetInfo.OpticalInfo)
+	}
+}
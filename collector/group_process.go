@@ -0,0 +1,89 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// processGroup the processes running on the chip
+type processGroup struct {
+	processInfo *prometheus.Desc
+}
+
+func newProcessGroup() *processGroup {
+	return &processGroup{
+		processInfo: newDesc("npu_chip_info_process_info",
+			"the npu process info, unit is 'MB'. if process run on host, container_id and container_name will be empty",
+			[]string{npuID, modelName, npuUUID, "process_id", "container_id", "container_name", npuPCIEInfo}),
+	}
+}
+
+// Name implements MetricGroup
+func (g *processGroup) Name() string {
+	return ProcessGroup
+}
+
+// Cadence implements MetricGroup, the processes are queried with the chip info
+func (g *processGroup) Cadence() Cadence {
+	return ChipCadence
+}
+
+// Describe implements MetricGroup
+func (g *processGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.processInfo
+}
+
+// Collect implements MetricGroup
+func (g *processGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	devInfo container.DevicesInfo) {
+	if chip.DevProcessInfo == nil {
+		return
+	}
+	containerName := ""
+	containerID := ""
+	cNameArray := getContainerNameArray(devInfo)
+	if len(cNameArray) == containerNameLen {
+		containerName = strings.Join(cNameArray, "_")
+		containerID = devInfo.ID
+	}
+	processLabels := func(pid string) []string {
+		return []string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo),
+			chip.VDieID, pid, containerID, containerName, chip.PCIeBusInfo}
+	}
+	if chip.DevProcessInfo.ProcNum == 0 {
+		sendGauge(ch, npu, g.processInfo, chip.valueOf(fieldProcess, 0), processLabels(""))
+		return
+	}
+	for i := int32(0); i < chip.DevProcessInfo.ProcNum; i++ {
+		procInfo := chip.DevProcessInfo.DevProcArray[i]
+		sendGauge(ch, npu, g.processInfo, procInfo.MemUsage,
+			processLabels(strconv.FormatInt(int64(procInfo.Pid), base)))
+	}
+}
+
+// Fields implements MetricGroup
+func (g *processGroup) Fields(chip *HuaWeiAIChip, fields map[string]interface{}) {
+	if chip.DevProcessInfo != nil {
+		chip.setField(fields, fieldProcess, "npu_chip_info_process_info_num", chip.DevProcessInfo.ProcNum)
+	}
+}
@@ -0,0 +1,82 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// vnpuGroup the vnpu info of the pods
+type vnpuGroup struct {
+	aiCoreUtilization *prometheus.Desc
+	totalMemory       *prometheus.Desc
+	usedMemory        *prometheus.Desc
+}
+
+func newVNPUGroup() *vnpuGroup {
+	podLabels := []string{npuID, modelName, vNpuUUID, "aicore_count", namespace, podName, "container_name",
+		isVirtual}
+	return &vnpuGroup{
+		aiCoreUtilization: newDesc("vnpu_pod_aicore_utilization",
+			"the vnpu aicore utilization rate, unit is '%'", podLabels),
+		totalMemory: newDesc("vnpu_pod_total_memory", "the vnpu total memory on pod, unit is 'KB'",
+			podLabels),
+		usedMemory: newDesc("vnpu_pod_used_memory", "the vnpu used memory on pod, unit is 'KB'",
+			podLabels),
+	}
+}
+
+// Name implements MetricGroup
+func (g *vnpuGroup) Name() string {
+	return VNPUGroup
+}
+
+// Cadence implements MetricGroup, the activity of the vnpus is queried with the chip info
+func (g *vnpuGroup) Cadence() Cadence {
+	return ChipCadence
+}
+
+// Describe implements MetricGroup
+func (g *vnpuGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.aiCoreUtilization
+	ch <- g.totalMemory
+	ch <- g.usedMemory
+}
+
+// Collect implements MetricGroup
+func (g *vnpuGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	devInfo container.DevicesInfo) {
+	if !strings.Contains(chip.ChipIfo.Name, "310P") || !common.IsValidVDevID(chip.VDevActivityInfo.VDevID) {
+		return
+	}
+	containerName := getContainerNameArray(devInfo)
+	if len(containerName) != containerNameLen {
+		return
+	}
+	labels := getPodDisplayInfo(chip, containerName)
+	sendGauge(ch, npu, g.aiCoreUtilization, float64(chip.VDevActivityInfo.VDevAiCoreRate), labels)
+	sendGauge(ch, npu, g.totalMemory, float64(chip.VDevActivityInfo.VDevTotalMem), labels)
+	sendGauge(ch, npu, g.usedMemory, float64(chip.VDevActivityInfo.VDevUsedMem), labels)
+}
+
+// Fields implements MetricGroup, the vnpu of the pods is not reported to telegraf
+func (g *vnpuGroup) Fields(_ *HuaWeiAIChip, _ map[string]interface{}) {
+}
@@ -0,0 +1,268 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+	"huawei.com/npu-exporter/v5/devmanager/dcmi"
+)
+
+// staticField the static info of a chip which is queried separately
+type staticField uint8
+
+const (
+	staticChipInfo staticField = 1 << iota
+	staticBoardInfo
+	staticVDieID
+	staticPCIeBusInfo
+
+	allStaticFields = staticChipInfo | staticBoardInfo | staticVDieID | staticPCIeBusInfo
+)
+
+const (
+	inventoryAdded   = "added"
+	inventoryRemoved = "removed"
+)
+
+// chipInventory the static info of a chip, which does not change during the device lifecycle
+type chipInventory struct {
+	cardID      int32
+	logicID     int32
+	phyID       int32
+	chipInfo    *common.ChipInfo
+	boardInfo   common.BoardInfo
+	vdieID      string
+	pcieBusInfo string
+	// failed the static fields whose query failed, they are queried again on the next refresh
+	failed staticField
+}
+
+// DeviceInventory caches the static info of the chips, the static info of a chip is queried only when the chip is
+// found for the first time
+type DeviceInventory struct {
+	lock  sync.RWMutex
+	chips []chipInventory
+	// the number of the chips which are added or removed after the first discovery
+	added   uint64
+	removed uint64
+}
+
+// inventoryChange the chips which are added or removed by a refresh
+type inventoryChange struct {
+	added   []chipInventory
+	removed []chipInventory
+}
+
+func (c inventoryChange) changed() bool {
+	return len(c.added) > 0 || len(c.removed) > 0
+}
+
+// NewDeviceInventory create an empty device inventory, the chips are discovered on the first use
+func NewDeviceInventory() *DeviceInventory {
+	return &DeviceInventory{}
+}
+
+// Refresh discover the chips of the cards, the cached static info is reused for the known chips
+func (inv *DeviceInventory) Refresh(dmgr devmanager.DeviceInterface) error {
+	_, err := inv.refresh(dmgr)
+	return err
+}
+
+// refresh discover the chips and get the chips which are added or removed since the last discovery, a chip whose
+// logic ID, card ID or phy ID is changed is treated as removed and added again
+func (inv *DeviceInventory) refresh(dmgr devmanager.DeviceInterface) (inventoryChange, error) {
+	var change inventoryChange
+	cardNum, cards, err := dmgr.GetCardList()
+	if err != nil || cardNum == 0 {
+		return change, fmt.Errorf("failed to get card list, error is: %v", err)
+	}
+	known := make(map[int32]chipInventory, initSize)
+	inv.lock.RLock()
+	for _, chip := range inv.chips {
+		known[chip.logicID] = chip
+	}
+	inv.lock.RUnlock()
+
+	chips := make([]chipInventory, 0, len(known))
+	for _, cardID := range cards {
+		deviceNum, err := dmgr.GetDeviceNumInCard(cardID)
+		if err != nil {
+			hwlog.RunLog.Errorf("get device num of card %v failed: %v", cardID, err)
+			continue
+		}
+		for i := int32(0); i < deviceNum; i++ {
+			logicID, err := dmgr.GetDeviceLogicID(cardID, i)
+			if err != nil {
+				hwlog.RunLog.Errorf("get logic ID of card %v device %v failed: %v", cardID, i, err)
+				continue
+			}
+			phyID, err := dmgr.GetPhysicIDFromLogicID(logicID)
+			if err != nil {
+				hwlog.RunLog.Errorf("get phy ID of chip %d failed: %v", logicID, err)
+				continue
+			}
+			// the chip which is reset may come back with the same logic ID but the other ids
+			if chip, ok := known[logicID]; ok && chip.sameChip(cardID, phyID) {
+				chip.queryStatic(dmgr, chip.failed)
+				chips = append(chips, chip)
+				continue
+			}
+			chip := queryChipInventory(cardID, logicID, phyID, dmgr)
+			chips = append(chips, chip)
+			change.added = append(change.added, chip)
+		}
+	}
+	if len(chips) == 0 {
+		return change, errors.New("no chip is found")
+	}
+	change.removed = removedChips(known, chips)
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	// the first discovery is not a change of the inventory
+	if len(inv.chips) > 0 && change.changed() {
+		inv.added += uint64(len(change.added))
+		inv.removed += uint64(len(change.removed))
+		hwlog.RunLog.Infof("the npu inventory is changed, added chips: %v, removed chips: %v",
+			logicIDsOf(change.added), logicIDsOf(change.removed))
+	}
+	inv.chips = chips
+	return change, nil
+}
+
+func (c chipInventory) sameChip(cardID, phyID int32) bool {
+	return c.cardID == cardID && c.phyID == phyID
+}
+
+func removedChips(known map[int32]chipInventory, chips []chipInventory) []chipInventory {
+	found := make(map[int32]chipInventory, len(chips))
+	for _, chip := range chips {
+		found[chip.logicID] = chip
+	}
+	var removed []chipInventory
+	for logicID, chip := range known {
+		if current, ok := found[logicID]; !ok || !current.sameChip(chip.cardID, chip.phyID) {
+			removed = append(removed, chip)
+		}
+	}
+	return removed
+}
+
+func logicIDsOf(chips []chipInventory) []int32 {
+	logicIDs := make([]int32, 0, len(chips))
+	for _, chip := range chips {
+		logicIDs = append(logicIDs, chip.logicID)
+	}
+	return logicIDs
+}
+
+// changes get the number of the chips which are added and removed after the first discovery
+func (inv *DeviceInventory) changes() (added, removed uint64) {
+	inv.lock.RLock()
+	defer inv.lock.RUnlock()
+	return inv.added, inv.removed
+}
+
+// list get the cached chips, the chips are discovered when the inventory is empty, and the static fields which
+// failed before are queried again
+func (inv *DeviceInventory) list(dmgr devmanager.DeviceInterface) []chipInventory {
+	inv.lock.RLock()
+	chips := inv.chips
+	inv.lock.RUnlock()
+	if len(chips) > 0 {
+		return inv.retryFailed(chips, dmgr)
+	}
+	if err := inv.Refresh(dmgr); err != nil {
+		hwlog.RunLog.Errorf("failed to discover npu, error is: %v", err)
+		return nil
+	}
+	inv.lock.RLock()
+	defer inv.lock.RUnlock()
+	return inv.chips
+}
+
+// retryFailed query the failed static fields of the chips again, the chips are returned as is when all are complete
+func (inv *DeviceInventory) retryFailed(chips []chipInventory, dmgr devmanager.DeviceInterface) []chipInventory {
+	copied := false
+	for i := range chips {
+		if chips[i].failed == 0 {
+			continue
+		}
+		if !copied {
+			chips = append([]chipInventory(nil), chips...)
+			copied = true
+		}
+		chips[i].queryStatic(dmgr, chips[i].failed)
+	}
+	if !copied {
+		return chips
+	}
+	inv.lock.Lock()
+	inv.chips = chips
+	inv.lock.Unlock()
+	return chips
+}
+
+func queryChipInventory(cardID, logicID, phyID int32, dmgr devmanager.DeviceInterface) chipInventory {
+	chip := chipInventory{cardID: cardID, logicID: logicID, phyID: phyID, chipInfo: &common.ChipInfo{}}
+	chip.queryStatic(dmgr, allStaticFields)
+	return chip
+}
+
+// queryStatic query the static fields of the chip, the fields which failed are recorded to be queried again
+func (chip *chipInventory) queryStatic(dmgr devmanager.DeviceInterface, fields staticField) {
+	if fields&staticChipInfo != 0 {
+		if chipInfo, err := dmgr.GetChipInfo(chip.logicID); err != nil {
+			hwlog.RunLog.Warnf("get chip info failed: %v", err)
+			chip.failed |= staticChipInfo
+		} else {
+			chip.chipInfo = chipInfo
+			chip.failed &^= staticChipInfo
+		}
+	}
+	if fields&staticBoardInfo != 0 {
+		if boardInfo, err := dmgr.GetBoardInfo(chip.logicID); err != nil {
+			hwlog.RunLog.Debugf("get board info failed: %v", err)
+			chip.failed |= staticBoardInfo
+		} else {
+			chip.boardInfo = boardInfo
+			chip.failed &^= staticBoardInfo
+		}
+	}
+	if fields&staticVDieID != 0 {
+		if vdieID, err := dmgr.GetDieID(chip.logicID, dcmi.VDIE); err != nil {
+			hwlog.RunLog.Debug(err)
+			chip.failed |= staticVDieID
+		} else {
+			chip.vdieID = vdieID
+			chip.failed &^= staticVDieID
+		}
+	}
+	if fields&staticPCIeBusInfo != 0 {
+		if pcieBusInfo, err := getPCIeBusInfo(chip.logicID, dmgr); err != nil {
+			hwlog.RunLog.Error(err)
+			chip.failed |= staticPCIeBusInfo
+		} else {
+			chip.pcieBusInfo = pcieBusInfo
+			chip.failed &^= staticPCIeBusInfo
+		}
+	}
+}
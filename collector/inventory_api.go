@@ -0,0 +1,348 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+const (
+	// InventoryAPIPath the url path prefix of the inventory api, eg: /api/v1/cards, /api/v1/chips/{id},
+	// /api/v1/containers and /api/v1/vnpus
+	InventoryAPIPath = "/api/v1/"
+
+	apiCards      = "cards"
+	apiChips      = "chips"
+	apiContainers = "containers"
+	apiVNPUs      = "vnpus"
+	// queryFields the comma separated fields of the response, the nested field is joined by dot, eg:
+	// fields=card_id,device_list.device_id
+	queryFields = "fields"
+)
+
+// Snapshot the cards and the containers of their chips in the cache
+type Snapshot struct {
+	Cards []HuaWeiNPUCard
+	// Containers the containers keyed by the physic id of the chip, or the id of the vnpu
+	Containers map[int]container.DevicesInfo
+}
+
+// ContainerInfo the container which the chips are mounted to
+type ContainerInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"pod_name"`
+	ContainerName string `json:"container_name"`
+	// Devices the physic ids of the chips, or the ids of the vnpus
+	Devices []int `json:"devices"`
+}
+
+// VNPUInfo the vnpu which is created on the 310P chip
+type VNPUInfo struct {
+	VDevID uint32 `json:"vdev_id"`
+	// DeviceID the physic id of the chip which the vnpu is created on
+	DeviceID          int            `json:"device_id"`
+	CardID            int            `json:"card_id"`
+	AICore            float64        `json:"aicore"`
+	AICoreUtilization uint32         `json:"aicore_utilization"`
+	TotalMemory       uint64         `json:"total_memory"`
+	UsedMemory        uint64         `json:"used_memory"`
+	IsVirtualDev      bool           `json:"is_virtual_dev"`
+	Container         *ContainerInfo `json:"container,omitempty"`
+}
+
+// Snapshot implements ManagedCollector, the chips are copied with the network info, so that they are not changed
+// by the following Collect
+func (n *npuCollector) Snapshot() Snapshot {
+	cached := n.loadCache()
+	snapshot := Snapshot{Cards: make([]HuaWeiNPUCard, 0, len(cached.npuList)), Containers: cached.containerMap}
+	for _, card := range cached.npuList {
+		chips := make([]*HuaWeiAIChip, 0, len(card.DeviceList))
+		for _, chip := range card.DeviceList {
+			copied := *chip
+			copied.NetInfo = nil
+			if netInfo, ok := cached.networkInfoMap[int32(chip.DeviceID)]; ok {
+				copied.NetInfo = &netInfo
+			}
+			chips = append(chips, &copied)
+		}
+		card.DeviceList = chips
+		snapshot.Cards = append(snapshot.Cards, card)
+	}
+	return snapshot
+}
+
+// containerKey the key of the chip in the containers, the virtual device is mounted by the id of the vnpu
+func containerKey(chip *HuaWeiAIChip) int {
+	if chip.VDevActivityInfo.IsVirtualDev {
+		return int(chip.VDevActivityInfo.VDevID)
+	}
+	return chip.DeviceID
+}
+
+func isVNPU(chip *HuaWeiAIChip) bool {
+	return common.IsValidVDevID(chip.VDevActivityInfo.VDevID)
+}
+
+// physicChips the chips of the card, the 310P chip which is split into the vnpus is listed once without the
+// activity of the vnpu
+func physicChips(card HuaWeiNPUCard) []*HuaWeiAIChip {
+	chips := make([]*HuaWeiAIChip, 0, len(card.DeviceList))
+	seen := make(map[int]bool, len(card.DeviceList))
+	for _, chip := range card.DeviceList {
+		if seen[chip.DeviceID] {
+			continue
+		}
+		seen[chip.DeviceID] = true
+		if isVNPU(chip) {
+			physic := *chip
+			physic.VDevActivityInfo = common.VDevActivityInfo{}
+			chip = &physic
+		}
+		chips = append(chips, chip)
+	}
+	return chips
+}
+
+func (s Snapshot) cards() []HuaWeiNPUCard {
+	cards := make([]HuaWeiNPUCard, 0, len(s.Cards))
+	for _, card := range s.Cards {
+		card.DeviceList = physicChips(card)
+		cards = append(cards, card)
+	}
+	return cards
+}
+
+func (s Snapshot) chips() []*HuaWeiAIChip {
+	chips := make([]*HuaWeiAIChip, 0, len(s.Cards))
+	for _, card := range s.Cards {
+		chips = append(chips, physicChips(card)...)
+	}
+	return chips
+}
+
+func (s Snapshot) chip(phyID int) *HuaWeiAIChip {
+	for _, chip := range s.chips() {
+		if chip.DeviceID == phyID {
+			return chip
+		}
+	}
+	return nil
+}
+
+func toContainerInfo(devInfo container.DevicesInfo) *ContainerInfo {
+	info := &ContainerInfo{ID: devInfo.ID, Name: devInfo.Name, Devices: devInfo.Devices}
+	if containerName := getContainerNameArray(devInfo); len(containerName) == containerNameLen {
+		info.Namespace = containerName[nameSpaceIdx]
+		info.PodName = containerName[podNameIdx]
+		info.ContainerName = containerName[conNameIdx]
+	}
+	return info
+}
+
+func (s Snapshot) containers() []*ContainerInfo {
+	containers := make([]*ContainerInfo, 0, len(s.Containers))
+	seen := make(map[string]bool, len(s.Containers))
+	for _, devInfo := range s.Containers {
+		if seen[devInfo.ID] {
+			continue
+		}
+		seen[devInfo.ID] = true
+		containers = append(containers, toContainerInfo(devInfo))
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].ID < containers[j].ID
+	})
+	return containers
+}
+
+func (s Snapshot) vnpus() []VNPUInfo {
+	vnpus := make([]VNPUInfo, 0, initSize)
+	for _, card := range s.Cards {
+		for _, chip := range card.DeviceList {
+			if !isVNPU(chip) {
+				continue
+			}
+			activity := chip.VDevActivityInfo
+			vnpu := VNPUInfo{VDevID: activity.VDevID, DeviceID: chip.DeviceID, CardID: card.CardID,
+				AICore: activity.VDevAiCore, AICoreUtilization: activity.VDevAiCoreRate,
+				TotalMemory: activity.VDevTotalMem, UsedMemory: activity.VDevUsedMem,
+				IsVirtualDev: activity.IsVirtualDev}
+			if devInfo, ok := s.Containers[containerKey(chip)]; ok {
+				vnpu.Container = toContainerInfo(devInfo)
+			}
+			vnpus = append(vnpus, vnpu)
+		}
+	}
+	return vnpus
+}
+
+// InventoryAPI serves the cards, chips, containers and vnpus in the cache of the collector as JSON
+type InventoryAPI struct {
+	collector ManagedCollector
+}
+
+// NewInventoryAPI create the inventory api of the collector
+func NewInventoryAPI(c ManagedCollector) *InventoryAPI {
+	return &InventoryAPI{collector: c}
+}
+
+// ServeHTTP query the inventory, eg: /api/v1/chips/0?fields=device_id,health_status,hbm_info.usage
+func (a *InventoryAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fields, err := parseFields(r.URL.Query().Get(queryFields))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid %s: %v", queryFields, err), http.StatusBadRequest)
+		return
+	}
+	resource := strings.TrimPrefix(r.URL.Path, InventoryAPIPath)
+	var data interface{}
+	switch {
+	case resource == apiCards:
+		data = a.collector.Snapshot().cards()
+	case resource == apiChips:
+		data = a.collector.Snapshot().chips()
+	case strings.HasPrefix(resource, apiChips+"/"):
+		value := strings.TrimPrefix(resource, apiChips+"/")
+		phyID, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid chip id: %s", value), http.StatusBadRequest)
+			return
+		}
+		chip := a.collector.Snapshot().chip(phyID)
+		if chip == nil {
+			http.Error(w, fmt.Sprintf("the chip %d is not found", phyID), http.StatusNotFound)
+			return
+		}
+		data = chip
+	case resource == apiContainers:
+		data = a.collector.Snapshot().containers()
+	case resource == apiVNPUs:
+		data = a.collector.Snapshot().vnpus()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	body, err := marshalFields(data, fields)
+	if err != nil {
+		hwlog.RunLog.Errorf("marshal the inventory %s failed: %v", resource, err)
+		http.Error(w, "marshal the inventory failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(body); err != nil {
+		hwlog.RunLog.Errorf("write inventory response failed: %v", err)
+	}
+}
+
+// fieldTree the selected fields, the nil subtree selects the whole field
+type fieldTree map[string]fieldTree
+
+func parseFields(value string) (fieldTree, error) {
+	if value == "" {
+		return nil, nil
+	}
+	tree := fieldTree{}
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		names := strings.Split(path, ".")
+		node := tree
+		for i, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("empty field name in %s", path)
+			}
+			child, ok := node[name]
+			if ok && child == nil {
+				// the whole field is selected already
+				break
+			}
+			if i == len(names)-1 {
+				node[name] = nil
+				break
+			}
+			if !ok {
+				child = fieldTree{}
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	if len(tree) == 0 {
+		return nil, errors.New("no field is given")
+	}
+	return tree, nil
+}
+
+// apply keep the selected fields of the objects, the fields are selected in each element of the arrays
+func (t fieldTree) apply(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		selected := make(map[string]interface{}, len(t))
+		for name, subtree := range t {
+			field, ok := v[name]
+			if !ok {
+				continue
+			}
+			if subtree != nil {
+				field = subtree.apply(field)
+			}
+			selected[name] = field
+		}
+		return selected
+	case []interface{}:
+		for i := range v {
+			v[i] = t.apply(v[i])
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// marshalFields marshal the data with the selected fields, all the fields are marshaled if none is selected
+func marshalFields(data interface{}, fields fieldTree) ([]byte, error) {
+	body, err := json.Marshal(data)
+	if err != nil || fields == nil {
+		return body, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// keep the precision of the integers, eg: the error codes
+	decoder.UseNumber()
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields.apply(value))
+}
@@ -0,0 +1,119 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+func newInventoryTestAPI(t *testing.T) *InventoryAPI {
+	groups, err := ParseMetricGroups(strings.Join([]string{BaseGroup, ContainerGroup, VNPUGroup}, ","))
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups})
+	chip910 := &HuaWeiAIChip{DeviceID: 0, HealthStatus: "Healthy", ChipIfo: &common.ChipInfo{Name: "910"},
+		HbmInfo: &common.HbmInfo{Usage: 1}, PCIeBusInfo: "0000:c1:00.0"}
+	chip310P := HuaWeiAIChip{DeviceID: 1, HealthStatus: "Healthy", ChipIfo: &common.ChipInfo{Name: "310P3"}}
+	vnpus := make([]*HuaWeiAIChip, 0, 2)
+	for _, vDevID := range []uint32{100, 101} {
+		vnpu := chip310P
+		vnpu.VDevActivityInfo = common.VDevActivityInfo{VDevID: vDevID, VDevTotalMem: 1024, VDevAiCore: 1,
+			IsVirtualDev: true}
+		vnpus = append(vnpus, &vnpu)
+	}
+	assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{CardID: 0, DeviceList: []*HuaWeiAIChip{chip910}},
+		{CardID: 1, DeviceList: vnpus}}, time.Minute))
+	assert.Nil(t, n.cache.Set(containersDevicesCacheKey, container.DevicesInfos{
+		"b": {ID: "b", Name: "default_pod-b_ctr", Devices: []int{100}},
+		"a": {ID: "a", Name: "default_pod-a_ctr", Devices: []int{0}},
+	}, time.Minute))
+	return NewInventoryAPI(n)
+}
+
+// TestInventoryAPI test the inventory is served from the cache with the selected fields
+func TestInventoryAPI(t *testing.T) {
+	api := newInventoryTestAPI(t)
+	for _, tc := range []struct {
+		method string
+		url    string
+		status int
+		body   string
+	}{
+		{url: "/api/v1/cards?fields=card_id,device_list.device_id", status: http.StatusOK,
+			body: `[{"card_id":0,"device_list":[{"device_id":0}]},{"card_id":1,"device_list":[{"device_id":1}]}]`},
+		{url: "/api/v1/chips?fields=device_id,v_dev_activity_info.VDevID", status: http.StatusOK,
+			body: `[{"device_id":0,"v_dev_activity_info":{"VDevID":0}},` +
+				`{"device_id":1,"v_dev_activity_info":{"VDevID":0}}]`},
+		{url: "/api/v1/chips/0?fields=device_id,hbm_info.memory_usage", status: http.StatusOK,
+			body: `{"device_id":0,"hbm_info":{"memory_usage":1}}`},
+		// the whole field is selected if it is given with its nested fields
+		{url: "/api/v1/chips/0?fields=hbm_info.memory_usage,hbm_info", status: http.StatusOK,
+			body: `{"hbm_info":{"memory_size":0,"hbm_frequency":0,"memory_usage":1,"hbm_temperature":0,` +
+				`"hbm_bandwidth_util":0}}`},
+		{url: "/api/v1/chips/0?fields=pcie_bus_info,net_info", status: http.StatusOK,
+			body: `{"pcie_bus_info":"0000:c1:00.0","net_info":null}`},
+		{url: "/api/v1/chips/9", status: http.StatusNotFound},
+		{url: "/api/v1/chips/x", status: http.StatusBadRequest},
+		{url: "/api/v1/containers?fields=id,pod_name,devices", status: http.StatusOK,
+			body: `[{"devices":[0],"id":"a","pod_name":"pod-a"},{"devices":[100],"id":"b","pod_name":"pod-b"}]`},
+		{url: "/api/v1/vnpus?fields=vdev_id,device_id,container.id", status: http.StatusOK,
+			body: `[{"container":{"id":"b"},"device_id":1,"vdev_id":100},{"device_id":1,"vdev_id":101}]`},
+		{url: "/api/v1/cards?fields=device_list..device_id", status: http.StatusBadRequest},
+		{url: "/api/v1/nodes", status: http.StatusNotFound},
+		{method: http.MethodPost, url: "/api/v1/cards", status: http.StatusMethodNotAllowed},
+	} {
+		method := tc.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		recorder := httptest.NewRecorder()
+		api.ServeHTTP(recorder, httptest.NewRequest(method, tc.url, nil))
+		assert.Equal(t, tc.status, recorder.Code, tc.url)
+		if tc.body != "" {
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.body, recorder.Body.String(), tc.url)
+		}
+	}
+}
+
+// TestChipJSONKeys test the network info is encoded by the snake case keys without the internal fields
+func TestChipJSONKeys(t *testing.T) {
+	chip := HuaWeiAIChip{NetInfo: &NpuNetInfo{LinkSpeedInfo: LinkSpeedInfo{Speed: 100},
+		StatInfo: StatInfo{MacRxBadPktNum: 1}, StatQueried: true, StatRates: map[string]float64{"rate": 1}}}
+	data, err := json.Marshal(chip)
+	assert.Nil(t, err)
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	for _, key := range []string{"dev_process_info", "pcie_bus_info", "board_info", "net_info"} {
+		assert.Contains(t, decoded, key)
+	}
+	netInfo, ok := decoded["net_info"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"speed": float64(100)}, netInfo["link_speed_info"])
+	assert.Equal(t, float64(1), netInfo["stat_info"].(map[string]interface{})["mac_rx_bad_pkt_num"])
+	assert.NotContains(t, netInfo, "stat_queried")
+	assert.NotContains(t, netInfo, "StatQueried")
+	assert.NotContains(t, netInfo, "StatRates")
+}
@@ -0,0 +1,171 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+type countingDeviceManager struct {
+	devmanager.DeviceManagerMock
+	chipInfoQueries int
+	// chipInfoFailures the number of the first chip info queries which fail
+	chipInfoFailures int
+}
+
+// GetChipInfo count the queries of the static chip info
+func (d *countingDeviceManager) GetChipInfo(logicID int32) (*common.ChipInfo, error) {
+	d.chipInfoQueries++
+	if d.chipInfoQueries <= d.chipInfoFailures {
+		return nil, errors.New("chip info is not ready")
+	}
+	return d.DeviceManagerMock.GetChipInfo(logicID)
+}
+
+// TestDeviceInventoryRefresh test the static info is queried only once per chip
+func TestDeviceInventoryRefresh(t *testing.T) {
+	dmgr := &countingDeviceManager{}
+	inventory := NewDeviceInventory()
+	assert.Nil(t, inventory.Refresh(dmgr))
+	assert.Nil(t, inventory.Refresh(dmgr))
+	chips := inventory.list(dmgr)
+	if assert.Equal(t, 1, len(chips)) {
+		assert.Equal(t, common.Chip910, chips[0].chipInfo.Name)
+	}
+	assert.Equal(t, 1, dmgr.chipInfoQueries)
+
+	npuList := getNPUInfo(dmgr, inventory, nil, 0)
+	assert.Equal(t, 1, len(npuList))
+	assert.Equal(t, 1, dmgr.chipInfoQueries)
+
+	assert.NotNil(t, NewDeviceInventory().Refresh(&devmanager.DeviceManagerMockErr{}))
+}
+
+// TestDeviceInventoryRetryFailed test the failed static info is queried again until it succeeds
+func TestDeviceInventoryRetryFailed(t *testing.T) {
+	dmgr := &countingDeviceManager{chipInfoFailures: 1}
+	inventory := NewDeviceInventory()
+	assert.Nil(t, inventory.Refresh(dmgr))
+	chips := inventory.list(dmgr)
+	if assert.Equal(t, 1, len(chips)) {
+		assert.Equal(t, common.Chip910, chips[0].chipInfo.Name)
+		assert.Equal(t, staticField(0), chips[0].failed&staticChipInfo)
+	}
+	assert.Equal(t, 2, dmgr.chipInfoQueries)
+
+	assert.Nil(t, inventory.Refresh(dmgr))
+	inventory.list(dmgr)
+	assert.Equal(t, 2, dmgr.chipInfoQueries)
+}
+
+// changingDeviceManager has a card whose chips can be changed, the physic id of the chip is the logic id plus the
+// offset
+type changingDeviceManager struct {
+	devmanager.DeviceManagerMock
+	lock      sync.Mutex
+	logicIDs  []int32
+	phyOffset int32
+}
+
+func (d *changingDeviceManager) setPhyOffset(offset int32) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.phyOffset = offset
+}
+
+func (d *changingDeviceManager) setLogicIDs(logicIDs ...int32) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.logicIDs = logicIDs
+}
+
+// GetDeviceNumInCard get the number of the current chips
+func (d *changingDeviceManager) GetDeviceNumInCard(cardID int32) (int32, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return int32(len(d.logicIDs)), nil
+}
+
+// GetDeviceLogicID get the logic id of the current chip
+func (d *changingDeviceManager) GetDeviceLogicID(cardID, deviceID int32) (int32, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.logicIDs[deviceID], nil
+}
+
+// GetPhysicIDFromLogicID the physic id is the logic id plus the offset
+func (d *changingDeviceManager) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return logicID + d.phyOffset, nil
+}
+
+// TestDeviceInventoryChanges test the added and removed chips are found by the refresh
+func TestDeviceInventoryChanges(t *testing.T) {
+	dmgr := &changingDeviceManager{logicIDs: []int32{0, 1}}
+	inventory := NewDeviceInventory()
+	change, err := inventory.refresh(dmgr)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(change.added))
+	added, removed := inventory.changes()
+	assert.Equal(t, uint64(0), added+removed, "the first discovery is not a change")
+
+	change, err = inventory.refresh(dmgr)
+	assert.Nil(t, err)
+	assert.False(t, change.changed())
+
+	dmgr.setLogicIDs(1, 2)
+	change, err = inventory.refresh(dmgr)
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{2}, logicIDsOf(change.added))
+	assert.Equal(t, []int32{0}, logicIDsOf(change.removed))
+	added, removed = inventory.changes()
+	assert.Equal(t, uint64(1), added)
+	assert.Equal(t, uint64(1), removed)
+
+	dmgr.setLogicIDs()
+	_, err = inventory.refresh(dmgr)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(inventory.list(dmgr)), "the chips are kept when no chip is found")
+}
+
+// TestDeviceInventoryReset test the chip which comes back with the same logic id but another physic id is refreshed
+func TestDeviceInventoryReset(t *testing.T) {
+	dmgr := &changingDeviceManager{logicIDs: []int32{0}}
+	inventory := NewDeviceInventory()
+	_, err := inventory.refresh(dmgr)
+	assert.Nil(t, err)
+
+	dmgr.setPhyOffset(1)
+	change, err := inventory.refresh(dmgr)
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{0}, logicIDsOf(change.added))
+	assert.Equal(t, []int32{0}, logicIDsOf(change.removed))
+	chips := inventory.list(dmgr)
+	if assert.Equal(t, 1, len(chips)) {
+		assert.Equal(t, int32(1), chips[0].phyID)
+	}
+	change, err = inventory.refresh(dmgr)
+	assert.Nil(t, err)
+	assert.False(t, change.changed())
+}
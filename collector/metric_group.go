@@ -0,0 +1,258 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// metric group names
+const (
+	// BaseGroup the chip info, eg: utilization, temperature, power, health and error codes
+	BaseGroup = "base"
+	// MemoryGroup the memory and hbm info
+	MemoryGroup = "memory"
+	// NetworkGroup the network health, link, bandwidth, mac and roce statistics
+	NetworkGroup = "network"
+	// OpticalGroup the optical module info
+	OpticalGroup = "optical"
+	// ProcessGroup the npu process info
+	ProcessGroup = "process"
+	// ContainerGroup the npu info of containers
+	ContainerGroup = "container"
+	// VNPUGroup the vnpu info of pods
+	VNPUGroup = "vnpu"
+)
+
+const groupSeparator = ","
+
+// Cadence the data source which refreshes a metric group, the groups of the same cadence are polled together
+type Cadence string
+
+const (
+	// ChipCadence the group is refreshed with the chip info queried from the driver
+	ChipCadence Cadence = "chip"
+	// NetworkCadence the group is refreshed with the network info queried by hccn_tool
+	NetworkCadence Cadence = "network"
+	// ContainerCadence the group is refreshed with the devices info of the containers
+	ContainerCadence Cadence = "container"
+)
+
+// MetricGroup a group of metrics which owns its descriptors, its cadence and the conversion from HuaWeiAIChip
+type MetricGroup interface {
+	// Name the unique name of the group, which is used to enable the group
+	Name() string
+	// Cadence the data source which refreshes the group
+	Cadence() Cadence
+	// Describe send the descriptors of the group
+	Describe(ch chan<- *prometheus.Desc)
+	// Collect convert the chip to the prometheus metrics
+	Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip, devInfo container.DevicesInfo)
+	// Fields convert the chip to the telegraf fields
+	Fields(chip *HuaWeiAIChip, fields map[string]interface{})
+}
+
+// the registered metric groups, in the order of registration
+var (
+	metricGroupRegistry []MetricGroup
+	registryLock        sync.RWMutex
+)
+
+func init() {
+	for _, group := range []MetricGroup{newBaseGroup(), newMemoryGroup(), newNetworkGroup(), newOpticalGroup(),
+		newProcessGroup(), newContainerGroup(), newVNPUGroup()} {
+		if err := RegisterMetricGroup(group); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterMetricGroup register a metric group, which can be enabled by its name afterwards
+func RegisterMetricGroup(group MetricGroup) error {
+	if group == nil || group.Name() == "" {
+		return errors.New("the metric group or its name is empty")
+	}
+	if strings.Contains(group.Name(), groupSeparator) {
+		return fmt.Errorf("the name of metric group %s contains %s", group.Name(), groupSeparator)
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for _, registered := range metricGroupRegistry {
+		if registered.Name() == group.Name() {
+			return fmt.Errorf("metric group %s is already registered", group.Name())
+		}
+	}
+	metricGroupRegistry = append(metricGroupRegistry, group)
+	return nil
+}
+
+// RegisteredMetricGroups the names of all the registered metric groups
+func RegisteredMetricGroups() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(metricGroupRegistry))
+	for _, group := range metricGroupRegistry {
+		names = append(names, group.Name())
+	}
+	return names
+}
+
+func registeredGroups() []MetricGroup {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return append([]MetricGroup(nil), metricGroupRegistry...)
+}
+
+// MetricGroups the enabled metric groups, nil means all the groups are enabled
+type MetricGroups map[string]struct{}
+
+// ParseMetricGroups parse the comma separated metric groups, eg: base,memory,network
+func ParseMetricGroups(groups string) (MetricGroups, error) {
+	supported := RegisteredMetricGroups()
+	res := make(MetricGroups, len(supported))
+	for _, group := range strings.Split(groups, groupSeparator) {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		if !isSupportedGroup(group, supported) {
+			return nil, fmt.Errorf("unsupported metric group %s, only support %s", group,
+				strings.Join(supported, groupSeparator))
+		}
+		res[group] = struct{}{}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no metric group is enabled")
+	}
+	return res, nil
+}
+
+func isSupportedGroup(group string, supported []string) bool {
+	for _, name := range supported {
+		if group == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (g MetricGroups) enabled(group string) bool {
+	if g == nil {
+		return true
+	}
+	_, ok := g[group]
+	return ok
+}
+
+// anyEnabled return true if one of the groups is enabled
+func (g MetricGroups) anyEnabled(groups ...string) bool {
+	for _, group := range groups {
+		if g.enabled(group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Groups the enabled metric groups, in the order of registration
+func (g MetricGroups) Groups() []MetricGroup {
+	var groups []MetricGroup
+	for _, group := range registeredGroups() {
+		if g.enabled(group.Name()) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// String implements fmt.Stringer
+func (g MetricGroups) String() string {
+	var names []string
+	for _, group := range g.Groups() {
+		names = append(names, group.Name())
+	}
+	return strings.Join(names, groupSeparator)
+}
+
+func (g MetricGroups) cadenceEnabled(cadence Cadence) bool {
+	for _, group := range g.Groups() {
+		if group.Cadence() == cadence {
+			return true
+		}
+	}
+	return false
+}
+
+// the driver and hccn_tool queries which are shared by several groups
+func (g MetricGroups) needNetInfo() bool {
+	return g.cadenceEnabled(NetworkCadence)
+}
+
+// the processes and the vnpus are labeled with their containers, though they are refreshed with the chip info
+func (g MetricGroups) needContainerInfo() bool {
+	return g.cadenceEnabled(ContainerCadence) || g.anyEnabled(ProcessGroup, VNPUGroup)
+}
+
+func (g MetricGroups) needMemoryInfo() bool {
+	return g.anyEnabled(MemoryGroup, ContainerGroup)
+}
+
+func (g MetricGroups) needUtilization() bool {
+	return g.anyEnabled(BaseGroup, ContainerGroup)
+}
+
+// the labels which identify a chip
+var chipLabels = []string{npuID, modelName, npuUUID, npuPCIEInfo}
+
+func newChipDesc(name, help string, extraLabels ...string) *prometheus.Desc {
+	return newDesc(name, help, append(append([]string(nil), chipLabels...), extraLabels...))
+}
+
+// newDesc create the descriptor of the group metric, which is recorded so that it can be renamed by the naming
+// scheme
+func newDesc(name, help string, labels []string) *prometheus.Desc {
+	desc := prometheus.NewDesc(name, help, labels, nil)
+	groupDescs.Store(desc, descInfo{name: name, help: help, labels: labels})
+	return desc
+}
+
+func chipLabelValues(chip *HuaWeiAIChip, extraValues ...string) []string {
+	return append([]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo),
+		chip.VDieID, chip.PCIeBusInfo}, extraValues...)
+}
+
+// sendGauge send the gauge with the timestamp of the card
+func sendGauge(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, desc *prometheus.Desc, value float64,
+	labelValues []string) {
+	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
+		prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...))
+}
+
+// sendCounter send the counter with the timestamp of the card
+func sendCounter(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, desc *prometheus.Desc, value float64,
+	labelValues []string) {
+	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
+		prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labelValues...))
+}
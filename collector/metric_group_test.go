@@ -0,0 +1,162 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/collector/container"
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// TestParseMetricGroups test method of ParseMetricGroups
+func TestParseMetricGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  string
+		want    string
+		wantErr bool
+	}{
+		{name: "should return all groups when given all groups", groups: "base,memory,network,optical,process," +
+			"container,vnpu", want: "base,memory,network,optical,process,container,vnpu"},
+		{name: "should ignore spaces and order", groups: " memory, base ", want: "base,memory"},
+		{name: "should return error when given unsupported group", groups: "base,gpu", wantErr: true},
+		{name: "should return error when given nothing", groups: ",", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := ParseMetricGroups(tt.groups)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, groups.String())
+		})
+	}
+}
+
+// TestDescribeMetricGroups test only the descriptors of the enabled groups are described
+func TestDescribeMetricGroups(t *testing.T) {
+	groups, err := ParseMetricGroups(MemoryGroup)
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups})
+	ch := make(chan *prometheus.Desc, cacheSize)
+	n.Describe(ch)
+	close(ch)
+	// version, machine npu nums, query errors, last update, breaker state, inventory changes and the 4 self
+	// metrics are always described
+	const memoryDescNum = 15
+	assert.Equal(t, memoryDescNum, len(ch))
+}
+
+// TestPackChipInfoWithGroups test the driver is not queried for the disabled groups
+func TestPackChipInfoWithGroups(t *testing.T) {
+	groups, err := ParseMetricGroups(MemoryGroup)
+	assert.Nil(t, err)
+	dmgr := &devmanager.DeviceManagerMock{}
+	chip := packChipInfo(queryChipInventory(0, 0, 0, dmgr), dmgr, groups)
+	assert.NotNil(t, chip.HbmInfo)
+	assert.NotNil(t, chip.DevProcessInfo)
+	assert.Equal(t, "", chip.HealthStatus)
+	assert.Equal(t, LinkDown, chip.LinkStatus)
+	assert.Nil(t, chip.ErrorCodes)
+}
+
+type siteGroup struct {
+	desc *prometheus.Desc
+}
+
+func (g *siteGroup) Name() string {
+	return "site"
+}
+
+func (g *siteGroup) Cadence() Cadence {
+	return ChipCadence
+}
+
+func (g *siteGroup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *siteGroup) Collect(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	_ container.DevicesInfo) {
+	sendGauge(ch, npu, g.desc, float64(chip.Temperature), chipLabelValues(chip))
+}
+
+func (g *siteGroup) Fields(chip *HuaWeiAIChip, fields map[string]interface{}) {
+	fields["site_temperature"] = chip.Temperature
+}
+
+// TestRegisterMetricGroup test the site-specific group can be registered and enabled by its name
+func TestRegisterMetricGroup(t *testing.T) {
+	registered := registeredGroups()
+	defer func() {
+		registryLock.Lock()
+		metricGroupRegistry = registered
+		registryLock.Unlock()
+	}()
+	group := &siteGroup{desc: newChipDesc("npu_chip_site_temperature", "the site temperature")}
+	assert.Nil(t, RegisterMetricGroup(group))
+	assert.NotNil(t, RegisterMetricGroup(group))
+	assert.NotNil(t, RegisterMetricGroup(newBaseGroup()))
+
+	groups, err := ParseMetricGroups("site")
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups})
+	chip := &HuaWeiAIChip{ChipIfo: &common.ChipInfo{Name: "910"}, Temperature: 40}
+	assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip},
+		Timestamp: time.Now()}}, time.Minute))
+	assert.Equal(t, 1, testutil.CollectAndCount(n, "npu_chip_site_temperature"))
+}
+
+// TestMetricGroupFields test the telegraf fields of the groups
+func TestMetricGroupFields(t *testing.T) {
+	chip := &HuaWeiAIChip{
+		ChipIfo:         &common.ChipInfo{Name: "910"},
+		HealthStatus:    Healthy,
+		NetHealthStatus: Healthy,
+		LinkStatus:      LinkUp,
+		ErrorCodes:      []int64{0x80E18402},
+		HbmInfo:         &common.HbmInfo{Usage: 1},
+		DevProcessInfo:  &common.DevProcessInfo{ProcNum: 1},
+		NetInfo: &NpuNetInfo{
+			StatInfo:      StatInfo{RoceRxAllPktNum: 10},
+			LinkSpeedInfo: LinkSpeedInfo{Speed: 1},
+			OpticalInfo:   OpticalInfo{OpticalState: 1, OpticalVcc: 3.3},
+		},
+	}
+	fields := make(map[string]interface{})
+	for _, group := range MetricGroups(nil).Groups() {
+		group.Fields(chip, fields)
+	}
+	assert.Equal(t, 1, fields["npu_chip_info_health_status"])
+	assert.Equal(t, 1, fields["npu_chip_info_error_code_count"])
+	assert.Equal(t, int64(0x80E18402), fields["npu_chip_info_error_code_0"])
+	assert.Equal(t, uint64(mega), fields["npu_chip_info_hbm_used_memory"])
+	assert.Equal(t, 1, fields["npu_chip_info_link_status"])
+	assert.Equal(t, 10, fields["npu_chip_roce_rx_all_pkt_num"])
+	assert.Equal(t, mega, fields["npu_chip_link_speed"])
+	assert.Equal(t, 1, fields["npu_chip_optical_state"])
+	assert.Equal(t, 3.3, fields["npu_chip_optical_vcc"])
+	assert.Equal(t, int32(1), fields["npu_chip_info_process_info_num"])
+}
@@ -0,0 +1,389 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+// MetricNaming the naming scheme of the metrics
+type MetricNaming string
+
+const (
+	// NamingV1 the legacy names and units
+	NamingV1 MetricNaming = "v1"
+	// NamingV2 the prometheus convention names with the base units, eg: _bytes, _celsius, _watts and _hertz
+	NamingV2 MetricNaming = "v2"
+	// NamingDual both the v1 and v2 metrics, so that the dashboards can be migrated gradually
+	NamingDual MetricNaming = "dual"
+)
+
+const (
+	percent      = 0.01
+	kilo         = 1024
+	megaHertz    = 1e6
+	megaBits     = 1e6
+	milliWatts   = 1e-3
+	opticalLanes = 4
+)
+
+// ParseMetricNaming parse the naming scheme, empty means v1
+func ParseMetricNaming(naming string) (MetricNaming, error) {
+	switch MetricNaming(strings.TrimSpace(naming)) {
+	case "", NamingV1:
+		return NamingV1, nil
+	case NamingV2:
+		return NamingV2, nil
+	case NamingDual:
+		return NamingDual, nil
+	default:
+		return "", fmt.Errorf("unsupported metric naming %s, only support %s, %s and %s", naming, NamingV1,
+			NamingV2, NamingDual)
+	}
+}
+
+func (m MetricNaming) v1() bool {
+	return m == "" || m == NamingV1 || m == NamingDual
+}
+
+func (m MetricNaming) v2() bool {
+	return m == NamingV2 || m == NamingDual
+}
+
+// descInfo the name, help and variable labels of a group descriptor
+type descInfo struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// groupDescs the descriptors created by newDesc, keyed by *prometheus.Desc
+var groupDescs sync.Map
+
+// namingRule how the v1 metric is converted to the v2 metric
+type namingRule struct {
+	name string
+	help string
+	// scale convert the v1 value to the base unit, 0 means 1
+	scale float64
+	// labels the renamed labels, eg: v_dev_id to vnpu_id
+	labels map[string]string
+	// constLabels merge the v1 families into one v2 family, eg: the lane of the optical power
+	constLabels prometheus.Labels
+	// counter the v1 gauge is a cumulative count
+	counter bool
+	// drop the v1 metric has no v2 metric, eg: the legacy statistic gauges which are exported as counters
+	drop bool
+	// field the v2 telegraf field, empty means the name
+	field string
+	// fieldScale convert the v1 telegraf field to the base unit, 0 means the scale. Some of the v1 fields are
+	// already converted from MB to bytes
+	fieldScale float64
+}
+
+func (r namingRule) scaleOf() float64 {
+	if r.scale == 0 {
+		return 1
+	}
+	return r.scale
+}
+
+func (r namingRule) fieldName() string {
+	if r.field != "" {
+		return r.field
+	}
+	return r.name
+}
+
+func (r namingRule) fieldScaleOf() float64 {
+	if r.fieldScale != 0 {
+		return r.fieldScale
+	}
+	return r.scaleOf()
+}
+
+var (
+	chipModelLabel  = map[string]string{"name": modelName}
+	containerLabels = map[string]string{"containerID": "container_id", "containerName": "container_name",
+		"npuID": npuID}
+	vnpuLabels = map[string]string{vNpuUUID: "vnpu_id"}
+)
+
+// namingRules the v2 rules of the group metrics, keyed by the v1 name. The metrics without rule keep the v1 name
+// in both schemes
+var namingRules = newNamingRules()
+
+func newNamingRules() map[string]namingRule {
+	rules := map[string]namingRule{
+		// base
+		"npu_chip_info_name": {name: "npu_chip_info", help: "the npu chip info with value '1'",
+			labels: chipModelLabel},
+		"npu_chip_info_utilization": {name: "npu_chip_aicore_utilization_ratio",
+			help: "the ai core utilization, range [0, 1]", scale: percent},
+		"npu_chip_info_temperature": {name: "npu_chip_temperature_celsius",
+			help: "the npu temperature in celsius"},
+		"npu_chip_info_power":   {name: "npu_chip_power_watts", help: "the npu power in watts"},
+		"npu_chip_info_voltage": {name: "npu_chip_voltage_volts", help: "the npu voltage in volts"},
+		"npu_chip_info_health_status": {name: "npu_chip_health_status",
+			help: "the npu health status, 1 is healthy"},
+		"npu_chip_info_error_code": {name: "npu_chip_error_code", help: "the npu error code"},
+		"npu_chip_info_error_code_count": {name: "npu_chip_error_codes",
+			help: "the number of the active npu error codes"},
+		"npu_chip_info_error_code_active": {name: "npu_chip_error_code_active",
+			help: "the npu error code which is currently active with value '1'"},
+		"npu_chip_info_aicore_current_freq": {name: "npu_chip_aicore_frequency_hertz",
+			help: "the npu ai core current frequency in hertz", scale: megaHertz},
+		// memory
+		"npu_chip_info_hbm_used_memory": {name: "npu_chip_hbm_used_bytes", help: "the npu hbm used memory in bytes",
+			scale: mega, fieldScale: 1},
+		"npu_chip_info_hbm_total_memory": {name: "npu_chip_hbm_total_bytes",
+			help: "the npu hbm total memory in bytes", scale: mega},
+		"npu_chip_info_hbm_utilization": {name: "npu_chip_hbm_utilization_ratio",
+			help: "the npu hbm utilization, range [0, 1]", scale: percent},
+		"npu_chip_info_used_memory": {name: "npu_chip_memory_used_bytes", help: "the npu used memory in bytes",
+			scale: mega},
+		"npu_chip_info_total_memory": {name: "npu_chip_memory_total_bytes", help: "the npu total memory in bytes",
+			scale: mega},
+		// network
+		"npu_chip_info_network_status": {name: "npu_chip_network_health_status",
+			help: "the npu network health status, 1 is healthy"},
+		"npu_chip_info_link_status": {name: "npu_chip_link_status", help: "the npu link status, 1 is up"},
+		"npu_chip_info_bandwidth_tx": {name: "npu_chip_network_transmit_bytes_per_second",
+			help: "the npu interface transmit speed in bytes per second", scale: mega, fieldScale: 1},
+		"npu_chip_info_bandwidth_rx": {name: "npu_chip_network_receive_bytes_per_second",
+			help: "the npu interface receive speed in bytes per second", scale: mega, fieldScale: 1},
+		"npu_chip_link_speed": {name: "npu_chip_link_speed_bits_per_second",
+			help: "the npu interface link speed in bits per second", scale: megaBits, fieldScale: megaBits / mega},
+		"npu_chip_link_up_num": {name: "npu_chip_link_up_total", help: "the times of the npu interface link-up",
+			counter: true},
+		// optical
+		"npu_chip_optical_vcc": {name: "npu_chip_optical_voltage_volts",
+			help: "the optical module voltage in volts"},
+		"npu_chip_optical_temp": {name: "npu_chip_optical_temperature_celsius",
+			help: "the optical module temperature in celsius"},
+		// process
+		"npu_chip_info_process_info": {name: "npu_chip_process_memory_used_bytes",
+			help: "the npu memory used by the process in bytes, the container labels are empty for the host " +
+				"process", scale: mega},
+		// container
+		"npu_container_info": {name: "npu_container_chip_info",
+			help: "the chip used by the container with value '1'", labels: containerLabels},
+		"container_npu_total_memory": {name: "npu_container_memory_total_bytes",
+			help: "the npu total memory in container in bytes", scale: mega},
+		"container_npu_used_memory": {name: "npu_container_memory_used_bytes",
+			help: "the npu used memory in container in bytes", scale: mega},
+		"container_npu_utilization": {name: "npu_container_aicore_utilization_ratio",
+			help: "the npu ai core utilization in container, range [0, 1]", scale: percent},
+		// vnpu
+		"vnpu_pod_aicore_utilization": {name: "npu_vnpu_aicore_utilization_ratio",
+			help: "the vnpu ai core utilization, range [0, 1]", scale: percent, labels: vnpuLabels},
+		"vnpu_pod_total_memory": {name: "npu_vnpu_memory_total_bytes", help: "the vnpu total memory in bytes",
+			scale: kilo, labels: vnpuLabels},
+		"vnpu_pod_used_memory": {name: "npu_vnpu_memory_used_bytes", help: "the vnpu used memory in bytes",
+			scale: kilo, labels: vnpuLabels},
+	}
+	for lane := 0; lane < opticalLanes; lane++ {
+		for _, direction := range []string{"tx", "rx"} {
+			name := fmt.Sprintf("npu_chip_optical_%s_power_watts", direction)
+			rules[fmt.Sprintf("npu_chip_optical_%s_power_%d", direction, lane)] = namingRule{name: name,
+				help:  fmt.Sprintf("the %s power of the optical module lane in watts", direction),
+				scale: milliWatts, constLabels: prometheus.Labels{"lane": fmt.Sprint(lane)},
+				field: fmt.Sprintf("npu_chip_optical_lane%d_%s_power_watts", lane, direction)}
+		}
+	}
+	// the statistics are exported as the counters, which already follow the convention
+	for _, stat := range statFields {
+		rules[stat.name] = namingRule{name: stat.counterName, drop: true}
+	}
+	return rules
+}
+
+// v2Descs the v2 descriptors, keyed by the v1 *prometheus.Desc
+var v2Descs sync.Map
+
+// v2DescInfos the names, help and labels of the v2 descriptors, keyed by the v2 *prometheus.Desc
+var v2DescInfos sync.Map
+
+// v2Desc get the v2 descriptor of the group descriptor, false is returned when the descriptor is not renamed
+func v2Desc(desc *prometheus.Desc) (*prometheus.Desc, descInfo, namingRule, bool) {
+	value, ok := groupDescs.Load(desc)
+	if !ok {
+		return nil, descInfo{}, namingRule{}, false
+	}
+	info, ok := value.(descInfo)
+	if !ok {
+		return nil, descInfo{}, namingRule{}, false
+	}
+	rule, ok := namingRules[info.name]
+	if !ok {
+		return nil, info, namingRule{}, false
+	}
+	if cached, ok := v2Descs.Load(desc); ok {
+		if renamed, ok := cached.(*prometheus.Desc); ok {
+			return renamed, info, rule, true
+		}
+	}
+	labels := make([]string, 0, len(info.labels))
+	for _, label := range info.labels {
+		if renamed, ok := rule.labels[label]; ok {
+			label = renamed
+		}
+		labels = append(labels, label)
+	}
+	help := rule.help
+	if help == "" {
+		help = info.help
+	}
+	renamed := prometheus.NewDesc(rule.name, help, labels, rule.constLabels)
+	v2Descs.Store(desc, renamed)
+	v2DescInfos.Store(renamed, descInfo{name: rule.name, help: help, labels: labels})
+	return renamed, info, rule, true
+}
+
+// describeWithNaming forward the descriptors to ch by the naming scheme, the returned func must be called after all
+// the descriptors are sent
+func describeWithNaming(ch chan<- *prometheus.Desc, naming MetricNaming) (chan<- *prometheus.Desc, func()) {
+	renamed := make(chan *prometheus.Desc, cacheSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for desc := range renamed {
+			v2, _, rule, ok := v2Desc(desc)
+			if !ok || naming.v1() {
+				ch <- desc
+			}
+			if ok && !rule.drop && naming.v2() {
+				ch <- v2
+			}
+		}
+	}()
+	return renamed, func() {
+		close(renamed)
+		<-done
+	}
+}
+
+// collectWithNaming forward the metrics to ch by the naming scheme, the returned func must be called after all the
+// metrics are sent
+func collectWithNaming(ch chan<- prometheus.Metric, naming MetricNaming) (chan<- prometheus.Metric, func()) {
+	renamed := make(chan prometheus.Metric, cacheSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for metric := range renamed {
+			v2, info, rule, ok := v2Desc(metric.Desc())
+			if !ok || naming.v1() {
+				ch <- metric
+			}
+			if !ok || rule.drop || !naming.v2() {
+				continue
+			}
+			converted, err := convertMetric(metric, v2, info, rule)
+			if err != nil {
+				hwlog.RunLog.Warnf("convert metric %s to %s failed: %v", info.name, rule.name, err)
+				continue
+			}
+			ch <- converted
+		}
+	}()
+	return renamed, func() {
+		close(renamed)
+		<-done
+	}
+}
+
+func convertMetric(metric prometheus.Metric, v2 *prometheus.Desc, info descInfo,
+	rule namingRule) (prometheus.Metric, error) {
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		return nil, err
+	}
+	valueType, value := prometheus.GaugeValue, m.GetGauge().GetValue()
+	if m.Counter != nil {
+		valueType, value = prometheus.CounterValue, m.GetCounter().GetValue()
+	}
+	if rule.counter {
+		valueType = prometheus.CounterValue
+	}
+	// the labels of the written metric are sorted by name, which are put back in the order of the descriptor
+	pairs := make(map[string]string, len(m.Label))
+	for _, pair := range m.Label {
+		pairs[pair.GetName()] = pair.GetValue()
+	}
+	labelValues := make([]string, 0, len(info.labels))
+	for _, label := range info.labels {
+		labelValues = append(labelValues, pairs[label])
+	}
+	converted, err := prometheus.NewConstMetric(v2, valueType, value*rule.scaleOf(), labelValues...)
+	if err != nil {
+		return nil, err
+	}
+	if m.TimestampMs != nil {
+		converted = prometheus.NewMetricWithTimestamp(time.UnixMilli(m.GetTimestampMs()), converted)
+	}
+	return converted, nil
+}
+
+// TranslateFields convert the v1 telegraf fields by the naming scheme, the fields without rule are kept
+func (m MetricNaming) TranslateFields(fields map[string]interface{}) map[string]interface{} {
+	if !m.v2() {
+		return fields
+	}
+	translated := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		rule, ok := namingRules[name]
+		if !ok || m.v1() {
+			translated[name] = value
+		}
+		if !ok {
+			continue
+		}
+		converted, ok := toFloat(value)
+		if !ok || rule.fieldScaleOf() == 1 {
+			translated[rule.fieldName()] = value
+			continue
+		}
+		translated[rule.fieldName()] = converted * rule.fieldScaleOf()
+	}
+	return translated
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
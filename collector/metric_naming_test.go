@@ -0,0 +1,106 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// TestParseMetricNaming test method of ParseMetricNaming
+func TestParseMetricNaming(t *testing.T) {
+	naming, err := ParseMetricNaming("")
+	assert.Nil(t, err)
+	assert.Equal(t, NamingV1, naming)
+	naming, err = ParseMetricNaming(" dual ")
+	assert.Nil(t, err)
+	assert.Equal(t, NamingDual, naming)
+	_, err = ParseMetricNaming("v3")
+	assert.NotNil(t, err)
+}
+
+func newNamingTestCollector(t *testing.T, naming MetricNaming) *npuCollector {
+	groups, err := ParseMetricGroups(strings.Join([]string{MemoryGroup, OpticalGroup}, groupSeparator))
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups, Naming: naming})
+	chip := &HuaWeiAIChip{ChipIfo: &common.ChipInfo{Name: "910"}, HbmInfo: &common.HbmInfo{MemorySize: 2},
+		Meminf: &common.MemoryInfo{}}
+	assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip},
+		Timestamp: time.UnixMilli(scrapeTimestamp)}}, time.Minute))
+	netInfo := NpuNetInfo{OpticalInfo: OpticalInfo{OpticalTxPower0: 500, OpticalTxPower1: 250}}
+	assert.Nil(t, n.cache.Set(npuNetworkCacheKey, map[int32]NpuNetInfo{0: netInfo}, time.Minute))
+	return n
+}
+
+// TestMetricNaming test the metrics are renamed and converted to the base units by the naming scheme
+func TestMetricNaming(t *testing.T) {
+	n := newNamingTestCollector(t, NamingV2)
+	expected := `
+# HELP npu_chip_hbm_total_bytes the npu hbm total memory in bytes
+# TYPE npu_chip_hbm_total_bytes gauge
+npu_chip_hbm_total_bytes{id="0",model_name="910--",pcie_bus_info="",vdie_id=""} 2.097152e+06 1000
+# HELP npu_chip_optical_tx_power_watts the tx power of the optical module lane in watts
+# TYPE npu_chip_optical_tx_power_watts gauge
+npu_chip_optical_tx_power_watts{id="0",lane="0",model_name="910--",pcie_bus_info="",vdie_id=""} 0.5 1000
+npu_chip_optical_tx_power_watts{id="0",lane="1",model_name="910--",pcie_bus_info="",vdie_id=""} 0.25 1000
+npu_chip_optical_tx_power_watts{id="0",lane="2",model_name="910--",pcie_bus_info="",vdie_id=""} 0 1000
+npu_chip_optical_tx_power_watts{id="0",lane="3",model_name="910--",pcie_bus_info="",vdie_id=""} 0 1000
+`
+	assert.Nil(t, testutil.CollectAndCompare(n, strings.NewReader(expected), "npu_chip_hbm_total_bytes",
+		"npu_chip_optical_tx_power_watts"))
+	assert.Equal(t, 0, testutil.CollectAndCount(n, "npu_chip_info_hbm_total_memory"))
+
+	// the v1 and v2 metrics are both exported and registered in the dual mode
+	dual := newNamingTestCollector(t, NamingDual)
+	reg := prometheus.NewPedanticRegistry()
+	assert.Nil(t, reg.Register(dual))
+	families, err := reg.Gather()
+	assert.Nil(t, err)
+	names := make(map[string]struct{}, len(families))
+	for _, family := range families {
+		names[family.GetName()] = struct{}{}
+	}
+	for _, name := range []string{"npu_chip_hbm_total_bytes", "npu_chip_info_hbm_total_memory",
+		"npu_chip_optical_tx_power_watts", "npu_chip_optical_tx_power_0"} {
+		assert.Contains(t, names, name)
+	}
+}
+
+// TestTranslateFields test the telegraf fields are renamed and converted to the base units by the naming scheme
+func TestTranslateFields(t *testing.T) {
+	fields := map[string]interface{}{
+		"npu_chip_info_hbm_used_memory": uint64(mega),
+		"npu_chip_link_speed":           mega,
+		"npu_chip_roce_rx_err_pkt_num":  1,
+		"npu_chip_info_error_code_0":    int64(1),
+	}
+	translated := NamingV2.TranslateFields(fields)
+	assert.Equal(t, map[string]interface{}{
+		"npu_chip_hbm_used_bytes":             uint64(mega),
+		"npu_chip_link_speed_bits_per_second": float64(megaBits),
+		"npu_chip_roce_rx_err_packets_total":  1,
+		"npu_chip_info_error_code_0":          int64(1),
+	}, translated)
+	assert.Equal(t, fields, NamingV1.TranslateFields(fields))
+	assert.Equal(t, len(fields)+len(translated)-1, len(NamingDual.TranslateFields(fields)))
+}
@@ -0,0 +1,107 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager"
+)
+
+// netWorkerPool the network workers of the chips, keyed by the physic id. A worker is started when the chip is
+// discovered and is stopped when the chip is gone from the inventory
+type netWorkerPool struct {
+	lock    sync.Mutex
+	cancels map[int32]context.CancelFunc
+}
+
+// syncNetWorkers start the workers of the new chips and stop the workers of the removed chips, the network info of
+// the removed chips is dropped as well
+func (n *npuCollector) syncNetWorkers(ctx context.Context, group *sync.WaitGroup, dmgr devmanager.DeviceInterface) {
+	if !dmgr.IsTrainingCard() {
+		return
+	}
+	chips := n.inventory.list(dmgr)
+	if len(chips) == 0 {
+		// keep the workers when the discovery failed, the chips may come back at the next refresh
+		return
+	}
+	alive := make(map[int32]struct{}, len(chips))
+	for _, chip := range chips {
+		alive[chip.phyID] = struct{}{}
+	}
+	pool := &n.netWorkers
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.cancels == nil {
+		pool.cancels = make(map[int32]context.CancelFunc, len(chips))
+	}
+	changed := len(pool.cancels) != len(alive)
+	for phyID, cancel := range pool.cancels {
+		if _, ok := alive[phyID]; ok {
+			continue
+		}
+		changed = true
+		cancel()
+		delete(pool.cancels, phyID)
+		n.netInfoMap.Delete(phyID)
+		n.statRates.forget(phyID)
+		hwlog.RunLog.Infof("the network worker of chip(phyID %d) is stopped, the chip is removed", phyID)
+	}
+	if changed {
+		// the network info is updated when all the workers of the current chips have refreshed
+		phyIDs := make([]int32, 0, len(alive))
+		for phyID := range alive {
+			phyIDs = append(phyIDs, phyID)
+		}
+		n.freshness.expectChips(NetworkCadence, phyIDs)
+	}
+	for phyID := range alive {
+		if _, ok := pool.cancels[phyID]; ok {
+			continue
+		}
+		workerCtx, cancel := context.WithCancel(ctx)
+		pool.cancels[phyID] = cancel
+		n.assembleNPUNetInfo(workerCtx, group, phyID)
+	}
+}
+
+// assembleNPUNetInfo query the network info of the chip periodically, until the ctx is done
+func (n *npuCollector) assembleNPUNetInfo(ctx context.Context, group *sync.WaitGroup, phyID int32) {
+	name := fmt.Sprintf("%s-%d", npuNetworkCacheKey, phyID)
+	runPeriodically(ctx, group, name, n.schedule.network, func() {
+		netInfo := networkPackInfo(phyID, n.metricGroups)
+		n.storeNetInfo(ctx, phyID, netInfo)
+	})
+}
+
+// storeNetInfo store the network info queried by the worker. The chip may be removed during the query, so the
+// worker is checked under the lock of the pool, which its removal holds as well, otherwise the stale info may be
+// stored after it is dropped
+func (n *npuCollector) storeNetInfo(ctx context.Context, phyID int32, netInfo NpuNetInfo) {
+	pool := &n.netWorkers
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	n.statRates.Track(phyID, &netInfo, time.Now())
+	n.setNetInfoWithMap(phyID, netInfo)
+}
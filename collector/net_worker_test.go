@@ -0,0 +1,111 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+func runningNetWorkers(n *npuCollector) []int32 {
+	n.netWorkers.lock.Lock()
+	defer n.netWorkers.lock.Unlock()
+	phyIDs := make([]int32, 0, len(n.netWorkers.cancels))
+	for phyID := range n.netWorkers.cancels {
+		phyIDs = append(phyIDs, phyID)
+	}
+	sort.Slice(phyIDs, func(i, k int) bool {
+		return phyIDs[i] < phyIDs[k]
+	})
+	return phyIDs
+}
+
+// TestSyncNetWorkers test the workers follow the chips of the inventory, and are stopped with the ctx
+func TestSyncNetWorkers(t *testing.T) {
+	groups, err := ParseMetricGroups(MemoryGroup)
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups, UpdateTime: time.Hour})
+	dmgr := &changingDeviceManager{logicIDs: []int32{0, 1}}
+	ctx, cancel := context.WithCancel(context.Background())
+	group := &sync.WaitGroup{}
+
+	n.syncNetWorkers(ctx, group, dmgr)
+	assert.Equal(t, []int32{0, 1}, runningNetWorkers(n))
+	assert.Eventually(t, func() bool {
+		return len(n.getNetInfoFromMap()) == 2
+	}, time.Second, time.Millisecond)
+
+	dmgr.setLogicIDs(1, 2)
+	_, err = n.inventory.refresh(dmgr)
+	assert.Nil(t, err)
+	n.syncNetWorkers(ctx, group, dmgr)
+	assert.Equal(t, []int32{1, 2}, runningNetWorkers(n))
+	assert.Eventually(t, func() bool {
+		netInfo := n.getNetInfoFromMap()
+		_, removed := netInfo[0]
+		_, added := netInfo[2]
+		return !removed && added
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	stopped := make(chan struct{})
+	go func() {
+		group.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the network workers are not stopped with the ctx")
+	}
+}
+
+// TestStoreNetInfo test the network info of the stopped worker is not stored, so that the info of the removed chip
+// is not brought back
+func TestStoreNetInfo(t *testing.T) {
+	n := newNpuCollector(nil, NpuCollectorOpts{UpdateTime: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	n.storeNetInfo(ctx, 0, NpuNetInfo{})
+	assert.Equal(t, 1, len(n.getNetInfoFromMap()))
+
+	cancel()
+	n.storeNetInfo(ctx, 1, NpuNetInfo{})
+	_, stored := n.getNetInfoFromMap()[1]
+	assert.False(t, stored)
+}
+
+// TestCollectKeepsCachedChip test the network info is not written to the cached chip, which is shared with the
+// other readers
+func TestCollectKeepsCachedChip(t *testing.T) {
+	groups, err := ParseMetricGroups("base,network")
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups})
+	chip := &HuaWeiAIChip{ChipIfo: &common.ChipInfo{Name: "910"}, HbmInfo: &common.HbmInfo{},
+		Meminf: &common.MemoryInfo{}}
+	assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip},
+		Timestamp: time.Now()}}, time.Minute))
+	assert.Nil(t, n.cache.Set(npuNetworkCacheKey, map[int32]NpuNetInfo{0: {}}, time.Minute))
+	assert.Equal(t, 1, testutil.CollectAndCount(n, "npu_chip_info_temperature"))
+	assert.Nil(t, chip.NetInfo)
+}
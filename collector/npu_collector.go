@@ -31,7 +31,6 @@ import (
 	"huawei.com/npu-exporter/v5/common-utils/hwlog"
 	"huawei.com/npu-exporter/v5/devmanager"
 	"huawei.com/npu-exporter/v5/devmanager/common"
-	"huawei.com/npu-exporter/v5/devmanager/dcmi"
 	"huawei.com/npu-exporter/v5/devmanager/hccn"
 	"huawei.com/npu-exporter/v5/versions"
 )
@@ -46,6 +45,11 @@ const (
 	namespace   = "namespace"
 	podName     = "pod_name"
 	isVirtual   = "is_virtual"
+	errorCode   = "error_code"
+
+	faultModule      = "module"
+	faultDescription = "description"
+	faultSuggestion  = "suggestion"
 )
 
 const (
@@ -86,135 +90,6 @@ const (
 	roCENewPktRtyNum       = "roce_new_pkt_rty_num"
 )
 
-var (
-	versionInfoDesc = prometheus.NewDesc("npu_exporter_version_info",
-		"exporter version with value '1'", []string{"exporterVersion"}, nil)
-	machineInfoNPUDesc = prometheus.NewDesc("machine_npu_nums",
-		"Amount of npu installed on the machine.", nil, nil)
-	npuChipInfoDescNpuName = prometheus.NewDesc("npu_chip_info_name",
-		"the Ascend npu name with value '1'", []string{npuID, "name", npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescUtil = prometheus.NewDesc("npu_chip_info_utilization",
-		"the ai core utilization", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescTemp = prometheus.NewDesc("npu_chip_info_temperature",
-		"the npu temperature", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescPower = prometheus.NewDesc("npu_chip_info_power",
-		"the npu power", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescVoltage = prometheus.NewDesc("npu_chip_info_voltage",
-		"the npu voltage", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescUsedMemory = prometheus.NewDesc("npu_chip_info_used_memory",
-		"the npu used memory", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescTotalMemory = prometheus.NewDesc("npu_chip_info_total_memory",
-		"the npu total memory", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescHealthStatus = prometheus.NewDesc("npu_chip_info_health_status",
-		"the npu health status", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescHbmUsedMemory = prometheus.NewDesc("npu_chip_info_hbm_used_memory",
-		"the npu hbm used memory", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescHbmTotalMemory = prometheus.NewDesc("npu_chip_info_hbm_total_memory",
-		"the npu hbm total memory", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescErrorCode = prometheus.NewDesc("npu_chip_info_error_code",
-		"the npu error code", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescLinkStatus = prometheus.NewDesc("npu_chip_info_link_status",
-		"the npu link status", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescNetworkStatus = prometheus.NewDesc("npu_chip_info_network_status",
-		"the npu network health status", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescBandwidthTx = prometheus.NewDesc("npu_chip_info_bandwidth_tx",
-		"the npu interface transport speed, unit is 'MB/s'", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescBandwidthRx = prometheus.NewDesc("npu_chip_info_bandwidth_rx",
-		"the npu interface receive speed, unit is 'MB/s'", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipLinkSpeed = prometheus.NewDesc("npu_chip_link_speed",
-		"the npu interface receive link speed, unit is 'Mb/s'", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipLinkUpNum = prometheus.NewDesc("npu_chip_link_up_num",
-		"the npu interface receive link-up num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacRxPauseNum = prometheus.NewDesc("npu_chip_mac_rx_pause_num",
-		"the npu interface receive mac-rx-pause-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacTxPauseNum = prometheus.NewDesc("npu_chip_mac_tx_pause_num",
-		"the npu interface receive mac-tx-pause-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacRxPfcPktNum = prometheus.NewDesc("npu_chip_mac_rx_pfc_pkt_num",
-		"the npu interface receive mac-rx-pfc-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacTxPfcPktNum = prometheus.NewDesc("npu_chip_mac_tx_pfc_pkt_num",
-		"the npu interface receive mac-tx-pfc-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacRxBadPktNum = prometheus.NewDesc("npu_chip_mac_rx_bad_pkt_num",
-		"the npu interface receive mac-rx-bad-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacTxBadPktNum = prometheus.NewDesc("npu_chip_mac_tx_bad_pkt_num",
-		"the npu interface receive mac-tx-bad-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceRxAllPktNum = prometheus.NewDesc("npu_chip_roce_rx_all_pkt_num",
-		"the npu interface receive roce-rx-all-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceTxAllPktNum = prometheus.NewDesc("npu_chip_roce_tx_all_pkt_num",
-		"the npu interface receive roce-tx-all-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceRxErrPktNum = prometheus.NewDesc("npu_chip_roce_rx_err_pkt_num",
-		"the npu interface receive roce-rx-err-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceTxErrPktNum = prometheus.NewDesc("npu_chip_roce_tx_err_pkt_num",
-		"the npu interface receive roce-tx-err-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceRxCnpPktNum = prometheus.NewDesc("npu_chip_roce_rx_cnp_pkt_num",
-		"the npu interface receive roce-rx-cnp-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceTxCnpPktNum = prometheus.NewDesc("npu_chip_roce_tx_cnp_pkt_num",
-		"the npu interface receive roce-tx-cnp-pkt-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceNewPktRtyNum = prometheus.NewDesc("npu_chip_roce_new_pkt_rty_num",
-		"the npu interface receive roce-new-pkt-rty-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacTxBadOctNum = prometheus.NewDesc("npu_chip_mac_tx_bad_oct_num",
-		"the npu interface receive mac-tx-bad-oct-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipMacRxBadOctNum = prometheus.NewDesc("npu_chip_mac_rx_bad_oct_num",
-		"the npu interface receive mac-rx-bad-oct-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceUnexpectedAcktNum = prometheus.NewDesc("npu_chip_roce_unexpected_ack_num",
-		"the npu interface receive roce-unexpected-ack-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceOutOfOrderNum = prometheus.NewDesc("npu_chip_roce_out_of_order_num",
-		"the npu interface receive roce-out-of-order-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceVerificationErrNum = prometheus.NewDesc("npu_chip_roce_verification_err_num",
-		"the npu interface receive roce-verification-err-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipRoceQpStatusErrNum = prometheus.NewDesc("npu_chip_roce_qp_status_err_num",
-		"the npu interface receive roce-qp-status-err-num", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalState = prometheus.NewDesc("npu_chip_optical_state",
-		"the npu interface receive optical-state", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalTxPower0 = prometheus.NewDesc("npu_chip_optical_tx_power_0",
-		"the npu interface receive optical-tx-power-0", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalTxPower1 = prometheus.NewDesc("npu_chip_optical_tx_power_1",
-		"the npu interface receive optical-tx-power-1", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalTxPower2 = prometheus.NewDesc("npu_chip_optical_tx_power_2",
-		"the npu interface receive optical-tx-power-2", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalTxPower3 = prometheus.NewDesc("npu_chip_optical_tx_power_3",
-		"the npu interface receive optical-tx-power-3", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalRxPower0 = prometheus.NewDesc("npu_chip_optical_rx_power_0",
-		"the npu interface receive optical-rx-power-0", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalRxPower1 = prometheus.NewDesc("npu_chip_optical_rx_power_1",
-		"the npu interface receive optical-rx-power-1", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalRxPower2 = prometheus.NewDesc("npu_chip_optical_rx_power_2",
-		"the npu interface receive optical-rx-power-2", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalRxPower3 = prometheus.NewDesc("npu_chip_optical_rx_power_3",
-		"the npu interface receive optical-rx-power-3", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalVcc = prometheus.NewDesc("npu_chip_optical_vcc",
-		"the npu interface receive optical-vcc", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipOpticalTemp = prometheus.NewDesc("npu_chip_optical_temp",
-		"the npu interface receive optical-temperature", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuChipInfoDescDevProcessInfo = prometheus.NewDesc("npu_chip_info_process_info",
-		"the npu process info, unit is 'MB'. if process run on host, container_id and container_name will be empty",
-		[]string{npuID, modelName, npuUUID, "process_id", "container_id", "container_name", npuPCIEInfo}, nil)
-	npuChipInfoDescAICoreFreqInfo = prometheus.NewDesc("npu_chip_info_aicore_current_freq",
-		"the npu ai core current frequency, unit is 'MHz'", []string{npuID, modelName, npuUUID, npuPCIEInfo}, nil)
-	npuContainerInfo = prometheus.NewDesc("npu_container_info",
-		"the container name and deviceID relationship", []string{"containerID", "containerName", "npuID", modelName, npuUUID,
-			npuPCIEInfo}, nil)
-	npuContainerTotalMemory = prometheus.NewDesc("container_npu_total_memory",
-		"the npu total memory in container, unit is 'MB'", []string{npuID, namespace, podName, "container_name",
-			modelName, npuUUID, npuPCIEInfo}, nil)
-	npuContainerUsedMemory = prometheus.NewDesc("container_npu_used_memory",
-		"the npu used memory in container, unit is 'MB'", []string{npuID, namespace, podName, "container_name",
-			modelName, npuUUID, npuPCIEInfo}, nil)
-	npuContainerUtilization = prometheus.NewDesc("container_npu_utilization",
-		"the npu ai core utilization in container, unit is '%'", []string{npuID, namespace, podName,
-			"container_name", modelName, npuUUID, npuPCIEInfo}, nil)
-	podAiCoreUtilizationRate = prometheus.NewDesc("vnpu_pod_aicore_utilization",
-		"the vnpu aicore utilization rate, unit is '%'",
-		[]string{npuID, modelName, vNpuUUID, "aicore_count", namespace, podName, "container_name", isVirtual}, nil)
-	podTotalMemory = prometheus.NewDesc("vnpu_pod_total_memory", "the vnpu total memory on pod, unit is 'KB'",
-		[]string{npuID, modelName, vNpuUUID, "aicore_count", namespace, podName, "container_name", isVirtual}, nil)
-	podUsedMemory = prometheus.NewDesc("vnpu_pod_used_memory", "the vnpu used memory on pod, unit is 'KB'",
-		[]string{npuID, modelName, vNpuUUID, "aicore_count", namespace, podName, "container_name", isVirtual}, nil)
-	npuContainerInfoInit sync.Once
-	npuChipInfoInit      sync.Once
-)
-
-var netInfoMap sync.Map
-
 const (
 	cacheSize      = 128
 	nameSpaceIdx   = 0
@@ -232,35 +107,154 @@ const (
 type npuCollector struct {
 	cache         *cache.ConcurrencyLRUCache
 	devicesParser *container.DevicesParser
-	updateTime    time.Duration
+	faultRecorder *faultEventRecorder
+	metricGroups  MetricGroups
+	inventory     *DeviceInventory
+	schedule      *collectSchedule
 	cacheTime     time.Duration
+	// the number of the workers which assemble the chips concurrently
+	workers int
+
+	versionInfoDesc    *prometheus.Desc
+	machineInfoNPUDesc *prometheus.Desc
+	queryErrors        *queryErrorCounter
+	freshness          *freshnessTracker
+	breakerStateDesc   *prometheus.Desc
+	inventoryDesc      *prometheus.Desc
+	// the guarded device manager which is used by the background collectors, nil before it is started
+	driver *devmanager.GuardedDeviceManager
+	// omit the metrics whose query failed instead of exporting them as NaN
+	omitFailedValues bool
+	// the naming scheme of the group metrics
+	naming MetricNaming
+	// the latest network info of each chip, which is written by the network workers
+	netInfoMap        sync.Map
+	netWorkers        netWorkerPool
+	statRates         *StatRateTracker
+	chipInfoInit      sync.Once
+	containerInfoInit sync.Once
+	readiness         *readinessTracker
+	// closed after the background collection is stopped and the driver is shut down
+	stopped chan struct{}
 }
 
-// NewNpuCollector create an instance of prometheus Collector
-func NewNpuCollector(ctx context.Context, cacheTime time.Duration, updateTime time.Duration,
-	deviceParser *container.DevicesParser) (prometheus.Collector, error) {
-	npuCollect := &npuCollector{
-		cache:         cache.New(cacheSize),
-		cacheTime:     cacheTime,
-		updateTime:    updateTime,
-		devicesParser: deviceParser,
-	}
+// NpuCollectorOpts the options of the npu collector
+type NpuCollectorOpts struct {
+	CacheTime  time.Duration
+	UpdateTime time.Duration
+	// Intervals the intervals of the data sources, the zero interval falls back to UpdateTime
+	Intervals CollectIntervals
+	// FaultJournal persists the subscribed fault events, nil means not to persist
+	FaultJournal *FaultJournal
+	// MetricGroups the enabled metric groups, nil means all the groups are enabled
+	MetricGroups MetricGroups
+	// OmitFailedValues omit the metrics whose query failed, otherwise they are exported as NaN
+	OmitFailedValues bool
+	// MaxAge the groups whose data is not updated within the max age are dropped from the scrape, 0 means never
+	MaxAge time.Duration
+	// CollectWorkers the number of the workers which assemble the chips concurrently, 0 means DefaultCollectWorkers
+	CollectWorkers int
+	// DriverGuard the deadline and circuit breaker options of the per-chip driver calls
+	DriverGuard devmanager.GuardOpts
+	// Naming the naming scheme of the group metrics, empty means NamingV1
+	Naming MetricNaming
+}
+
+// ManagedCollector the prometheus Collector whose background collection is stopped when its ctx is done, and
+// whose intervals can be changed while it is running
+type ManagedCollector interface {
+	prometheus.Collector
+	// Stopped is closed after all the collection goroutines are stopped, the container runtime connections are
+	// closed and the driver is shut down
+	Stopped() <-chan struct{}
+	// SetIntervals change the intervals of the data sources, the zero interval falls back to the updateTime. The
+	// changed intervals take effect from the next run of the tasks
+	SetIntervals(intervals CollectIntervals, updateTime time.Duration)
+	// UpdateInterval how often the fast-telemetry of the chips is updated, which is the cadence of pushing them
+	UpdateInterval() time.Duration
+	// CollectChips collect the samples of the enabled metric groups chip by chip, the samples are converted as
+	// the prometheus metrics are
+	CollectChips() []ChipSamples
+	// Snapshot the cards and containers in the cache, which is read by Collect as well
+	Snapshot() Snapshot
+	// Readiness check the driver reaches the chips, the caches are populated and the container runtime and hccn_tool
+	// are available
+	Readiness() Readiness
+}
+
+// NewNpuCollector create an instance of prometheus Collector, the background collection runs until the ctx is done
+func NewNpuCollector(ctx context.Context, deviceParser *container.DevicesParser,
+	opts NpuCollectorOpts) (ManagedCollector, error) {
+	npuCollect := newNpuCollector(deviceParser, opts)
 	devManager, err := devmanager.AutoInit("")
 	if err != nil {
 		hwlog.RunLog.Errorf("new npu collector failed, error is %v", err)
 		return nil, err
 	}
-	go start(ctx, npuCollect, devManager)
+	npuCollect.driver = devmanager.NewGuardedDeviceManager(devManager, opts.DriverGuard)
+	go func() {
+		defer close(npuCollect.stopped)
+		start(ctx, npuCollect, npuCollect.driver)
+	}()
 	return npuCollect, nil
 }
 
-func setNetInfoWithMap(phyID int32, netInfo NpuNetInfo) {
-	netInfoMap.Store(phyID, netInfo)
+// Stopped implements ManagedCollector
+func (n *npuCollector) Stopped() <-chan struct{} {
+	return n.stopped
+}
+
+// SetIntervals implements ManagedCollector
+func (n *npuCollector) SetIntervals(intervals CollectIntervals, updateTime time.Duration) {
+	intervals = intervals.withDefault(updateTime)
+	n.schedule.set(intervals)
+	hwlog.RunLog.Infof("the collect intervals are changed to %s", intervals)
 }
 
-func getNetInfoFromMap(oldNetInfo map[int32]NpuNetInfo) map[int32]NpuNetInfo {
-	newNetInfo := oldNetInfo
-	netInfoMap.Range(func(key, value interface{}) bool {
+// UpdateInterval implements ManagedCollector
+func (n *npuCollector) UpdateInterval() time.Duration {
+	return n.schedule.fast()
+}
+
+func newNpuCollector(deviceParser *container.DevicesParser, opts NpuCollectorOpts) *npuCollector {
+	return &npuCollector{
+		cache:         cache.New(cacheSize),
+		cacheTime:     opts.CacheTime,
+		workers:       opts.CollectWorkers,
+		schedule:      newCollectSchedule(opts.Intervals.withDefault(opts.UpdateTime)),
+		inventory:     NewDeviceInventory(),
+		statRates:     NewStatRateTracker(),
+		devicesParser: deviceParser,
+		faultRecorder: newFaultEventRecorder(opts.FaultJournal),
+		metricGroups:  opts.MetricGroups,
+		versionInfoDesc: prometheus.NewDesc("npu_exporter_version_info",
+			"exporter version with value '1'", []string{"exporterVersion"}, nil),
+		machineInfoNPUDesc: prometheus.NewDesc("machine_npu_nums",
+			"Amount of npu installed on the machine.", nil, nil),
+		breakerStateDesc: prometheus.NewDesc("npu_exporter_driver_breaker_state",
+			"the circuit breaker state of the driver calls of the chip, 0: closed, 1: open, 2: half open",
+			[]string{"logic_id"}, nil),
+		inventoryDesc: prometheus.NewDesc("npu_exporter_inventory_changes_total",
+			"the number of the chips which are added to or removed from the inventory after the first discovery",
+			[]string{"change"}, nil),
+		queryErrors:      newQueryErrorCounter(),
+		freshness:        newFreshnessTracker(opts.MaxAge),
+		omitFailedValues: opts.OmitFailedValues,
+		naming:           opts.Naming,
+		readiness:        newReadinessTracker(),
+		stopped:          make(chan struct{}),
+	}
+}
+
+func (n *npuCollector) setNetInfoWithMap(phyID int32, netInfo NpuNetInfo) {
+	n.netInfoMap.Store(phyID, netInfo)
+	n.freshness.updateChip(NetworkCadence, phyID)
+}
+
+// getNetInfoFromMap get the network info of the chips which have running workers
+func (n *npuCollector) getNetInfoFromMap() map[int32]NpuNetInfo {
+	newNetInfo := make(map[int32]NpuNetInfo, initSize)
+	n.netInfoMap.Range(func(key, value interface{}) bool {
 		phyID, ok := key.(int32)
 		if !ok {
 			hwlog.RunLog.Warnf("failed to get phyID of netInfo from map, which is: %v", key)
@@ -278,107 +272,117 @@ func getNetInfoFromMap(oldNetInfo map[int32]NpuNetInfo) map[int32]NpuNetInfo {
 	return newNetInfo
 }
 
-func startToGetNetInfo(dmgr devmanager.DeviceInterface, updateTime time.Duration) {
-	cardNum, cards, err := dmgr.GetCardList()
-	if err != nil || cardNum == 0 {
-		hwlog.RunLog.Errorf("failed to get npu info, error is: %v", err)
-		return
-	}
-
-	for _, cardID := range cards {
-		deviceNum, err := dmgr.GetDeviceNumInCard(cardID)
-		if err != nil {
-			hwlog.RunLog.Errorf("get device num of card: %v failed: %v", cardID, err)
-			continue
-		}
-		for i := int32(0); i < deviceNum; i++ {
-			logicID, err := dmgr.GetDeviceLogicID(cardID, i)
-			if err != nil {
-				hwlog.RunLog.Errorf("get logic ID of card: %v device:%v failed: %v", cardID, i, err)
-				continue
-			}
-
-			phyID, err := dmgr.GetPhysicIDFromLogicID(logicID)
-			if err != nil {
-				hwlog.RunLog.Errorf("failed to get phy id when assemble net info: %v", err)
-				continue
-			}
-			go assembleNPUNetInfo(phyID, dmgr, updateTime)
-		}
-	}
-}
-
-func getNPUInfo(dmgr devmanager.DeviceInterface) []HuaWeiNPUCard {
+// getNPUInfo assemble the chips by the workers concurrently, and merge them into the cards in the inventory order
+func getNPUInfo(dmgr devmanager.DeviceInterface, inventory *DeviceInventory, groups MetricGroups,
+	workers int) []HuaWeiNPUCard {
+	defer selfMetrics.observeStage(stageNPUInfo, time.Now())
 	var npuList []HuaWeiNPUCard
-	cardNum, cards, err := dmgr.GetCardList()
-	if err != nil || cardNum == 0 {
-		hwlog.RunLog.Errorf("failed to get npu info, error is: %v", err)
+	chips := inventory.list(dmgr)
+	if len(chips) == 0 {
+		hwlog.RunLog.Error("failed to get npu info, no chip is found")
 		return npuList
 	}
-
-	for _, cardID := range cards {
-		deviceNum, err := dmgr.GetDeviceNumInCard(cardID)
-		if err != nil {
-			hwlog.RunLog.Errorf("get device num of card %v failed: %v", cardID, err)
+	chipInfos := assembleChips(chips, dmgr, groups, workers)
+	cardIndex := make(map[int32]int, initSize)
+	for i, inv := range chips {
+		chipInfo := chipInfos[i]
+		if chipInfo == nil {
 			continue
 		}
-		var deviceList []*HuaWeiAIChip
-		for i := int32(0); i < deviceNum; i++ {
-			var chipInfo *HuaWeiAIChip
-			logicID, err := dmgr.GetDeviceLogicID(cardID, i)
-			if err != nil {
-				hwlog.RunLog.Errorf("get logic ID of card %v device %v failed: %v", cardID, i, err)
-				continue
-			}
-			chipInfo = assembleNPUInfo(cardID, logicID, dmgr)
-			if chipInfo == nil {
-				continue
-			}
-			if !strings.Contains(chipInfo.ChipIfo.Name, "310P") || chipInfo.VDevInfos.TotalResource.VDevNum == 0 {
-				deviceList = append(deviceList, chipInfo)
-				continue
-			}
-			deviceList = append(deviceList, getVNPUInfo(*chipInfo)...)
+		idx, ok := cardIndex[inv.cardID]
+		if !ok {
+			idx = len(npuList)
+			cardIndex[inv.cardID] = idx
+			npuList = append(npuList, HuaWeiNPUCard{CardID: int(inv.cardID)})
 		}
-		npuCard := HuaWeiNPUCard{
-			CardID:     int(cardID),
-			DeviceList: deviceList,
-			Timestamp:  time.Now(),
+		if !strings.Contains(chipInfo.ChipIfo.Name, "310P") || chipInfo.VDevInfos.TotalResource.VDevNum == 0 {
+			npuList[idx].DeviceList = append(npuList[idx].DeviceList, chipInfo)
+			continue
 		}
-		npuList = append(npuList, npuCard)
+		npuList[idx].DeviceList = append(npuList[idx].DeviceList, getVNPUInfo(*chipInfo)...)
+	}
+	now := time.Now()
+	for i := range npuList {
+		npuList[i].Timestamp = now
 	}
 	return npuList
 }
 
-func assembleNPUNetInfo(phyID int32, dmgr devmanager.DeviceInterface, updateTime time.Duration) {
-	if !dmgr.IsTrainingCard() {
-		return
+// assembleChips the result of each chip is at the same index as the chip, nil means the assembling panics
+func assembleChips(chips []chipInventory, dmgr devmanager.DeviceInterface, groups MetricGroups,
+	workers int) []*HuaWeiAIChip {
+	if workers <= 0 {
+		workers = DefaultCollectWorkers
+	}
+	if workers > len(chips) {
+		workers = len(chips)
 	}
-	for {
-		setNetInfoWithMap(phyID, networkPackInfo(phyID))
-		time.Sleep(updateTime)
+	chipInfos := make([]*HuaWeiAIChip, len(chips))
+	indexes := make(chan int, len(chips))
+	for i := range chips {
+		indexes <- i
 	}
+	close(indexes)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				chipInfos[i] = assembleChipSafely(chips[i], dmgr, groups)
+			}
+		}()
+	}
+	wg.Wait()
+	return chipInfos
 }
 
-func assembleNPUInfo(cardID int32, logicID int32, dmgr devmanager.DeviceInterface) *HuaWeiAIChip {
-	phyID, err := dmgr.GetPhysicIDFromLogicID(logicID)
-	// check cardId, convert it to int type later
-	if err != nil {
-		hwlog.RunLog.Errorf("failed to get phy id when assemble npu info: %v", err)
-		return nil
+func assembleChipSafely(inv chipInventory, dmgr devmanager.DeviceInterface, groups MetricGroups) *HuaWeiAIChip {
+	defer func() {
+		if err := recover(); err != nil {
+			hwlog.RunLog.Errorf("assemble info of chip(logicID %d) failed with %v", inv.logicID, err)
+		}
+	}()
+	return assembleNPUInfo(inv, dmgr, groups)
+}
+
+// GatherNPUInfo get the npu info of the enabled metric groups synchronously, the network info is queried at the
+// same time for the training card. The chips are assembled by the workers concurrently, 0 means the default workers
+func GatherNPUInfo(dmgr devmanager.DeviceInterface, inventory *DeviceInventory, groups MetricGroups,
+	workers int) []HuaWeiNPUCard {
+	npuList := getNPUInfo(dmgr, inventory, groups, workers)
+	queryNetInfo := groups.needNetInfo() && dmgr.IsTrainingCard()
+	for _, card := range npuList {
+		for _, chip := range card.DeviceList {
+			chip.NetInfo = &NpuNetInfo{}
+			if queryNetInfo {
+				netInfo := networkPackInfo(int32(chip.DeviceID), groups)
+				chip.NetInfo = &netInfo
+			}
+		}
 	}
-	chipInfo := packChipInfo(logicID, dmgr)
-	chipInfo.DeviceID = int(phyID)
+	return npuList
+}
 
+func assembleNPUInfo(inv chipInventory, dmgr devmanager.DeviceInterface, groups MetricGroups) *HuaWeiAIChip {
+	chipInfo := packChipInfo(inv, dmgr, groups)
 	if dmgr.GetDevType() == common.Ascend310P {
-		cardPower, err := dmgr.GetMcuPowerInfo(cardID)
-		if err != nil {
-			hwlog.RunLog.Error(err)
-			cardPower = float32(common.InvalidVal)
+		if groups.enabled(BaseGroup) {
+			cardPower, err := dmgr.GetMcuPowerInfo(inv.cardID)
+			if err != nil {
+				hwlog.RunLog.Error(err)
+				chipInfo.setQueryError(fieldPower, apiGetMcuPowerInfo, err)
+				cardPower = float32(common.InvalidVal)
+			} else {
+				delete(chipInfo.QueryErrors, fieldPower)
+			}
+			// Ascend310P use cardPower to replace chipPower
+			chipInfo.Power = cardPower
 		}
-		// Ascend310P use cardPower to replace chipPower
-		chipInfo.Power = cardPower
-		vDevInfos, err := dmgr.GetVirtualDeviceInfo(logicID)
+		if !groups.enabled(VNPUGroup) {
+			return chipInfo
+		}
+		vDevInfos, err := dmgr.GetVirtualDeviceInfo(inv.logicID)
 		if err != nil || vDevInfos.TotalResource.VDevNum == 0 {
 			return chipInfo
 		}
@@ -410,160 +414,98 @@ func start(ctx context.Context, n *npuCollector, dmgr devmanager.DeviceInterface
 		hwlog.RunLog.Error("Invalid param in function start")
 		return
 	}
-	if err := n.devicesParser.Init(); err != nil {
-		hwlog.RunLog.Errorf("failed to init devices parser: %v", err)
-	}
-	defer n.devicesParser.Close()
-	n.devicesParser.Timeout = n.updateTime
-	hwlog.RunLog.Infof("Starting update cache every %d seconds", n.updateTime/time.Second)
-
+	hwlog.RunLog.Infof("Starting update cache, intervals: %s, enabled metric groups: %s", n.schedule.get(),
+		n.metricGroups)
 	group := &sync.WaitGroup{}
+	if n.metricGroups.enabled(BaseGroup) {
+		subscribeFaultEvent(ctx, group, n.faultRecorder, dmgr)
+	}
 
-	npuBaseInfoCollect(group, n, dmgr)
-	npuNetworkInfoCollect(group, n, dmgr)
-	containerInfoCollect(group, n)
+	inventoryCollect(ctx, group, n, dmgr)
+	npuBaseInfoCollect(ctx, group, n, dmgr)
+	if n.metricGroups.needNetInfo() {
+		npuNetworkInfoCollect(ctx, group, n, dmgr)
+	}
+	if n.metricGroups.needContainerInfo() {
+		if err := n.devicesParser.Init(); err != nil {
+			hwlog.RunLog.Errorf("failed to init devices parser: %v", err)
+			n.readiness.setRuntimeErr(err)
+		}
+		defer n.devicesParser.Close()
+		n.devicesParser.Timeout = n.schedule.container()
+		containerInfoCollect(ctx, group, n)
+	}
 
 	group.Wait()
 	hwlog.RunLog.Info("received the stop signal,STOPPED")
 	return
 }
 
-func npuBaseInfoCollect(group *sync.WaitGroup, n *npuCollector, dmgr devmanager.DeviceInterface) {
-	group.Add(1)
-	go func() {
-		defer group.Done()
-		ticker := time.NewTicker(n.updateTime)
-		defer ticker.Stop()
-		for {
-			npuInfo := getNPUInfo(dmgr)
-			if err := n.cache.Set(npuListCacheKey, npuInfo, n.cacheTime); err != nil {
-				hwlog.RunLog.Error(err)
-			} else {
-				hwlog.RunLog.Infof("update cache,key is %s", npuListCacheKey)
-			}
-			if _, ok := <-ticker.C; !ok {
-				hwlog.RunLog.Errorf("%s ticker failed, task shutdown", npuListCacheKey)
-				return
-			}
+// inventoryCollect re-discover the chips, the static info is queried only for the new chips
+func inventoryCollect(ctx context.Context, group *sync.WaitGroup, n *npuCollector,
+	dmgr devmanager.DeviceInterface) {
+	const name = "npu-exporter-inventory"
+	runPeriodically(ctx, group, name, n.schedule.static, func() {
+		if err := n.inventory.Refresh(dmgr); err != nil {
+			hwlog.RunLog.Errorf("failed to discover npu, error is: %v", err)
 		}
-	}()
+	})
 }
 
-func npuNetworkInfoCollect(group *sync.WaitGroup, n *npuCollector, dmgr devmanager.DeviceInterface) {
-	group.Add(1)
-	netInfo := make(map[int32]NpuNetInfo, initSize)
-	startToGetNetInfo(dmgr, n.updateTime)
-	go func() {
-		defer group.Done()
-		ticker := time.NewTicker(n.updateTime)
-		defer ticker.Stop()
-		for {
-			obj, err := n.cache.Get(npuNetworkCacheKey)
-			if err != nil {
-				hwlog.RunLog.Warnf("get info of %s failed: %v, so use initial net info", npuNetworkCacheKey, err)
-			} else {
-				if oldNetWorkInfo, ok := obj.(map[int32]NpuNetInfo); ok {
-					netInfo = oldNetWorkInfo
-				} else {
-					hwlog.RunLog.Warn("format of net info in cache is not right")
-				}
-			}
-			// get current net info from map to update cache
-			newNetInfo := getNetInfoFromMap(netInfo)
-			if err := n.cache.Set(npuNetworkCacheKey, newNetInfo, n.cacheTime); err != nil {
-				hwlog.RunLog.Error(err)
-			} else {
-				hwlog.RunLog.Infof("update cache,key is %s", npuNetworkCacheKey)
-			}
-			if _, ok := <-ticker.C; !ok {
-				hwlog.RunLog.Errorf("%s ticker failed, task shutdown", npuNetworkCacheKey)
-				return
+func npuBaseInfoCollect(ctx context.Context, group *sync.WaitGroup, n *npuCollector,
+	dmgr devmanager.DeviceInterface) {
+	runPeriodically(ctx, group, npuListCacheKey, n.schedule.fast, func() {
+		npuInfo := getNPUInfo(dmgr, n.inventory, n.metricGroups, n.workers)
+		n.queryErrors.record(npuInfo)
+		n.readiness.queried(npuInfo)
+		if err := n.cache.Set(npuListCacheKey, npuInfo, n.cacheTime); err != nil {
+			hwlog.RunLog.Error(err)
+		} else {
+			if anyChipReached(npuInfo) {
+				n.freshness.update(ChipCadence)
 			}
+			n.readiness.populate(npuListCacheKey)
+			hwlog.RunLog.Infof("update cache,key is %s", npuListCacheKey)
 		}
-	}()
+	})
 }
 
-func containerInfoCollect(group *sync.WaitGroup, n *npuCollector) {
-	group.Add(1)
-	go func() {
-		defer group.Done()
-		ticker := time.NewTicker(n.updateTime)
-		defer ticker.Stop()
-		for {
-			n.devicesParser.FetchAndParse(nil)
-			select {
-			case result := <-n.devicesParser.RecvResult():
-				if err := n.cache.Set(containersDevicesCacheKey, result, n.cacheTime); err != nil {
-					hwlog.RunLog.Error(err)
-				}
-				hwlog.RunLog.Infof("update cache,key is %s", containersDevicesCacheKey)
-			case err := <-n.devicesParser.RecvErr():
-				hwlog.RunLog.Errorf("received error from device parser: %v", err)
-			}
-			if _, ok := <-ticker.C; !ok {
-				hwlog.RunLog.Errorf("%s ticker failed, task shutdown", containersDevicesCacheKey)
-				return
-			}
+func npuNetworkInfoCollect(ctx context.Context, group *sync.WaitGroup, n *npuCollector,
+	dmgr devmanager.DeviceInterface) {
+	runPeriodically(ctx, group, npuNetworkCacheKey, n.schedule.network, func() {
+		// follow the re-discovered chips, so that the workers of the removed chips are stopped
+		n.syncNetWorkers(ctx, group, dmgr)
+		// get current net info from map to update cache
+		newNetInfo := n.getNetInfoFromMap()
+		if err := n.cache.Set(npuNetworkCacheKey, newNetInfo, n.cacheTime); err != nil {
+			hwlog.RunLog.Error(err)
+		} else {
+			n.readiness.populate(npuNetworkCacheKey)
+			hwlog.RunLog.Infof("update cache,key is %s", npuNetworkCacheKey)
 		}
-	}()
-}
-
-func describeBaseChipInfo(ch chan<- *prometheus.Desc) {
-	ch <- versionInfoDesc
-	ch <- machineInfoNPUDesc
-	ch <- npuChipInfoDescUtil
-	ch <- npuChipInfoDescTemp
-	ch <- npuChipInfoDescPower
-	ch <- npuChipInfoDescVoltage
-	ch <- npuChipInfoDescHealthStatus
-	ch <- npuChipInfoDescHbmUsedMemory
-	ch <- npuChipInfoDescHbmTotalMemory
-	ch <- npuChipInfoDescUsedMemory
-	ch <- npuChipInfoDescTotalMemory
-	ch <- npuChipInfoDescErrorCode
-	ch <- npuChipInfoDescNpuName
-}
-
-func describeOpticalInfo(ch chan<- *prometheus.Desc) {
-	ch <- npuChipOpticalState
-	ch <- npuChipOpticalTxPower0
-	ch <- npuChipOpticalTxPower1
-	ch <- npuChipOpticalTxPower2
-	ch <- npuChipOpticalTxPower3
-	ch <- npuChipOpticalRxPower0
-	ch <- npuChipOpticalRxPower1
-	ch <- npuChipOpticalRxPower2
-	ch <- npuChipOpticalRxPower3
-	ch <- npuChipOpticalVcc
-	ch <- npuChipOpticalTemp
+	})
 }
 
-func describeRoCEInfo(ch chan<- *prometheus.Desc) {
-	ch <- npuChipInfoDescNetworkStatus
-	ch <- npuChipInfoDescBandwidthTx
-	ch <- npuChipInfoDescBandwidthRx
-	ch <- npuChipInfoDescLinkStatus
-	ch <- npuChipLinkSpeed
-	ch <- npuChipLinkUpNum
-	ch <- npuChipMacRxPauseNum
-	ch <- npuChipMacTxPauseNum
-	ch <- npuChipMacRxPfcPktNum
-	ch <- npuChipMacTxPfcPktNum
-	ch <- npuChipMacRxBadPktNum
-	ch <- npuChipMacTxBadPktNum
-	ch <- npuChipRoceRxAllPktNum
-	ch <- npuChipRoceTxAllPktNum
-	ch <- npuChipRoceRxErrPktNum
-	ch <- npuChipRoceTxErrPktNum
-	ch <- npuChipRoceRxCnpPktNum
-	ch <- npuChipRoceTxCnpPktNum
-	ch <- npuChipRoceNewPktRtyNum
-	ch <- npuChipMacTxBadOctNum
-	ch <- npuChipMacRxBadOctNum
-	ch <- npuChipRoceUnexpectedAcktNum
-	ch <- npuChipRoceOutOfOrderNum
-	ch <- npuChipRoceVerificationErrNum
-	ch <- npuChipRoceQpStatusErrNum
+func containerInfoCollect(ctx context.Context, group *sync.WaitGroup, n *npuCollector) {
+	runPeriodically(ctx, group, containersDevicesCacheKey, n.schedule.container, func() {
+		start := time.Now()
+		n.devicesParser.FetchAndParse(nil)
+		select {
+		case result := <-n.devicesParser.RecvResult():
+			selfMetrics.observeStage(stageContainerParse, start)
+			n.readiness.setRuntimeErr(nil)
+			if err := n.cache.Set(containersDevicesCacheKey, result, n.cacheTime); err != nil {
+				hwlog.RunLog.Error(err)
+			} else {
+				n.freshness.update(ContainerCadence)
+				n.readiness.populate(containersDevicesCacheKey)
+			}
+			hwlog.RunLog.Infof("update cache,key is %s", containersDevicesCacheKey)
+		case err := <-n.devicesParser.RecvErr():
+			hwlog.RunLog.Errorf("received error from device parser: %v", err)
+			n.readiness.setRuntimeErr(err)
+		}
+	})
 }
 
 // Describe implements prometheus.Collector
@@ -572,18 +514,24 @@ func (n *npuCollector) Describe(ch chan<- *prometheus.Desc) {
 		hwlog.RunLog.Error("Invalid param in function Describe")
 		return
 	}
-	describeBaseChipInfo(ch)
-	describeOpticalInfo(ch)
-	describeRoCEInfo(ch)
-	ch <- npuContainerInfo
-	ch <- npuContainerTotalMemory
-	ch <- npuContainerUsedMemory
-	ch <- npuContainerUtilization
-	ch <- npuChipInfoDescDevProcessInfo
-	ch <- npuChipInfoDescAICoreFreqInfo
-	ch <- podAiCoreUtilizationRate
-	ch <- podTotalMemory
-	ch <- podUsedMemory
+	if n.naming.v2() {
+		var flush func()
+		ch, flush = describeWithNaming(ch, n.naming)
+		defer flush()
+	}
+	ch <- n.versionInfoDesc
+	ch <- n.machineInfoNPUDesc
+	n.queryErrors.counter.Describe(ch)
+	selfMetrics.Describe(ch)
+	n.freshness.Describe(ch)
+	ch <- n.breakerStateDesc
+	ch <- n.inventoryDesc
+	for _, group := range n.metricGroups.Groups() {
+		group.Describe(ch)
+	}
+	if n.metricGroups.enabled(BaseGroup) {
+		n.faultRecorder.Describe(ch)
+	}
 }
 
 // Collect implements prometheus.Collector
@@ -592,64 +540,129 @@ func (n *npuCollector) Collect(ch chan<- prometheus.Metric) {
 		hwlog.RunLog.Error("Invalid param in function Collect")
 		return
 	}
-	npuList := getNPUInfoInCache(ch, n)
-	networkInfoMap := getNetworkInfoInCache(ch, n)
-	containerMap := getContainerNPUInfo(ch, n)
-	ch <- prometheus.MustNewConstMetric(versionInfoDesc, prometheus.GaugeValue, 1, []string{versions.BuildVersion}...)
-	var totalCount = 0
-	for _, card := range npuList {
-		deviceCount := len(card.DeviceList)
-		if deviceCount <= 0 {
-			continue
+	if n.omitFailedValues {
+		var flush func()
+		ch, flush = omitNaNMetrics(ch)
+		defer flush()
+	}
+	if n.naming.v2() {
+		var flush func()
+		ch, flush = collectWithNaming(ch, n.naming)
+		defer flush()
+	}
+	cached := n.loadCache()
+	ch <- prometheus.MustNewConstMetric(n.versionInfoDesc, prometheus.GaugeValue, 1,
+		[]string{versions.BuildVersion}...)
+	n.freshness.collect(ch, n.metricGroups.Groups())
+	groups := n.freshness.freshGroups(n.metricGroups.Groups())
+	totalCount := n.visitChips(cached, func(card *HuaWeiNPUCard, chip *HuaWeiAIChip, devInfo container.DevicesInfo) {
+		for _, group := range groups {
+			group.Collect(ch, card, chip, devInfo)
 		}
-		totalCount += deviceCount
+	})
+
+	ch <- prometheus.MustNewConstMetric(n.machineInfoNPUDesc, prometheus.GaugeValue, float64(totalCount))
+	n.queryErrors.counter.Collect(ch)
+	selfMetrics.Collect(ch)
+	n.collectBreakerStates(ch)
+	added, removed := n.inventory.changes()
+	ch <- prometheus.MustNewConstMetric(n.inventoryDesc, prometheus.CounterValue, float64(added), inventoryAdded)
+	ch <- prometheus.MustNewConstMetric(n.inventoryDesc, prometheus.CounterValue, float64(removed),
+		inventoryRemoved)
+	if n.metricGroups.enabled(BaseGroup) {
+		n.faultRecorder.Collect(ch)
+	}
+}
+
+// cachedInfo the info of the chips, their network and their containers in the cache
+type cachedInfo struct {
+	npuList        []HuaWeiNPUCard
+	networkInfoMap map[int32]NpuNetInfo
+	containerMap   map[int]container.DevicesInfo
+}
+
+// loadCache load the info which is needed by the enabled groups from the cache, the cache of the chips and the
+// containers is rebuilt at the first load if it is missed
+func (n *npuCollector) loadCache() cachedInfo {
+	cached := cachedInfo{npuList: getNPUInfoInCache(n)}
+	if n.metricGroups.needNetInfo() {
+		cached.networkInfoMap = getNetworkInfoInCache(n)
+	}
+	if n.metricGroups.needContainerInfo() {
+		cached.containerMap = getContainerNPUInfo(n)
+	}
+	return cached
+}
+
+// visitChips visit each cached chip with its network info and the devices info of its container, the count of the
+// chips is returned
+func (n *npuCollector) visitChips(cached cachedInfo, visit func(card *HuaWeiNPUCard, chip *HuaWeiAIChip,
+	devInfo container.DevicesInfo)) int {
+	var totalCount = 0
+	for i := range cached.npuList {
+		card := &cached.npuList[i]
+		totalCount += len(card.DeviceList)
 		for _, chip := range card.DeviceList {
-			deviceID := chip.DeviceID
-			if devNetWorkInfo, ok := networkInfoMap[int32(deviceID)]; ok {
-				chip.NetInfo = &devNetWorkInfo
+			// the cached chip is shared with the other readers, so the network info is set on a copy
+			visited := *chip
+			deviceID := visited.DeviceID
+			if devNetWorkInfo, ok := cached.networkInfoMap[int32(deviceID)]; ok {
+				visited.NetInfo = &devNetWorkInfo
 			} else {
-				hwlog.RunLog.Warn("no network information at the moment, so use initial info")
-				chip.NetInfo = &NpuNetInfo{}
+				if n.metricGroups.needNetInfo() {
+					hwlog.RunLog.Warn("no network information at the moment, so use initial info")
+				}
+				visited.NetInfo = &NpuNetInfo{}
 			}
 
-			if chip.VDevActivityInfo.IsVirtualDev {
-				deviceID = int(chip.VDevActivityInfo.VDevID)
-			}
-			devInfo, ok := containerMap[deviceID]
+			devInfo, ok := cached.containerMap[containerKey(&visited)]
 			if !ok {
 				devInfo = container.DevicesInfo{}
 			}
-			updateNPUCommonInfo(ch, &card, chip)
-			updateNPUMemoryInfo(ch, &card, chip)
-			updateNPUNetworkInfo(ch, &card, chip)
-			updateProcessInfo(ch, &card, chip, devInfo)
-			updateContainerInfo(ch, &card, chip, devInfo)
-			updatePodVNPUInfo(ch, &card, chip, devInfo)
+			visit(card, &visited, devInfo)
 		}
 	}
-
-	ch <- prometheus.MustNewConstMetric(machineInfoNPUDesc, prometheus.GaugeValue, float64(totalCount))
+	return totalCount
 }
 
-func getNPUInfoInCache(ch chan<- prometheus.Metric, n *npuCollector) []HuaWeiNPUCard {
-	if ch == nil {
-		hwlog.RunLog.Error("metric channel is nil")
-		return nil
+func (n *npuCollector) collectBreakerStates(ch chan<- prometheus.Metric) {
+	if n.driver == nil {
+		return
+	}
+	for logicID, state := range n.driver.BreakerStates() {
+		ch <- prometheus.MustNewConstMetric(n.breakerStateDesc, prometheus.GaugeValue, float64(state),
+			strconv.Itoa(int(logicID)))
 	}
+}
+
+func getNPUInfoInCache(n *npuCollector) []HuaWeiNPUCard {
 	obj, err := n.cache.Get(npuListCacheKey)
-	npuChipInfoInit.Do(func() {
+	if err != nil {
+		selfMetrics.cacheMissed(cacheNPUList)
+	}
+	n.chipInfoInit.Do(func() {
 		if err != nil {
 			hwlog.RunLog.Debugf("no cache, start to get npulist and rebuild cache")
-			devManager, err := devmanager.GetDeviceManager()
-			if err != nil {
-				hwlog.RunLog.Debugf("get device manager failed, error is: %v ", err)
-				return
+			var devManager devmanager.DeviceInterface = n.driver
+			if n.driver == nil {
+				dmgr, err := devmanager.GetDeviceManager()
+				if err != nil {
+					hwlog.RunLog.Debugf("get device manager failed, error is: %v ", err)
+					return
+				}
+				devManager = dmgr
 			}
-			npuInfo := getNPUInfo(devManager)
+			npuInfo := getNPUInfo(devManager, n.inventory, n.metricGroups, n.workers)
+			n.queryErrors.record(npuInfo)
+			n.readiness.queried(npuInfo)
 			if err = n.cache.Set(npuListCacheKey, npuInfo, n.cacheTime); err != nil {
 				hwlog.RunLog.Errorf("no cache for prometheus, try to build cache failed, error is: %v", err)
 				return
 			}
+			if anyChipReached(npuInfo) {
+				n.freshness.update(ChipCadence)
+			}
+			n.readiness.populate(npuListCacheKey)
 			hwlog.RunLog.Debugf("rebuild cache successfully")
 			obj = npuInfo
 		}
@@ -664,14 +677,11 @@ func getNPUInfoInCache(ch chan<- prometheus.Metric, n *npuCollector) []HuaWeiNPU
 	return npuList
 }
 
-func getNetworkInfoInCache(ch chan<- prometheus.Metric, n *npuCollector) map[int32]NpuNetInfo {
+func getNetworkInfoInCache(n *npuCollector) map[int32]NpuNetInfo {
 	res := make(map[int32]NpuNetInfo, initSize)
-	if ch == nil {
-		hwlog.RunLog.Error("metric channel is nil")
-		return res
-	}
 	obj, err := n.cache.Get(npuNetworkCacheKey)
 	if err != nil {
+		selfMetrics.cacheMissed(cacheNetworkInfo)
 		hwlog.RunLog.Warn("npu network info not found in cache, please wait for the cache to be rebuilt")
 		return res
 	}
@@ -685,14 +695,13 @@ func getNetworkInfoInCache(ch chan<- prometheus.Metric, n *npuCollector) map[int
 	return networkInfoList
 }
 
-func getContainerNPUInfo(ch chan<- prometheus.Metric, n *npuCollector) map[int]container.DevicesInfo {
-	if ch == nil {
-		hwlog.RunLog.Error("metric channel is nil")
-		return nil
-	}
+func getContainerNPUInfo(n *npuCollector) map[int]container.DevicesInfo {
 	obj, err := n.cache.Get(containersDevicesCacheKey)
+	if err != nil {
+		selfMetrics.cacheMissed(cacheContainerDevices)
+	}
 	// only run once to prevent wait when container info get failed
-	npuContainerInfoInit.Do(func() {
+	n.containerInfoInit.Do(func() {
 		if err != nil {
 			hwlog.RunLog.Warn("containers' devices info not found in cache, rebuilding")
 			resultChan := make(chan container.DevicesInfos, 1)
@@ -745,407 +754,194 @@ func getContainerNameArray(devInfo container.DevicesInfo) []string {
 	return strings.Split(devInfo.Name, "_")
 }
 
-func updateNPUMemoryInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip) {
-	if !validate(ch, npu, chip, chip.HbmInfo, chip.Meminf) {
-		hwlog.RunLog.Error("Invalid param in function updateNPUMemoryInfo")
-		return
-	}
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipInfoDescHbmUsedMemory, prometheus.GaugeValue, float64(chip.HbmInfo.Usage),
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipInfoDescHbmTotalMemory, prometheus.GaugeValue,
-			float64(chip.HbmInfo.MemorySize), []string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID,
-				chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescUsedMemory,
-		prometheus.GaugeValue, float64(chip.Meminf.MemorySize-chip.Meminf.MemoryAvailable),
-		[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipInfoDescTotalMemory, prometheus.GaugeValue,
-			float64(chip.Meminf.MemorySize), []string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID,
-				chip.PCIeBusInfo}...))
-}
-
-func updateStatInfoOfMac(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip) {
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacRxPauseNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacRxPauseNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacTxPauseNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacTxPauseNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacRxPfcPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacRxPfcPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacTxPfcPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacTxPfcPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacRxBadPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacRxBadPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacTxBadPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacTxBadPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacTxBadOctNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacTxBadOctNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipMacRxBadOctNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.MacRxBadOctNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-
-}
-
-func updateStatInfoOfRoCE(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip) {
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceRxAllPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceRxAllPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceTxAllPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceTxAllPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceRxErrPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceRxErrPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceTxErrPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceTxErrPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceRxCnpPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceRxCnpPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceTxCnpPktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceTxCnpPktNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceNewPktRtyNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceNewPktRtyNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceUnexpectedAcktNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceUnexpectedAckNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceOutOfOrderNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceOutOfOrderNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceVerificationErrNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceVerificationErrNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipRoceQpStatusErrNum, prometheus.GaugeValue, chip.NetInfo.StatInfo.RoceQpStatusErrNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
+// snapshotQuery the chip field which is queried in the snapshot and the api which is recorded when it fails
+type snapshotQuery struct {
+	snapshotField common.SnapshotField
+	field         string
+	api           string
 }
 
-func updateOpticalInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip) {
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalState, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalState,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalTxPower0, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalTxPower0,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalTxPower1, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalTxPower1,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalTxPower2, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalTxPower2,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalTxPower3, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalTxPower3,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalRxPower0, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalRxPower0,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalRxPower1, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalRxPower1,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalRxPower2, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalRxPower2,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalRxPower3, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalRxPower3,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalVcc, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalVcc,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipOpticalTemp, prometheus.GaugeValue, chip.NetInfo.OpticalInfo.OpticalTemp,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
+var snapshotQueries = []snapshotQuery{
+	{snapshotField: common.SnapshotHealth, field: fieldHealthStatus, api: apiGetDeviceHealth},
+	{snapshotField: common.SnapshotNetworkHealth, field: fieldNetworkStatus, api: apiGetDeviceNetWorkHealth},
+	{snapshotField: common.SnapshotAICoreFreq, field: fieldAICoreFreq, api: apiGetDeviceFrequency},
+	{snapshotField: common.SnapshotPower, field: fieldPower, api: apiGetDevicePowerInfo},
+	{snapshotField: common.SnapshotTemperature, field: fieldTemperature, api: apiGetDeviceTemperature},
+	{snapshotField: common.SnapshotVoltage, field: fieldVoltage, api: apiGetDeviceVoltage},
+	{snapshotField: common.SnapshotUtilization, field: fieldUtilization, api: apiGetDeviceUtilizationRate},
+	{snapshotField: common.SnapshotHbmUtilization, field: fieldHbmUtilization, api: apiGetDeviceUtilizationRate},
+	{snapshotField: common.SnapshotMemory, field: fieldMemory, api: apiGetDeviceMemoryInfo},
+	{snapshotField: common.SnapshotHbm, field: fieldHbm, api: apiGetDeviceHbmInfo},
+	{snapshotField: common.SnapshotErrorCodes, field: fieldErrorCode, api: apiGetDeviceAllErrorCode},
+	{snapshotField: common.SnapshotProcess, field: fieldProcess, api: apiGetDevProcessInfo},
 }
 
-func updateNPUNetworkInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip) {
-	if !validate(ch, npu, chip) {
-		hwlog.RunLog.Error("Invalid param in function updateNPUNetworkInfo")
-		return
+var packChipInfo = func(inv chipInventory, dmgr devmanager.DeviceInterface, groups MetricGroups) *HuaWeiAIChip {
+	chip := &HuaWeiAIChip{
+		ChipIfo:         inv.chipInfo,
+		BoardInfo:       inv.boardInfo,
+		DeviceID:        int(inv.phyID),
+		LogicID:         inv.logicID,
+		VDieID:          inv.vdieID,
+		PCIeBusInfo:     inv.pcieBusInfo,
+		Meminf:          &common.MemoryInfo{},
+		HbmInfo:         &common.HbmInfo{},
+		DevProcessInfo:  new(common.DevProcessInfo),
+		NetHealthStatus: UnHealthy,
+		LinkStatus:      LinkDown,
 	}
-	updateStatInfoOfMac(ch, npu, chip)
-	updateStatInfoOfRoCE(ch, npu, chip)
-	updateOpticalInfo(ch, npu, chip)
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipInfoDescBandwidthTx, prometheus.GaugeValue, chip.NetInfo.BandwidthInfo.TxValue,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipInfoDescBandwidthRx, prometheus.GaugeValue, chip.NetInfo.BandwidthInfo.RxValue,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipInfoDescNetworkStatus, prometheus.GaugeValue,
-			float64(getHealthCode(chip.NetHealthStatus)), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-				common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipLinkSpeed, prometheus.GaugeValue, chip.NetInfo.LinkSpeedInfo.Speed,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipLinkUpNum, prometheus.GaugeValue, chip.NetInfo.LinkStatInfo.LinkUPNum,
-			[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-}
-
-func updateContainerInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
-	devInfo container.DevicesInfo) {
-	containerName := getContainerNameArray(devInfo)
-	if len(containerName) != containerNameLen {
-		return
+	if chip.ChipIfo == nil {
+		chip.ChipIfo = &common.ChipInfo{}
 	}
-	ch <- prometheus.MustNewConstMetric(npuContainerInfo, prometheus.GaugeValue, 1,
-		[]string{devInfo.ID, strings.Join(containerName, "_"), strconv.Itoa(chip.DeviceID), common.GetNpuName(*chip.ChipIfo), chip.VDieID,
-			chip.PCIeBusInfo}...)
-	if common.IsValidVDevID(chip.VDevActivityInfo.VDevID) {
-		return
+	fields := snapshotFieldsOf(groups, chip.ChipIfo, dmgr.IsTrainingCard())
+	if fields != 0 {
+		snapshot, err := dmgr.GetChipSnapshot(inv.logicID, fields)
+		if err != nil {
+			hwlog.RunLog.Errorf("get snapshot of chip(logicID %d) failed: %v", inv.logicID, err)
+			snapshot = failedSnapshot(inv.logicID, fields, err)
+		}
+		setQueryErrors(chip, snapshot, fields, dmgr.GetProductTypeArray())
+		packChipInfoPart1(chip, snapshot, fields)
+		packChipInfoPart2(chip, snapshot, fields)
 	}
-	updateContainerNPUMemoryInfo(ch, npu, chip, containerName)
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuContainerUtilization,
-		prometheus.GaugeValue, float64(chip.Utilization), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			containerName[nameSpaceIdx], containerName[podNameIdx], containerName[conNameIdx], common.GetNpuName(*chip.ChipIfo), chip.VDieID,
-			chip.PCIeBusInfo}...))
-
+	if groups.enabled(NetworkGroup) && dmgr.IsTrainingCard() {
+		chip.LinkStatus = hccn.GetNPULinkStatus(inv.phyID)
+	}
+	return chip
 }
 
-func updatePodVNPUInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
-	devInfo container.DevicesInfo) {
-	if !strings.Contains(chip.ChipIfo.Name, "310P") || !common.IsValidVDevID(chip.VDevActivityInfo.VDevID) {
-		return
+// snapshotFieldsOf get the snapshot fields which are needed by the enabled groups
+func snapshotFieldsOf(groups MetricGroups, chipInfo *common.ChipInfo, trainingCard bool) common.SnapshotField {
+	var fields common.SnapshotField
+	if groups.enabled(BaseGroup) {
+		fields |= common.SnapshotHealth | common.SnapshotAICoreFreq | common.SnapshotPower |
+			common.SnapshotTemperature | common.SnapshotVoltage | common.SnapshotErrorCodes
 	}
-	containerName := getContainerNameArray(devInfo)
-	if len(containerName) != containerNameLen {
-		return
+	if groups.needMemoryInfo() {
+		fields |= common.SnapshotMemory | common.SnapshotHbm
+		if strings.Contains(chipInfo.Name, common.Chip910) {
+			fields |= common.SnapshotHbmUtilization
+		}
 	}
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(podAiCoreUtilizationRate, prometheus.GaugeValue,
-			float64(chip.VDevActivityInfo.VDevAiCoreRate), getPodDisplayInfo(chip, containerName)...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(podTotalMemory, prometheus.GaugeValue,
-			float64(chip.VDevActivityInfo.VDevTotalMem), getPodDisplayInfo(chip, containerName)...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(podUsedMemory, prometheus.GaugeValue,
-			float64(chip.VDevActivityInfo.VDevUsedMem), getPodDisplayInfo(chip, containerName)...))
-}
-
-func updateContainerNPUMemoryInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
-	containerName []string) {
-	if strings.Contains(chip.ChipIfo.Name, common.Chip910) {
-		ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-			prometheus.MustNewConstMetric(npuContainerTotalMemory, prometheus.GaugeValue,
-				float64(chip.HbmInfo.MemorySize), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-					containerName[nameSpaceIdx], containerName[podNameIdx], containerName[conNameIdx],
-					common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-		ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-			prometheus.MustNewConstMetric(npuContainerUsedMemory, prometheus.GaugeValue, float64(chip.HbmInfo.Usage),
-				[]string{strconv.FormatInt(int64(chip.DeviceID), base), containerName[nameSpaceIdx],
-					containerName[podNameIdx], containerName[conNameIdx],
-					common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-		return
+	if groups.needUtilization() {
+		fields |= common.SnapshotUtilization
+	}
+	if groups.enabled(ProcessGroup) {
+		fields |= common.SnapshotProcess
+	}
+	if groups.enabled(NetworkGroup) && trainingCard {
+		fields |= common.SnapshotNetworkHealth
 	}
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuContainerTotalMemory,
-		prometheus.GaugeValue, float64(chip.Meminf.MemorySize), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			containerName[nameSpaceIdx], containerName[podNameIdx], containerName[conNameIdx], common.GetNpuName(*chip.ChipIfo), chip.VDieID,
-			chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuContainerUsedMemory,
-		prometheus.GaugeValue, float64(chip.Meminf.MemorySize-chip.Meminf.MemoryAvailable),
-		[]string{strconv.FormatInt(int64(chip.DeviceID), base), containerName[nameSpaceIdx],
-			containerName[podNameIdx], containerName[conNameIdx], common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
+	return fields
 }
 
-func updateNPUCommonInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip) {
-	if !validate(ch, npu, chip, chip.ChipIfo) {
-		hwlog.RunLog.Error("Invalid param in function updateNpuCommonInfo")
-		return
+// failedSnapshot all the fields fail with the error
+func failedSnapshot(logicID int32, fields common.SnapshotField, err error) *common.ChipSnapshot {
+	snapshot := &common.ChipSnapshot{LogicID: logicID}
+	for _, query := range snapshotQueries {
+		if fields.Has(query.snapshotField) {
+			snapshot.SetErr(query.snapshotField, err)
+		}
 	}
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescLinkStatus,
-		prometheus.GaugeValue, float64(hccn.GetLinkStatusCode(chip.LinkStatus)),
-		[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescUtil,
-		prometheus.GaugeValue, float64(chip.Utilization), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescTemp,
-		prometheus.GaugeValue, float64(chip.Temperature), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescPower,
-		prometheus.GaugeValue, float64(chip.Power), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescVoltage,
-		prometheus.GaugeValue, float64(chip.Voltage), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-		prometheus.MustNewConstMetric(npuChipInfoDescHealthStatus, prometheus.GaugeValue,
-			float64(getHealthCode(chip.HealthStatus)), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-				common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescErrorCode,
-		prometheus.GaugeValue, float64(chip.ErrorCode), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescNpuName,
-		prometheus.GaugeValue, 1, []string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID,
-			chip.PCIeBusInfo}...))
-	ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp, prometheus.MustNewConstMetric(npuChipInfoDescAICoreFreqInfo,
-		prometheus.GaugeValue, float64(chip.AICoreCurrentFreq), []string{strconv.FormatInt(int64(chip.DeviceID), base),
-			common.GetNpuName(*chip.ChipIfo), chip.VDieID, chip.PCIeBusInfo}...))
+	return snapshot
 }
 
-func updateProcessInfo(ch chan<- prometheus.Metric, npu *HuaWeiNPUCard, chip *HuaWeiAIChip,
-	devInfo container.DevicesInfo) {
-	containerName := ""
-	containerID := ""
-	cNameArray := getContainerNameArray(devInfo)
-	if len(cNameArray) == containerNameLen {
-		containerName = strings.Join(cNameArray, "_")
-		containerID = devInfo.ID
-	}
-	if chip.DevProcessInfo.ProcNum == 0 {
-		ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-			prometheus.MustNewConstMetric(npuChipInfoDescDevProcessInfo, prometheus.GaugeValue, 0,
-				[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo),
-					chip.VDieID, "", containerID, containerName, chip.PCIeBusInfo}...))
-		return
-	}
-	for i := int32(0); i < chip.DevProcessInfo.ProcNum; i++ {
-		procInfo := chip.DevProcessInfo.DevProcArray[i]
-		ch <- prometheus.NewMetricWithTimestamp(npu.Timestamp,
-			prometheus.MustNewConstMetric(npuChipInfoDescDevProcessInfo, prometheus.GaugeValue, procInfo.MemUsage,
-				[]string{strconv.FormatInt(int64(chip.DeviceID), base), common.GetNpuName(*chip.ChipIfo), chip.VDieID,
-					strconv.FormatInt(int64(procInfo.Pid), base), containerID, containerName, chip.PCIeBusInfo}...))
+func setQueryErrors(hwChip *HuaWeiAIChip, snapshot *common.ChipSnapshot, fields common.SnapshotField,
+	productTypes []string) {
+	for _, query := range snapshotQueries {
+		err := snapshot.Err(query.snapshotField)
+		if !fields.Has(query.snapshotField) || err == nil {
+			continue
+		}
+		if query.snapshotField == common.SnapshotProcess && len(productTypes) == 1 &&
+			productTypes[0] == common.Atlas200ISoc {
+			hwlog.RunLog.Debugf("process info is not supported on %s", common.Atlas200ISoc)
+			continue
+		}
+		hwChip.setQueryError(query.field, query.api, err)
 	}
 }
 
-var packChipInfo = func(logicID int32, dmgr devmanager.DeviceInterface) *HuaWeiAIChip {
-	chip := &HuaWeiAIChip{}
-
-	info, err := dmgr.GetChipInfo(logicID)
-	if err != nil {
-		hwlog.RunLog.Warnf("get chip info failed: %v", err)
-		info = &common.ChipInfo{}
+// snapshotValue get the value of the field, the invalid value is returned when the field fails
+func snapshotValue(snapshot *common.ChipSnapshot, field common.SnapshotField, value, invalid float64) float64 {
+	if snapshot.Err(field) != nil {
+		return invalid
 	}
-	chip.ChipIfo = info
-
-	packChipInfoPart2(logicID, dmgr, chip)
-	packChipInfoPart1(logicID, dmgr, chip)
-	return chip
+	return value
 }
 
-func packChipInfoPart1(logicID int32, dmgr devmanager.DeviceInterface, hwChip *HuaWeiAIChip) {
-	freq, err := dmgr.GetDeviceFrequency(logicID, common.AICoreCurrentFreq)
-	if err != nil {
-		freq = common.InvalidVal
-	}
-	power, err := dmgr.GetDevicePowerInfo(logicID)
-	if err != nil {
-		power = common.InvalidVal
+func packChipInfoPart1(hwChip *HuaWeiAIChip, snapshot *common.ChipSnapshot, fields common.SnapshotField) {
+	if fields.Has(common.SnapshotMemory) && snapshot.Err(common.SnapshotMemory) == nil && snapshot.Memory != nil {
+		hwChip.Meminf = snapshot.Memory
 	}
-	temp, err := dmgr.GetDeviceTemperature(logicID)
-	if err != nil {
-		temp = common.InvalidVal
+	if fields.Has(common.SnapshotHbm) && snapshot.Err(common.SnapshotHbm) == nil && snapshot.Hbm != nil {
+		hwChip.HbmInfo = snapshot.Hbm
 	}
-	vol, err := dmgr.GetDeviceVoltage(logicID)
-	if err != nil {
-		vol = common.InvalidVal
+	if fields.Has(common.SnapshotHbmUtilization) {
+		hwChip.HbmUtilization = int(snapshotValue(snapshot, common.SnapshotHbmUtilization,
+			float64(snapshot.HbmUtilization), common.InvalidVal))
 	}
-	mem, err := dmgr.GetDeviceMemoryInfo(logicID)
-	if err != nil {
-		mem = &common.MemoryInfo{}
+	if !fields.Has(common.SnapshotHealth) {
+		return
 	}
-	hbmInfo, err := dmgr.GetDeviceHbmInfo(logicID)
-	if err != nil {
-		hbmInfo = &common.HbmInfo{}
+	hwChip.AICoreCurrentFreq = uint32(snapshotValue(snapshot, common.SnapshotAICoreFreq,
+		float64(snapshot.AICoreFreq), common.InvalidVal))
+	hwChip.Power = float32(snapshotValue(snapshot, common.SnapshotPower, float64(snapshot.Power),
+		common.InvalidVal))
+	hwChip.Temperature = int(snapshotValue(snapshot, common.SnapshotTemperature, float64(snapshot.Temperature),
+		common.InvalidVal))
+	hwChip.Voltage = float32(snapshotValue(snapshot, common.SnapshotVoltage, float64(snapshot.Voltage),
+		common.InvalidVal))
+	hwChip.HealthStatus = Healthy
+	if snapshot.Err(common.SnapshotHealth) != nil || snapshot.Health != 0 {
+		hwChip.HealthStatus = UnHealthy
 	}
-
-	hwChip.AICoreCurrentFreq = freq
-	hwChip.Power = power
-	hwChip.HealthStatus = getHealth(logicID, dmgr)
-	hwChip.Temperature = int(temp)
-	hwChip.Voltage = vol
-	hwChip.Meminf = mem
-	hwChip.HbmInfo = hbmInfo
 }
 
-func packChipInfoPart2(logicID int32, dmgr devmanager.DeviceInterface, hwChip *HuaWeiAIChip) {
-	util, err := dmgr.GetDeviceUtilizationRate(logicID, common.AICore)
-	if err != nil {
-		util = common.InvalidVal // valid data range 0-100
+func packChipInfoPart2(hwChip *HuaWeiAIChip, snapshot *common.ChipSnapshot, fields common.SnapshotField) {
+	if fields.Has(common.SnapshotProcess) && snapshot.Err(common.SnapshotProcess) == nil && snapshot.Process != nil {
+		hwChip.DevProcessInfo = snapshot.Process
 	}
-	_, errCode, err := dmgr.GetDeviceErrorCode(logicID)
-	if err != nil {
-		errCode = common.RetError // valid data range 0-128
+	if fields.Has(common.SnapshotNetworkHealth) {
+		netCode := snapshot.NetworkHealth
+		if snapshot.Err(common.SnapshotNetworkHealth) != nil {
+			netCode = math.MaxUint32
+		}
+		hwlog.RunLog.Debugf("chip %d network healthy code is %d", snapshot.LogicID, netCode)
+		hwChip.NetHealthStatus = getNetworkHealthy(netCode)
 	}
-	vdieID, err := dmgr.GetDieID(logicID, dcmi.VDIE)
-	if err != nil {
-		hwlog.RunLog.Debug(err)
+	if fields.Has(common.SnapshotUtilization) {
+		// valid data range 0-100
+		hwChip.Utilization = int(snapshotValue(snapshot, common.SnapshotUtilization, float64(snapshot.Utilization),
+			common.InvalidVal))
 	}
-
-	setNetHealthStatus(logicID, dmgr, hwChip)
-	setProcessInfo(logicID, dmgr, hwChip)
-	setPCIeBusInfo(logicID, dmgr, hwChip)
-	setLinkStatus(logicID, dmgr, hwChip)
-	hwChip.ErrorCode = errCode
-	hwChip.Utilization = int(util)
-	hwChip.VDieID = vdieID
-}
-
-func setNetHealthStatus(logicID int32, dmgr devmanager.DeviceInterface, hwChip *HuaWeiAIChip) {
-	hwChip.NetHealthStatus = UnHealthy
-	if !dmgr.IsTrainingCard() {
+	if !fields.Has(common.SnapshotErrorCodes) {
 		return
 	}
-
-	netCode, err := dmgr.GetDeviceNetWorkHealth(logicID)
-	hwlog.RunLog.Debugf("chip %d network healthy code is %d", logicID, netCode)
-	if err != nil {
-		netCode = math.MaxUint32
+	errCode := int64(common.InvalidVal)
+	errCodes := snapshot.ErrorCodes
+	if snapshot.Err(common.SnapshotErrorCodes) != nil {
+		errCode = common.RetError
+		errCodes = nil
+	} else if len(errCodes) > 0 {
+		// npu_chip_info_error_code keeps reporting the first error code as before
+		errCode = errCodes[0]
 	}
-	hwChip.NetHealthStatus = getNetworkHealthy(netCode)
-}
-
-func setProcessInfo(logicID int32, dmgr devmanager.DeviceInterface, hwChip *HuaWeiAIChip) {
-	productTypes := dmgr.GetProductTypeArray()
-	info, err := dmgr.GetDevProcessInfo(logicID)
-	if err != nil {
-		if len(productTypes) == 1 && productTypes[0] == common.Atlas200ISoc {
-			hwlog.RunLog.Debugf("process info is not supported on %s", common.Atlas200ISoc)
-			hwChip.DevProcessInfo = new(common.DevProcessInfo)
-			return
-		}
-		hwlog.RunLog.Error(err)
-		info = new(common.DevProcessInfo)
-	}
-	hwChip.DevProcessInfo = info
+	hwChip.ErrorCode = errCode
+	hwChip.ErrorCodes = errCodes
 }
 
-func setPCIeBusInfo(logicID int32, dmgr devmanager.DeviceInterface, hwChip *HuaWeiAIChip) {
+func getPCIeBusInfo(logicID int32, dmgr devmanager.DeviceInterface) (string, error) {
 	productTypes := dmgr.GetProductTypeArray()
 	pcieInfo, err := dmgr.GetPCIeBusInfo(logicID)
 	if err != nil {
 		if len(productTypes) == 1 && productTypes[0] == common.Atlas200ISoc {
 			hwlog.RunLog.Debugf("pcie bus info is not supported on %s", common.Atlas200ISoc)
-			hwChip.PCIeBusInfo = ""
-			return
+			return "", nil
 		}
-		hwlog.RunLog.Error(err)
-		pcieInfo = ""
+		return "", err
 	}
-	hwChip.PCIeBusInfo = pcieInfo
-}
-
-func setLinkStatus(logicID int32, dmgr devmanager.DeviceInterface, hwChip *HuaWeiAIChip) {
-	hwChip.LinkStatus = LinkDown
-	if !dmgr.IsTrainingCard() {
-		return
-	}
-
-	phyID, err := dmgr.GetPhysicIDFromLogicID(logicID)
-	if err != nil {
-		hwlog.RunLog.Error("set link status failed")
-		return
-	}
-	hwChip.LinkStatus = hccn.GetNPULinkStatus(phyID)
+	return pcieInfo, nil
 }
 
 func getMainOptInfo(opticalInfo map[string]string) OpticalInfo {
@@ -1195,18 +991,25 @@ func getMainStatInfo(statInfo map[string]int) StatInfo {
 	return mainStatInfo
 }
 
-func networkPackInfo(phyID int32) NpuNetInfo {
+func networkPackInfo(phyID int32, groups MetricGroups) NpuNetInfo {
+	defer selfMetrics.observeStage(stageNetworkInfo, time.Now())
 	newNetInfo := NpuNetInfo{}
+	if groups.enabled(OpticalGroup) {
+		if opticalInfo, err := hccn.GetNPUOpticalInfo(phyID); err == nil {
+			newNetInfo.OpticalInfo = getMainOptInfo(opticalInfo)
+		}
+	}
+	if !groups.enabled(NetworkGroup) {
+		return newNetInfo
+	}
 	if tx, rx, err := hccn.GetNPUInterfaceTraffic(phyID); err == nil {
 		newNetInfo.BandwidthInfo.RxValue = rx
 		newNetInfo.BandwidthInfo.TxValue = tx
 	}
-	if opticalInfo, err := hccn.GetNPUOpticalInfo(phyID); err == nil {
-		newNetInfo.OpticalInfo = getMainOptInfo(opticalInfo)
-	}
 
 	if statInfo, err := hccn.GetNPUStatInfo(phyID); err == nil {
 		newNetInfo.StatInfo = getMainStatInfo(statInfo)
+		newNetInfo.StatQueried = true
 	}
 
 	linkUpNum := hccn.GetNPULinkUpNum(phyID)
@@ -1217,14 +1020,6 @@ func networkPackInfo(phyID int32) NpuNetInfo {
 	return newNetInfo
 }
 
-func getHealth(logicID int32, dmgr devmanager.DeviceInterface) string {
-	health, err := dmgr.GetDeviceHealth(logicID)
-	if err != nil || health != 0 {
-		return UnHealthy
-	}
-	return Healthy
-}
-
 func getHealthCode(health string) int {
 	if Healthy == health {
 		return 1
@@ -17,6 +17,7 @@ package collector
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -24,12 +25,12 @@ import (
 	"github.com/agiledragon/gomonkey/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 
 	"huawei.com/npu-exporter/v5/collector/container"
 	"huawei.com/npu-exporter/v5/collector/container/isula"
 	"huawei.com/npu-exporter/v5/collector/container/v1"
-	"huawei.com/npu-exporter/v5/common-utils/cache"
 	"huawei.com/npu-exporter/v5/common-utils/hwlog"
 	"huawei.com/npu-exporter/v5/devmanager"
 	"huawei.com/npu-exporter/v5/devmanager/common"
@@ -101,7 +102,7 @@ func TestNewNpuCollector(t *testing.T) {
 			path: "testdata/prometheus_metrics",
 			mockFunc: func(ctx context.Context, n *npuCollector, dmgr devmanager.DeviceInterface) {
 				_ = n.devicesParser.Init()
-				npuInfo := mockGetNPUInfo(nil)
+				npuInfo := mockGetNPUInfo(nil, nil, nil, 0)
 				if err := n.cache.Set(npuListCacheKey, npuInfo, n.cacheTime); err != nil {
 					t.Fatal(err)
 				}
@@ -143,7 +144,8 @@ func excuteTestCollector(t *testing.T, tt struct {
 		return &devmanager.DeviceManager{}, nil
 	})
 	defer patch.Reset()
-	c, err := NewNpuCollector(context.Background(), cacheTime, time.Second, makeMockDevicesParser())
+	c, err := NewNpuCollector(context.Background(), makeMockDevicesParser(),
+		NpuCollectorOpts{CacheTime: cacheTime, UpdateTime: time.Second})
 	if err != nil {
 		t.Fatalf("test failes")
 	}
@@ -170,7 +172,8 @@ func TestGetChipInfo(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			chipInfo := packChipInfo(0, tt.mockPart.(devmanager.DeviceInterface))
+			dmgr := tt.mockPart.(devmanager.DeviceInterface)
+			chipInfo := packChipInfo(queryChipInventory(0, 0, 0, dmgr), dmgr, nil)
 			t.Logf("%#v", chipInfo)
 			assert.NotNil(t, chipInfo)
 			if tt.wantErr {
@@ -182,6 +185,52 @@ func TestGetChipInfo(t *testing.T) {
 	}
 }
 
+// TestCollectErrorCodes test every active error code of the chip is exported
+func TestCollectErrorCodes(t *testing.T) {
+	activeCodes := []int64{0x80E18402, 0x80CB8009}
+	chip := &HuaWeiAIChip{
+		DeviceID:   0,
+		ChipIfo:    &common.ChipInfo{Name: "910", Type: "Ascend", Version: "V1"},
+		ErrorCode:  activeCodes[0],
+		ErrorCodes: activeCodes,
+	}
+	npu := &HuaWeiNPUCard{DeviceList: []*HuaWeiAIChip{chip}, Timestamp: time.Now()}
+	// one count metric, and an active metric and an info metric for each error code
+	ch := make(chan prometheus.Metric, 2*len(activeCodes)+1)
+	group := newBaseGroup()
+	group.collectErrorCodes(ch, npu, chip)
+	close(ch)
+	var countValue float64
+	activeSeries := make(map[string]float64, len(activeCodes))
+	infoSeries := make(map[string]float64, len(activeCodes))
+	for metric := range ch {
+		var m dto.Metric
+		assert.Nil(t, metric.Write(&m))
+		switch metric.Desc() {
+		case group.errorCodeCount:
+			countValue = m.GetGauge().GetValue()
+		case group.errorCodeActive:
+			activeSeries[labelValue(&m, errorCode)] = m.GetGauge().GetValue()
+		case group.errorCodeInfo:
+			infoSeries[labelValue(&m, errorCode)] = m.GetGauge().GetValue()
+		default:
+			t.Errorf("unexpected metric %s", metric.Desc())
+		}
+	}
+	assert.Equal(t, float64(len(activeCodes)), countValue)
+	assert.Equal(t, map[string]float64{"0x80E18402": 1, "0x80CB8009": 1}, activeSeries)
+	assert.Equal(t, activeSeries, infoSeries)
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
 // TestGetHealthCode test getHealthCode
 func TestGetHealthCode(t *testing.T) {
 	tests := []struct {
@@ -233,7 +282,7 @@ func TestGetNPUInfo(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := getNPUInfo(tt.args); len(got) != len(tt.want) {
+			if got := getNPUInfo(tt.args, NewDeviceInventory(), nil, 0); len(got) != len(tt.want) {
 				t.Errorf("getNPUInfo() = %#v,want %#v", got, tt.want)
 			}
 		})
@@ -256,7 +305,8 @@ func newTestCase(name string, wantErr bool, mockPart interface{}) testCase {
 	}
 }
 
-func mockGetNPUInfo(dmgr devmanager.DeviceInterface) []HuaWeiNPUCard {
+func mockGetNPUInfo(dmgr devmanager.DeviceInterface, inventory *DeviceInventory, groups MetricGroups,
+	workers int) []HuaWeiNPUCard {
 	var npuList []HuaWeiNPUCard
 	for devicePhysicID := int32(0); devicePhysicID < npuCount; devicePhysicID++ {
 		chipInfo := &HuaWeiAIChip{
@@ -308,12 +358,8 @@ func TestStart(t *testing.T) {
 	}{
 		{
 			name: "should set cache successfully",
-			collector: &npuCollector{
-				cache:         cache.New(cacheSize),
-				cacheTime:     cacheTime,
-				updateTime:    time.Second,
-				devicesParser: makeMockDevicesParser(),
-			},
+			collector: newNpuCollector(makeMockDevicesParser(),
+				NpuCollectorOpts{CacheTime: cacheTime, UpdateTime: time.Second}),
 		},
 	}
 	mk := gomonkey.ApplyFunc(getNPUInfo, mockGetNPUInfo)
@@ -333,6 +379,183 @@ func TestStart(t *testing.T) {
 	}
 }
 
+const wedgedLogicID = 1
+
+// wedgedDeviceManager has two chips, the temperature query of the wedged chip never returns
+type wedgedDeviceManager struct {
+	devmanager.DeviceManagerMock
+	wedged chan struct{}
+}
+
+// GetDeviceNumInCard the card has two chips
+func (d *wedgedDeviceManager) GetDeviceNumInCard(cardID int32) (int32, error) {
+	return 2, nil
+}
+
+// GetDeviceLogicID the logic id is the device id
+func (d *wedgedDeviceManager) GetDeviceLogicID(cardID, deviceID int32) (int32, error) {
+	return deviceID, nil
+}
+
+// GetPhysicIDFromLogicID the physic id is the logic id
+func (d *wedgedDeviceManager) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	return logicID, nil
+}
+
+// GetChipSnapshot compose the snapshot with the wedged temperature query
+func (d *wedgedDeviceManager) GetChipSnapshot(logicID int32, fields common.SnapshotField) (*common.ChipSnapshot,
+	error) {
+	return devmanager.ComposeChipSnapshot(d, logicID, fields)
+}
+
+// GetDeviceTemperature hang until the test finishes for the wedged chip
+func (d *wedgedDeviceManager) GetDeviceTemperature(logicID int32) (int32, error) {
+	if logicID == wedgedLogicID {
+		<-d.wedged
+	}
+	return d.DeviceManagerMock.GetDeviceTemperature(logicID)
+}
+
+// TestGetNPUInfoWithWedgedChip test the healthy chip keeps reporting and the wedged chip is backed off
+func TestGetNPUInfoWithWedgedChip(t *testing.T) {
+	wedged := &wedgedDeviceManager{wedged: make(chan struct{})}
+	defer close(wedged.wedged)
+	dmgr := devmanager.NewGuardedDeviceManager(wedged, devmanager.GuardOpts{CallTimeout: 10 * time.Millisecond,
+		FailureThreshold: 1, OpenTimeout: time.Minute})
+	groups, err := ParseMetricGroups(BaseGroup)
+	assert.Nil(t, err)
+	inventory := NewDeviceInventory()
+	for i := 0; i < 2; i++ {
+		npuList := getNPUInfo(dmgr, inventory, groups, 1)
+		if !assert.Equal(t, 1, len(npuList)) || !assert.Equal(t, 2, len(npuList[0].DeviceList)) {
+			return
+		}
+		healthy, wedgedChip := npuList[0].DeviceList[0], npuList[0].DeviceList[1]
+		assert.False(t, healthy.queryFailed(fieldTemperature))
+		assert.True(t, wedgedChip.queryFailed(fieldTemperature))
+	}
+	assert.Equal(t, devmanager.BreakerOpen, dmgr.BreakerStates()[wedgedLogicID])
+	assert.Equal(t, devmanager.BreakerClosed, dmgr.BreakerStates()[0])
+}
+
+// TestSnapshotFieldsOf test only the snapshot fields of the enabled groups are queried
+func TestSnapshotFieldsOf(t *testing.T) {
+	chipInfo := &common.ChipInfo{Name: common.Chip910}
+	groups, err := ParseMetricGroups("memory,network")
+	assert.Nil(t, err)
+	fields := snapshotFieldsOf(groups, chipInfo, false)
+	assert.Equal(t, common.SnapshotMemory|common.SnapshotHbm|common.SnapshotHbmUtilization, fields)
+	fields = snapshotFieldsOf(groups, &common.ChipInfo{Name: "310P3"}, true)
+	assert.Equal(t, common.SnapshotMemory|common.SnapshotHbm|common.SnapshotNetworkHealth, fields)
+	assert.Equal(t, common.SnapshotAll, snapshotFieldsOf(nil, chipInfo, true))
+}
+
+// TestPackChipInfoWithFailedSnapshot test all the requested fields fail when the snapshot fails
+func TestPackChipInfoWithFailedSnapshot(t *testing.T) {
+	inv := chipInventory{chipInfo: &common.ChipInfo{Name: common.Chip910}}
+	chip := packChipInfo(inv, &devmanager.DeviceManagerMockErr{}, nil)
+	for _, query := range snapshotQueries {
+		if query.snapshotField == common.SnapshotNetworkHealth {
+			continue
+		}
+		assert.True(t, chip.queryFailed(query.field), query.field)
+	}
+	assert.Equal(t, UnHealthy, chip.HealthStatus)
+	assert.Nil(t, chip.ErrorCodes)
+	assert.NotNil(t, chip.DevProcessInfo)
+}
+
+const (
+	benchChipNum  = 16
+	driverLatency = 200 * time.Microsecond
+)
+
+// latencyDeviceManager has 16 chips, each telemetry query costs the driver latency like the cgo call
+type latencyDeviceManager struct {
+	devmanager.DeviceManagerMock
+}
+
+// GetDeviceNumInCard the card has 16 chips
+func (d *latencyDeviceManager) GetDeviceNumInCard(cardID int32) (int32, error) {
+	return benchChipNum, nil
+}
+
+// GetDeviceLogicID the logic id is the device id
+func (d *latencyDeviceManager) GetDeviceLogicID(cardID, deviceID int32) (int32, error) {
+	return deviceID, nil
+}
+
+// GetChipSnapshot compose the snapshot with the latency queries
+func (d *latencyDeviceManager) GetChipSnapshot(logicID int32, fields common.SnapshotField) (*common.ChipSnapshot,
+	error) {
+	return devmanager.ComposeChipSnapshot(d, logicID, fields)
+}
+
+// GetDeviceTemperature query the temperature with the driver latency
+func (d *latencyDeviceManager) GetDeviceTemperature(logicID int32) (int32, error) {
+	time.Sleep(driverLatency)
+	return d.DeviceManagerMock.GetDeviceTemperature(logicID)
+}
+
+// GetDevicePowerInfo query the power with the driver latency
+func (d *latencyDeviceManager) GetDevicePowerInfo(logicID int32) (float32, error) {
+	time.Sleep(driverLatency)
+	return d.DeviceManagerMock.GetDevicePowerInfo(logicID)
+}
+
+// GetDeviceUtilizationRate query the utilization with the driver latency
+func (d *latencyDeviceManager) GetDeviceUtilizationRate(logicID int32, deviceType common.DeviceType) (uint32,
+	error) {
+	time.Sleep(driverLatency)
+	return d.DeviceManagerMock.GetDeviceUtilizationRate(logicID, deviceType)
+}
+
+// GetDeviceHbmInfo query the hbm info with the driver latency
+func (d *latencyDeviceManager) GetDeviceHbmInfo(logicID int32) (*common.HbmInfo, error) {
+	time.Sleep(driverLatency)
+	return d.DeviceManagerMock.GetDeviceHbmInfo(logicID)
+}
+
+// BenchmarkGetNPUInfo benchmark the chips assembled sequentially and by the worker pool
+func BenchmarkGetNPUInfo(b *testing.B) {
+	dmgr := &latencyDeviceManager{}
+	inventory := NewDeviceInventory()
+	if err := inventory.Refresh(dmgr); err != nil {
+		b.Fatalf("refresh inventory failed: %v", err)
+	}
+	// the network info is queried by hccn_tool instead of the driver
+	groups, err := ParseMetricGroups("base,memory,process")
+	if err != nil {
+		b.Fatalf("parse metric groups failed: %v", err)
+	}
+	for _, workers := range []int{1, 4, DefaultCollectWorkers, benchChipNum} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				npuList := getNPUInfo(dmgr, inventory, groups, workers)
+				if len(npuList) != 1 || len(npuList[0].DeviceList) != benchChipNum {
+					b.Fatalf("unexpected npu list: %v", npuList)
+				}
+			}
+		})
+	}
+}
+
+// TestGetNPUInfoWithWorkers test the chips are merged in the inventory order whatever the workers are
+func TestGetNPUInfoWithWorkers(t *testing.T) {
+	dmgr := &latencyDeviceManager{}
+	inventory := NewDeviceInventory()
+	assert.Nil(t, inventory.Refresh(dmgr))
+	for _, workers := range []int{0, 1, 3, benchChipNum * 2} {
+		npuList := getNPUInfo(dmgr, inventory, nil, workers)
+		if !assert.Equal(t, 1, len(npuList)) || !assert.Equal(t, benchChipNum, len(npuList[0].DeviceList)) {
+			continue
+		}
+		for i, chip := range npuList[0].DeviceList {
+			assert.Equal(t, int32(i), chip.LogicID)
+		}
+	}
+}
+
 func init() {
 	config := hwlog.LogConfig{
 		OnlyToStdout: true,
@@ -0,0 +1,167 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package otlp exports the npu metrics to an OpenTelemetry collector by OTLP over gRPC or HTTP
+package otlp
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	conventions "go.opentelemetry.io/collector/semconv/v1.18.0"
+
+	"huawei.com/npu-exporter/v5/collector"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+	"huawei.com/npu-exporter/v5/versions"
+)
+
+// the resource attributes which identify the chip
+const (
+	AttributeChipID        = "npu.chip.id"
+	AttributeChipModelName = "npu.chip.model_name"
+	AttributeChipVDieID    = "npu.chip.vdie_id"
+	AttributeChipPCIeBus   = "npu.chip.pcie_bus_info"
+	AttributeCardID        = "npu.card.id"
+
+	scopeName = "huawei.com/npu-exporter"
+)
+
+// chipLabels the labels which identify the chip, they are the resource attributes rather than the point attributes
+var chipLabels = map[string]bool{"id": true, "model_name": true, "vdie_id": true, "pcie_bus_info": true,
+	"npuID": true}
+
+// semanticLabels the labels of the pods and containers, which are renamed by the semantic conventions
+var semanticLabels = map[string]string{
+	"namespace":      conventions.AttributeK8SNamespaceName,
+	"pod_name":       conventions.AttributeK8SPodName,
+	"container_name": conventions.AttributeK8SContainerName,
+	"containerID":    conventions.AttributeContainerID,
+	"container_id":   conventions.AttributeContainerID,
+	"containerName":  conventions.AttributeContainerName,
+}
+
+// unitSuffixes the units of the v2 metric names, the longer suffix comes first
+var unitSuffixes = []struct {
+	suffix string
+	unit   string
+}{
+	{suffix: "_bytes_per_second", unit: "By/s"},
+	{suffix: "_bits_per_second", unit: "bit/s"},
+	{suffix: "_per_second", unit: "1/s"},
+	{suffix: "_bytes", unit: "By"},
+	{suffix: "_celsius", unit: "Cel"},
+	{suffix: "_watts", unit: "W"},
+	{suffix: "_volts", unit: "V"},
+	{suffix: "_hertz", unit: "Hz"},
+	{suffix: "_ratio", unit: "1"},
+}
+
+// toMetrics convert the chips to the OTLP metrics, each chip is a resource whose samples are the data points
+func toMetrics(chips []collector.ChipSamples, opts Options, startTime, now time.Time) pmetric.Metrics {
+	metrics := pmetric.NewMetrics()
+	for _, chip := range chips {
+		if chip.Chip == nil || len(chip.Samples) == 0 {
+			continue
+		}
+		resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+		setResource(resourceMetrics.Resource(), chip, opts)
+		scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+		scopeMetrics.Scope().SetName(scopeName)
+		scopeMetrics.Scope().SetVersion(versions.BuildVersion)
+		byName := make(map[string]pmetric.Metric, len(chip.Samples))
+		for _, sample := range chip.Samples {
+			metric, ok := byName[sample.Name]
+			if !ok {
+				metric = newMetric(scopeMetrics.Metrics(), sample)
+				byName[sample.Name] = metric
+			}
+			var point pmetric.NumberDataPoint
+			if sample.Counter {
+				point = metric.Sum().DataPoints().AppendEmpty()
+				point.SetStartTimestamp(pcommon.NewTimestampFromTime(startTime))
+			} else {
+				point = metric.Gauge().DataPoints().AppendEmpty()
+			}
+			setPoint(point, sample, now)
+		}
+	}
+	return metrics
+}
+
+func setResource(resource pcommon.Resource, chip collector.ChipSamples, opts Options) {
+	attrs := resource.Attributes()
+	for name, value := range opts.Attributes {
+		attrs.PutStr(name, value)
+	}
+	if opts.HostName != "" {
+		attrs.PutStr(conventions.AttributeHostName, opts.HostName)
+	}
+	if opts.NodeName != "" {
+		attrs.PutStr(conventions.AttributeK8SNodeName, opts.NodeName)
+	}
+	attrs.PutInt(AttributeCardID, int64(chip.CardID))
+	attrs.PutInt(AttributeChipID, int64(chip.Chip.DeviceID))
+	if chip.Chip.ChipIfo != nil {
+		attrs.PutStr(AttributeChipModelName, common.GetNpuName(*chip.Chip.ChipIfo))
+	}
+	attrs.PutStr(AttributeChipVDieID, chip.Chip.VDieID)
+	attrs.PutStr(AttributeChipPCIeBus, chip.Chip.PCIeBusInfo)
+}
+
+// newMetric create the gauge, or the monotonic cumulative sum of the counter
+func newMetric(metrics pmetric.MetricSlice, sample collector.Sample) pmetric.Metric {
+	metric := metrics.AppendEmpty()
+	metric.SetName(sample.Name)
+	metric.SetDescription(sample.Help)
+	for _, unit := range unitSuffixes {
+		if strings.HasSuffix(sample.Name, unit.suffix) {
+			metric.SetUnit(unit.unit)
+			break
+		}
+	}
+	if !sample.Counter {
+		metric.SetEmptyGauge()
+		return metric
+	}
+	sum := metric.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	return metric
+}
+
+// setPoint set the value and attributes of the point, the failed value is flagged as no recorded value
+func setPoint(point pmetric.NumberDataPoint, sample collector.Sample, now time.Time) {
+	timestamp := sample.Timestamp
+	if timestamp.IsZero() {
+		timestamp = now
+	}
+	point.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	point.SetDoubleValue(sample.Value)
+	if math.IsNaN(sample.Value) {
+		point.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+	}
+	attrs := point.Attributes()
+	for name, value := range sample.Labels {
+		if chipLabels[name] {
+			continue
+		}
+		if renamed, ok := semanticLabels[name]; ok {
+			name = renamed
+		}
+		attrs.PutStr(name, value)
+	}
+}
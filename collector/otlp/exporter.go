@@ -0,0 +1,244 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package otlp exports the npu metrics to an OpenTelemetry collector by OTLP over gRPC or HTTP
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"huawei.com/npu-exporter/v5/collector"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+const (
+	// ProtocolGRPC OTLP over gRPC, the endpoint is the host:port of the receiver
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP OTLP over HTTP with the protobuf payload, the endpoint is the url of the receiver
+	ProtocolHTTP = "http/protobuf"
+	// DefaultTimeout the default deadline of each export
+	DefaultTimeout = 10 * time.Second
+
+	defaultHTTPPath = "/v1/metrics"
+	contentType     = "application/x-protobuf"
+	// maxRespBodyLen the max length of the response body which is read, eg: the reason of the rejection
+	maxRespBodyLen = 256
+)
+
+// Source the collector whose chips are exported
+type Source interface {
+	// UpdateInterval how often the chips are updated, which is the cadence of the export
+	UpdateInterval() time.Duration
+	// CollectChips collect the samples of the chips
+	CollectChips() []collector.ChipSamples
+}
+
+// Options the options of the OTLP exporter
+type Options struct {
+	Protocol string
+	// Endpoint the host:port of the gRPC receiver, or the http or https url of the HTTP receiver
+	Endpoint string
+	// Insecure export without TLS, only for the gRPC receiver, the HTTP receiver follows the url scheme
+	Insecure bool
+	// Timeout the deadline of each export, 0 means the DefaultTimeout
+	Timeout time.Duration
+	// HostName the host.name resource attribute
+	HostName string
+	// NodeName the k8s.node.name resource attribute, empty means it is not running in kubernetes
+	NodeName string
+	// Attributes the extra resource attributes of each chip, eg: the cluster name
+	Attributes map[string]string
+}
+
+// Exporter pushes the samples of the chips to the OTLP receiver, each chip is a resource
+type Exporter struct {
+	source    Source
+	opts      Options
+	startTime time.Time
+	export    func(ctx context.Context, req pmetricotlp.ExportRequest) error
+	close     func() error
+}
+
+// CheckEndpoint check the endpoint of the protocol
+func CheckEndpoint(protocol, endpoint string) error {
+	switch protocol {
+	case ProtocolGRPC:
+		if endpoint == "" {
+			return errors.New("the endpoint is empty")
+		}
+		return nil
+	case ProtocolHTTP:
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return fmt.Errorf("parse the url failed: %v", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.New("only support the http and https url")
+		}
+		if u.Host == "" {
+			return errors.New("the host of the url is empty")
+		}
+		return nil
+	default:
+		return fmt.Errorf("only support %s and %s", ProtocolGRPC, ProtocolHTTP)
+	}
+}
+
+// NewExporter create the exporter of the source, the connection of gRPC is established lazily
+func NewExporter(source Source, opts Options) (*Exporter, error) {
+	if source == nil {
+		return nil, errors.New("the source is nil")
+	}
+	if err := CheckEndpoint(opts.Protocol, opts.Endpoint); err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	e := &Exporter{source: source, opts: opts, startTime: time.Now()}
+	if opts.Protocol == ProtocolHTTP {
+		e.newHTTPExport()
+		return e, nil
+	}
+	if err := e.newGRPCExport(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Exporter) newGRPCExport() error {
+	creds := insecure.NewCredentials()
+	if !e.opts.Insecure {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.Dial(e.opts.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("dial the grpc endpoint failed: %v", err)
+	}
+	client := pmetricotlp.NewGRPCClient(conn)
+	e.export = func(ctx context.Context, req pmetricotlp.ExportRequest) error {
+		resp, err := client.Export(ctx, req)
+		if err != nil {
+			return err
+		}
+		logPartialSuccess(resp)
+		return nil
+	}
+	e.close = conn.Close
+	return nil
+}
+
+func (e *Exporter) newHTTPExport() {
+	endpoint := e.opts.Endpoint
+	if u, err := url.Parse(endpoint); err == nil && (u.Path == "" || u.Path == "/") {
+		u.Path = defaultHTTPPath
+		endpoint = u.String()
+	}
+	client := &http.Client{Timeout: e.opts.Timeout}
+	e.export = func(ctx context.Context, req pmetricotlp.ExportRequest) error {
+		body, err := req.MarshalProto()
+		if err != nil {
+			return fmt.Errorf("marshal the export request failed: %v", err)
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				hwlog.RunLog.Warnf("close the response body failed: %v", err)
+			}
+		}()
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxRespBodyLen))
+		if err != nil {
+			hwlog.RunLog.Warnf("read the response body failed: %v", err)
+		}
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("the endpoint responded %s: %s", resp.Status, bytes.TrimSpace(respBody))
+		}
+		exportResp := pmetricotlp.NewExportResponse()
+		if err = exportResp.UnmarshalProto(respBody); err == nil {
+			logPartialSuccess(exportResp)
+		}
+		return nil
+	}
+	e.close = func() error {
+		client.CloseIdleConnections()
+		return nil
+	}
+}
+
+func logPartialSuccess(resp pmetricotlp.ExportResponse) {
+	if rejected := resp.PartialSuccess().RejectedDataPoints(); rejected > 0 {
+		hwlog.RunLog.Warnf("the endpoint rejected %d data points: %s", rejected,
+			resp.PartialSuccess().ErrorMessage())
+	}
+}
+
+// Run export the chips on the update cadence of the source until the ctx is done, the connection is closed then.
+// The changed cadence takes effect from the next export
+func (e *Exporter) Run(ctx context.Context) {
+	defer func() {
+		if err := e.close(); err != nil {
+			hwlog.RunLog.Warnf("close the otlp exporter failed: %v", err)
+		}
+	}()
+	current := e.source.UpdateInterval()
+	ticker := time.NewTicker(current)
+	defer ticker.Stop()
+	for {
+		if err := e.Export(ctx); err != nil {
+			hwlog.RunLog.Warnf("export the metrics to the otlp endpoint failed: %v", err)
+		}
+		if next := e.source.UpdateInterval(); next != current && next > 0 {
+			hwlog.RunLog.Infof("the interval of the otlp export is changed from %v to %v", current, next)
+			current = next
+			ticker.Reset(current)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export collect the chips of the source and export them once
+func (e *Exporter) Export(ctx context.Context) error {
+	metrics := toMetrics(e.source.CollectChips(), e.opts, e.startTime, time.Now())
+	if metrics.DataPointCount() == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+	return e.export(ctx, pmetricotlp.NewExportRequestFromMetrics(metrics))
+}
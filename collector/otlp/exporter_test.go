@@ -0,0 +1,191 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package otlp exports the npu metrics to an OpenTelemetry collector by OTLP over gRPC or HTTP
+package otlp
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/grpc"
+
+	"huawei.com/npu-exporter/v5/collector"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+func init() {
+	config := hwlog.LogConfig{
+		OnlyToStdout: true,
+	}
+	hwlog.InitRunLogger(&config, context.TODO())
+}
+
+type fakeSource struct {
+	chips []collector.ChipSamples
+}
+
+func (s *fakeSource) UpdateInterval() time.Duration {
+	return time.Second
+}
+
+func (s *fakeSource) CollectChips() []collector.ChipSamples {
+	return s.chips
+}
+
+func newFakeSource() *fakeSource {
+	chip := &collector.HuaWeiAIChip{DeviceID: 1, VDieID: "vdie", PCIeBusInfo: "0000:c1:00.0",
+		ChipIfo: &common.ChipInfo{Name: "910"}}
+	timestamp := time.UnixMilli(1000)
+	return &fakeSource{chips: []collector.ChipSamples{{CardID: 3, Chip: chip, Samples: []collector.Sample{
+		{Name: "npu_chip_hbm_total_bytes", Help: "the npu hbm total memory", Value: 2, Timestamp: timestamp,
+			Labels: map[string]string{"id": "1", "model_name": "910--", "namespace": "default",
+				"pod_name": "pod", "container_name": "container"}},
+		{Name: "npu_chip_roce_rx_errors_total", Help: "the rx errors", Counter: true, Value: 5,
+			Timestamp: timestamp, Labels: map[string]string{"id": "1"}},
+		{Name: "npu_chip_memory_used_bytes", Help: "the npu used memory", Value: math.NaN(),
+			Timestamp: timestamp, Labels: map[string]string{"id": "1"}},
+	}}, {CardID: 4}}}
+}
+
+func metricsByName(metrics pmetric.Metrics) map[string]pmetric.Metric {
+	byName := make(map[string]pmetric.Metric)
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				byName[metric.Name()] = metric
+			}
+		}
+	}
+	return byName
+}
+
+// TestToMetrics test the chips are converted to the resources and the samples to the data points
+func TestToMetrics(t *testing.T) {
+	startTime := time.UnixMilli(500)
+	opts := Options{HostName: "host", NodeName: "node", Attributes: map[string]string{"cluster": "a"}}
+	metrics := toMetrics(newFakeSource().chips, opts, startTime, time.Now())
+	// the chip without the samples is skipped
+	assert.Equal(t, 1, metrics.ResourceMetrics().Len())
+	attrs := metrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw()
+	assert.Equal(t, map[string]interface{}{"host.name": "host", "k8s.node.name": "node", "cluster": "a",
+		AttributeCardID: int64(3), AttributeChipID: int64(1), AttributeChipModelName: "910--",
+		AttributeChipVDieID: "vdie", AttributeChipPCIeBus: "0000:c1:00.0"}, attrs)
+
+	byName := metricsByName(metrics)
+	hbm := byName["npu_chip_hbm_total_bytes"]
+	assert.Equal(t, pmetric.MetricTypeGauge, hbm.Type())
+	assert.Equal(t, "By", hbm.Unit())
+	point := hbm.Gauge().DataPoints().At(0)
+	assert.Equal(t, float64(2), point.DoubleValue())
+	assert.Equal(t, pcommon.NewTimestampFromTime(time.UnixMilli(1000)), point.Timestamp())
+	assert.Equal(t, map[string]interface{}{"k8s.namespace.name": "default", "k8s.pod.name": "pod",
+		"k8s.container.name": "container"}, point.Attributes().AsRaw())
+
+	rxErrors := byName["npu_chip_roce_rx_errors_total"]
+	assert.Equal(t, pmetric.MetricTypeSum, rxErrors.Type())
+	assert.True(t, rxErrors.Sum().IsMonotonic())
+	assert.Equal(t, pmetric.AggregationTemporalityCumulative, rxErrors.Sum().AggregationTemporality())
+	assert.Equal(t, pcommon.NewTimestampFromTime(startTime), rxErrors.Sum().DataPoints().At(0).StartTimestamp())
+
+	used := byName["npu_chip_memory_used_bytes"].Gauge().DataPoints().At(0)
+	assert.True(t, used.Flags().NoRecordedValue())
+}
+
+// TestCheckEndpoint test the endpoint is checked by the protocol
+func TestCheckEndpoint(t *testing.T) {
+	assert.Nil(t, CheckEndpoint(ProtocolGRPC, "localhost:4317"))
+	assert.NotNil(t, CheckEndpoint(ProtocolGRPC, ""))
+	assert.Nil(t, CheckEndpoint(ProtocolHTTP, "https://localhost:4318"))
+	assert.NotNil(t, CheckEndpoint(ProtocolHTTP, "localhost:4318"))
+	assert.NotNil(t, CheckEndpoint("http/json", "http://localhost:4318"))
+}
+
+// TestExportHTTP test the metrics are exported to the HTTP receiver on the default path
+func TestExportHTTP(t *testing.T) {
+	var lock sync.Mutex
+	var received []pmetricotlp.ExportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		req := pmetricotlp.NewExportRequest()
+		if err != nil || r.URL.Path != defaultHTTPPath || r.Header.Get("Content-Type") != contentType ||
+			req.UnmarshalProto(body) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		received = append(received, req)
+		lock.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	e, err := NewExporter(newFakeSource(), Options{Protocol: ProtocolHTTP, Endpoint: server.URL})
+	assert.Nil(t, err)
+	assert.Nil(t, e.Export(context.Background()))
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Len(t, received, 1)
+	assert.Equal(t, 3, received[0].Metrics().DataPointCount())
+}
+
+type grpcReceiver struct {
+	pmetricotlp.UnimplementedGRPCServer
+	received chan pmetricotlp.ExportRequest
+}
+
+func (r *grpcReceiver) Export(_ context.Context, req pmetricotlp.ExportRequest) (pmetricotlp.ExportResponse,
+	error) {
+	r.received <- req
+	return pmetricotlp.NewExportResponse(), nil
+}
+
+// TestExportGRPC test the metrics are exported to the gRPC receiver
+func TestExportGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer()
+	recv := &grpcReceiver{received: make(chan pmetricotlp.ExportRequest, 1)}
+	pmetricotlp.RegisterGRPCServer(server, recv)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			t.Logf("serve the grpc receiver failed: %v", err)
+		}
+	}()
+	defer server.Stop()
+
+	e, err := NewExporter(newFakeSource(), Options{Protocol: ProtocolGRPC, Endpoint: listener.Addr().String(),
+		Insecure: true})
+	assert.Nil(t, err)
+	defer func() {
+		assert.Nil(t, e.close())
+	}()
+	assert.Nil(t, e.Export(context.Background()))
+	req := <-recv.received
+	assert.Equal(t, 3, req.Metrics().DataPointCount())
+}
@@ -0,0 +1,155 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+// the chip fields whose value is queried from the driver and may be invalid
+const (
+	fieldAICoreFreq     = "aicore_current_freq"
+	fieldPower          = "power"
+	fieldTemperature    = "temperature"
+	fieldVoltage        = "voltage"
+	fieldHealthStatus   = "health_status"
+	fieldUtilization    = "utilization"
+	fieldHbmUtilization = "hbm_utilization"
+	fieldMemory         = "memory"
+	fieldHbm            = "hbm"
+	fieldErrorCode      = "error_code"
+	fieldNetworkStatus  = "network_status"
+	fieldProcess        = "process"
+)
+
+// the DeviceInterface methods which are recorded when they fail
+const (
+	apiGetDeviceFrequency       = "GetDeviceFrequency"
+	apiGetDevicePowerInfo       = "GetDevicePowerInfo"
+	apiGetMcuPowerInfo          = "GetMcuPowerInfo"
+	apiGetDeviceTemperature     = "GetDeviceTemperature"
+	apiGetDeviceVoltage         = "GetDeviceVoltage"
+	apiGetDeviceHealth          = "GetDeviceHealth"
+	apiGetDeviceUtilizationRate = "GetDeviceUtilizationRate"
+	apiGetDeviceMemoryInfo      = "GetDeviceMemoryInfo"
+	apiGetDeviceHbmInfo         = "GetDeviceHbmInfo"
+	apiGetDeviceAllErrorCode    = "GetDeviceAllErrorCode"
+	apiGetDeviceNetWorkHealth   = "GetDeviceNetWorkHealth"
+	apiGetDevProcessInfo        = "GetDevProcessInfo"
+)
+
+// setQueryError mark the value of the field as invalid because the api failed
+func (c *HuaWeiAIChip) setQueryError(field, api string, err error) {
+	hwlog.RunLog.Debugf("chip %d query %s by %s failed: %v", c.LogicID, field, api, err)
+	if c.QueryErrors == nil {
+		c.QueryErrors = make(map[string]string, initSize)
+	}
+	c.QueryErrors[field] = api
+}
+
+func (c *HuaWeiAIChip) queryFailed(field string) bool {
+	_, ok := c.QueryErrors[field]
+	return ok
+}
+
+// valueOf get the value of the field, NaN is returned when the query of the field failed
+func (c *HuaWeiAIChip) valueOf(field string, value float64) float64 {
+	if c.queryFailed(field) {
+		return math.NaN()
+	}
+	return value
+}
+
+// anyChipReached check at least one chip is reached by the driver, the chip whose health query failed is not
+// reached, so the chip info is not regarded as updated when the list is empty or all the chips are unreachable
+func anyChipReached(npuList []HuaWeiNPUCard) bool {
+	for _, card := range npuList {
+		for _, chip := range card.DeviceList {
+			if !chip.queryFailed(fieldHealthStatus) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setField set the telegraf field unless the query of the field failed
+func (c *HuaWeiAIChip) setField(fields map[string]interface{}, field, name string, value interface{}) {
+	if !c.queryFailed(field) {
+		fields[name] = value
+	}
+}
+
+// queryErrorCounter counts the failed driver queries
+type queryErrorCounter struct {
+	counter *prometheus.CounterVec
+}
+
+func newQueryErrorCounter() *queryErrorCounter {
+	return &queryErrorCounter{counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "npu_exporter_device_query_errors_total",
+		Help: "the number of the failed device queries, the api is the failed method of the device manager",
+	}, []string{"api", npuID})}
+}
+
+// record count the failed queries of the chips, the vnpus of the same chip are counted once
+func (q *queryErrorCounter) record(npuList []HuaWeiNPUCard) {
+	for _, card := range npuList {
+		counted := make(map[int32]struct{}, len(card.DeviceList))
+		for _, chip := range card.DeviceList {
+			if _, ok := counted[chip.LogicID]; ok {
+				continue
+			}
+			counted[chip.LogicID] = struct{}{}
+			for _, api := range chip.QueryErrors {
+				q.counter.WithLabelValues(api, strconv.Itoa(chip.DeviceID)).Inc()
+			}
+		}
+	}
+}
+
+// omitNaNMetrics forward the metrics to ch except the ones whose value is NaN, the returned func must be called
+// after all the metrics are sent
+func omitNaNMetrics(ch chan<- prometheus.Metric) (chan<- prometheus.Metric, func()) {
+	filtered := make(chan prometheus.Metric, cacheSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for metric := range filtered {
+			if !isNaNMetric(metric) {
+				ch <- metric
+			}
+		}
+	}()
+	return filtered, func() {
+		close(filtered)
+		<-done
+	}
+}
+
+func isNaNMetric(metric prometheus.Metric) bool {
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		return false
+	}
+	return m.Gauge != nil && math.IsNaN(m.Gauge.GetValue())
+}
@@ -0,0 +1,102 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+func packFailedChip(t *testing.T) *HuaWeiAIChip {
+	inv := chipInventory{chipInfo: &common.ChipInfo{Name: common.Chip910}}
+	chip := packChipInfo(inv, &devmanager.DeviceManagerMockErr{}, nil)
+	assert.Equal(t, apiGetDeviceTemperature, chip.QueryErrors[fieldTemperature])
+	assert.Equal(t, apiGetDevicePowerInfo, chip.QueryErrors[fieldPower])
+	return chip
+}
+
+// TestQueryErrorValue test the failed value is NaN in prometheus and omitted in telegraf
+func TestQueryErrorValue(t *testing.T) {
+	chip := packFailedChip(t)
+	assert.True(t, math.IsNaN(chip.valueOf(fieldTemperature, float64(chip.Temperature))))
+	fields := make(map[string]interface{})
+	newBaseGroup().Fields(chip, fields)
+	_, ok := fields["npu_chip_info_temperature"]
+	assert.False(t, ok)
+	assert.Equal(t, float64(1), (&HuaWeiAIChip{}).valueOf(fieldTemperature, 1))
+}
+
+// TestQueryErrorCounter test the failed queries are counted by api and chip
+func TestQueryErrorCounter(t *testing.T) {
+	chip := packFailedChip(t)
+	counter := newQueryErrorCounter()
+	counter.record([]HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip, chip}}})
+	assert.Equal(t, float64(1), testutil.ToFloat64(counter.counter.WithLabelValues(apiGetDeviceTemperature, "0")))
+}
+
+// TestAnyChipReached test the chip info is not regarded as updated when no chip is reached
+func TestAnyChipReached(t *testing.T) {
+	assert.False(t, anyChipReached(nil))
+	failed := packFailedChip(t)
+	assert.False(t, anyChipReached([]HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{failed}}}))
+	assert.True(t, anyChipReached([]HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{failed, {}}}}))
+}
+
+// TestOmitFailedValues test the failed values are omitted or exported as NaN
+func TestOmitFailedValues(t *testing.T) {
+	groups, err := ParseMetricGroups(BaseGroup)
+	assert.Nil(t, err)
+	for _, omit := range []bool{false, true} {
+		n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups, OmitFailedValues: omit})
+		chip := packFailedChip(t)
+		assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip},
+			Timestamp: time.Now()}}, time.Minute))
+		families, err := newTestRegistry(t, n).Gather()
+		assert.Nil(t, err)
+		var values []float64
+		for _, family := range families {
+			if family.GetName() != "npu_chip_info_temperature" {
+				continue
+			}
+			for _, metric := range family.Metric {
+				values = append(values, metric.GetGauge().GetValue())
+			}
+		}
+		if omit {
+			assert.Equal(t, 0, len(values))
+			continue
+		}
+		if assert.Equal(t, 1, len(values)) {
+			assert.True(t, math.IsNaN(values[0]))
+		}
+	}
+}
+
+func newTestRegistry(t *testing.T, c prometheus.Collector) *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	if err := r.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
@@ -0,0 +1,190 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/hccn"
+)
+
+const (
+	// ReadyzPath the url path of the readiness probe
+	ReadyzPath = "/readyz"
+
+	checkDCMI             = "dcmi"
+	checkContainerRuntime = "container_runtime"
+	checkHccnTool         = "hccn_tool"
+)
+
+// ReadinessCheck the result of a check, the message is the reason why it is not ready
+type ReadinessCheck struct {
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+}
+
+// Readiness the collector is ready when all the checks are ready
+type Readiness struct {
+	Ready  bool             `json:"ready"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+func (r *Readiness) add(name string, err error) {
+	check := ReadinessCheck{Name: name, Ready: err == nil}
+	if err != nil {
+		check.Message = err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+	r.Ready = r.Ready && check.Ready
+}
+
+// readinessTracker records the cache keys which are populated at least once, the latest query result of the driver
+// and the latest connection result of the container runtime
+type readinessTracker struct {
+	lock      sync.RWMutex
+	populated map[string]bool
+	// driverErr the error of the latest chip query, nil means at least one chip is reached by the driver
+	driverErr error
+	// runtimeErr the error of the latest connection, nil means it is connected
+	runtimeErr error
+}
+
+func newReadinessTracker() *readinessTracker {
+	return &readinessTracker{populated: make(map[string]bool, initSize),
+		driverErr:  errors.New("the chips are not queried by the driver yet"),
+		runtimeErr: errors.New("the container runtime is not connected yet")}
+}
+
+func (r *readinessTracker) populate(key string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.populated[key] = true
+}
+
+// queried record the result of the chip query, the driver is not ready when none of the chips is reached
+func (r *readinessTracker) queried(npuList []HuaWeiNPUCard) {
+	var err error
+	if !anyChipReached(npuList) {
+		err = errors.New("none of the chips is reached by the driver in the latest query")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.driverErr = err
+}
+
+func (r *readinessTracker) setRuntimeErr(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.runtimeErr = err
+}
+
+func (r *readinessTracker) cacheErr(key string) error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if !r.populated[key] {
+		return errors.New("the cache is not populated yet")
+	}
+	return nil
+}
+
+func (r *readinessTracker) latestDriverErr() error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.driverErr
+}
+
+func (r *readinessTracker) containerRuntimeErr() error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.runtimeErr
+}
+
+// Readiness implements ManagedCollector, only the caches and tools which are needed by the enabled groups are
+// checked
+func (n *npuCollector) Readiness() Readiness {
+	readiness := Readiness{Ready: true}
+	readiness.add(checkDCMI, n.driverErr())
+	readiness.add(npuListCacheKey, n.readiness.cacheErr(npuListCacheKey))
+	if n.metricGroups.needNetInfo() {
+		readiness.add(npuNetworkCacheKey, n.readiness.cacheErr(npuNetworkCacheKey))
+		// the network info is queried by hccn_tool on the training cards only
+		if n.driver != nil && n.driver.IsTrainingCard() {
+			readiness.add(checkHccnTool, hccn.CheckTool())
+		}
+	}
+	if n.metricGroups.needContainerInfo() {
+		readiness.add(containersDevicesCacheKey, n.readiness.cacheErr(containersDevicesCacheKey))
+		readiness.add(checkContainerRuntime, n.readiness.containerRuntimeErr())
+	}
+	return readiness
+}
+
+// driverErr the driver is not ready when it is not initialized, none of the chips is reached in the latest query or
+// the circuit breakers of all the queried chips are open
+func (n *npuCollector) driverErr() error {
+	if n.driver == nil {
+		return errors.New("the device manager is not initialized")
+	}
+	if err := n.readiness.latestDriverErr(); err != nil {
+		return err
+	}
+	states := n.driver.BreakerStates()
+	for _, state := range states {
+		if state != devmanager.BreakerOpen {
+			return nil
+		}
+	}
+	if len(states) == 0 {
+		return nil
+	}
+	return errors.New("the circuit breakers of all the chips are open")
+}
+
+// ReadinessHandler serves the readiness of the collector, the status is 503 when it is not ready
+type ReadinessHandler struct {
+	collector ManagedCollector
+}
+
+// NewReadinessHandler create the readiness handler of the collector
+func NewReadinessHandler(c ManagedCollector) *ReadinessHandler {
+	return &ReadinessHandler{collector: c}
+}
+
+// ServeHTTP implements http.Handler
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	readiness := h.collector.Readiness()
+	body, err := json.Marshal(readiness)
+	if err != nil {
+		hwlog.RunLog.Errorf("marshal the readiness failed: %v", err)
+		http.Error(w, "marshal the readiness failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if readiness.Ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if _, err = w.Write(body); err != nil {
+		hwlog.RunLog.Errorf("write readiness response failed: %v", err)
+	}
+}
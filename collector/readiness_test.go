@@ -0,0 +1,134 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager"
+	"huawei.com/npu-exporter/v5/devmanager/hccn"
+)
+
+func serveReadiness(t *testing.T, n *npuCollector) (int, Readiness) {
+	recorder := httptest.NewRecorder()
+	NewReadinessHandler(n).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ReadyzPath, nil))
+	var readiness Readiness
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &readiness))
+	return recorder.Code, readiness
+}
+
+func notReadyChecks(readiness Readiness) []string {
+	var names []string
+	for _, check := range readiness.Checks {
+		if !check.Ready {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
+
+// TestReadiness test the collector is ready after the driver is initialized, the caches are populated and the
+// container runtime and hccn_tool are available
+func TestReadiness(t *testing.T) {
+	toolErr := errors.New("hccn_tool is not found")
+	patch := gomonkey.ApplyFunc(hccn.CheckTool, func() error {
+		return toolErr
+	})
+	defer patch.Reset()
+	groups, err := ParseMetricGroups(strings.Join([]string{BaseGroup, NetworkGroup, ContainerGroup}, ","))
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups})
+	status, readiness := serveReadiness(t, n)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, []string{checkDCMI, npuListCacheKey, npuNetworkCacheKey, containersDevicesCacheKey,
+		checkContainerRuntime}, notReadyChecks(readiness))
+
+	n.driver = devmanager.NewGuardedDeviceManager(&devmanager.DeviceManagerMock{}, devmanager.GuardOpts{})
+	for _, key := range []string{npuListCacheKey, npuNetworkCacheKey, containersDevicesCacheKey} {
+		n.readiness.populate(key)
+	}
+	n.readiness.setRuntimeErr(nil)
+	n.readiness.queried([]HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{{}}}})
+	status, readiness = serveReadiness(t, n)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, []string{checkHccnTool}, notReadyChecks(readiness))
+	assert.Contains(t, readiness.Checks, ReadinessCheck{Name: checkHccnTool, Message: toolErr.Error()})
+
+	toolErr = nil
+	status, readiness = serveReadiness(t, n)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, readiness.Ready)
+	assert.Len(t, readiness.Checks, 6)
+
+	// the container runtime is disconnected after it is ready
+	n.readiness.setRuntimeErr(errors.New("connection refused"))
+	status, readiness = serveReadiness(t, n)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, []string{checkContainerRuntime}, notReadyChecks(readiness))
+}
+
+// hangingDeviceManager the health query of the chips hangs until the call deadline
+type hangingDeviceManager struct {
+	devmanager.DeviceManagerMock
+}
+
+// GetDeviceHealth hang longer than the call deadline of the test
+func (d *hangingDeviceManager) GetDeviceHealth(int32) (uint32, error) {
+	const hang = 50 * time.Millisecond
+	time.Sleep(hang)
+	return 0, nil
+}
+
+// TestReadinessOfDriver test the dcmi check fails when no chip is reached or all the breakers are open
+func TestReadinessOfDriver(t *testing.T) {
+	groups, err := ParseMetricGroups(BaseGroup)
+	assert.Nil(t, err)
+	n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups})
+	n.readiness.populate(npuListCacheKey)
+	n.driver = devmanager.NewGuardedDeviceManager(&hangingDeviceManager{},
+		devmanager.GuardOpts{CallTimeout: time.Millisecond, FailureThreshold: 1, OpenTimeout: time.Minute})
+	_, readiness := serveReadiness(t, n)
+	assert.Equal(t, []string{checkDCMI}, notReadyChecks(readiness))
+
+	failed := &HuaWeiAIChip{}
+	failed.setQueryError(fieldHealthStatus, apiGetDeviceHealth, errors.New("failed"))
+	n.readiness.queried([]HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{failed}}})
+	_, readiness = serveReadiness(t, n)
+	assert.Contains(t, readiness.Checks, ReadinessCheck{Name: checkDCMI,
+		Message: "none of the chips is reached by the driver in the latest query"})
+
+	n.readiness.queried([]HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{failed, {}}}})
+	status, readiness := serveReadiness(t, n)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, readiness.Ready)
+
+	// the breaker of the only chip is opened by the timeout
+	_, err = n.driver.GetDeviceHealth(0)
+	assert.NotNil(t, err)
+	_, readiness = serveReadiness(t, n)
+	assert.Contains(t, readiness.Checks, ReadinessCheck{Name: checkDCMI,
+		Message: "the circuit breakers of all the chips are open"})
+}
@@ -0,0 +1,129 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+const defaultStaticInterval = 5 * time.Minute
+
+// DefaultCollectWorkers the default number of the workers which assemble the chips concurrently
+const DefaultCollectWorkers = 8
+
+// CollectIntervals the independent intervals of the data sources, the zero interval falls back to the update time
+type CollectIntervals struct {
+	// Static how often the chips are re-discovered, the static info of the known chips is never re-queried
+	Static time.Duration
+	// Fast how often the fast-telemetry is queried from the driver, eg: utilization, power and temperature
+	Fast time.Duration
+	// Network how often the network and optical info is queried by hccn_tool
+	Network time.Duration
+	// Container how often the devices of the containers are parsed
+	Container time.Duration
+}
+
+func (c CollectIntervals) withDefault(updateTime time.Duration) CollectIntervals {
+	if c.Static <= 0 {
+		c.Static = defaultStaticInterval
+	}
+	for _, interval := range []*time.Duration{&c.Fast, &c.Network, &c.Container} {
+		if *interval <= 0 {
+			*interval = updateTime
+		}
+	}
+	return c
+}
+
+// String implements fmt.Stringer
+func (c CollectIntervals) String() string {
+	return fmt.Sprintf("static: %v, fast: %v, network: %v, container: %v", c.Static, c.Fast, c.Network,
+		c.Container)
+}
+
+// collectSchedule the intervals of the data sources, which can be changed while the tasks are running
+type collectSchedule struct {
+	lock      sync.RWMutex
+	intervals CollectIntervals
+}
+
+func newCollectSchedule(intervals CollectIntervals) *collectSchedule {
+	return &collectSchedule{intervals: intervals}
+}
+
+func (s *collectSchedule) get() CollectIntervals {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.intervals
+}
+
+func (s *collectSchedule) set(intervals CollectIntervals) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.intervals = intervals
+}
+
+func (s *collectSchedule) static() time.Duration {
+	return s.get().Static
+}
+
+func (s *collectSchedule) fast() time.Duration {
+	return s.get().Fast
+}
+
+func (s *collectSchedule) network() time.Duration {
+	return s.get().Network
+}
+
+func (s *collectSchedule) container() time.Duration {
+	return s.get().Container
+}
+
+// runPeriodically run the task at once and then every interval in a goroutine, until the ctx is done. The interval
+// is checked after each run, so that the changed interval takes effect from the next run
+func runPeriodically(ctx context.Context, group *sync.WaitGroup, name string, interval func() time.Duration,
+	task func()) {
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		current := interval()
+		ticker := time.NewTicker(current)
+		defer ticker.Stop()
+		for {
+			task()
+			if next := interval(); next != current && next > 0 {
+				hwlog.RunLog.Infof("the interval of %s task is changed from %v to %v", name, current, next)
+				current = next
+				ticker.Reset(current)
+			}
+			select {
+			case <-ctx.Done():
+				hwlog.RunLog.Infof("%s task is stopped", name)
+				return
+			case _, ok := <-ticker.C:
+				if !ok {
+					hwlog.RunLog.Errorf("%s ticker failed, task shutdown", name)
+					return
+				}
+			}
+		}
+	}()
+}
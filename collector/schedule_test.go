@@ -0,0 +1,79 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCollectIntervalsWithDefault test the zero intervals fall back to the update time
+func TestCollectIntervalsWithDefault(t *testing.T) {
+	intervals := CollectIntervals{Fast: time.Second}.withDefault(waitTime)
+	assert.Equal(t, defaultStaticInterval, intervals.Static)
+	assert.Equal(t, time.Second, intervals.Fast)
+	assert.Equal(t, waitTime, intervals.Network)
+	assert.Equal(t, waitTime, intervals.Container)
+}
+
+// TestRunPeriodically test the task runs at once and stops when the ctx is done
+func TestRunPeriodically(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	group := &sync.WaitGroup{}
+	runs := make(chan struct{}, 1)
+	runPeriodically(ctx, group, "test", func() time.Duration {
+		return time.Hour
+	}, func() {
+		runs <- struct{}{}
+	})
+	select {
+	case <-runs:
+	case <-time.After(waitTime):
+		t.Fatal("the task is not run at once")
+	}
+	cancel()
+	group.Wait()
+}
+
+// TestRunPeriodicallyWithChangedInterval test the changed interval takes effect from the next run
+func TestRunPeriodicallyWithChangedInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	group := &sync.WaitGroup{}
+	schedule := newCollectSchedule(CollectIntervals{Fast: time.Hour})
+	const wantRuns = 3
+	runs := make(chan struct{}, wantRuns)
+	runPeriodically(ctx, group, "test", schedule.fast, func() {
+		// the interval is shortened during the first run
+		schedule.set(CollectIntervals{Fast: time.Millisecond})
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
+	})
+	for i := 0; i < wantRuns; i++ {
+		select {
+		case <-runs:
+		case <-time.After(waitTime):
+			t.Fatal("the changed interval does not take effect")
+		}
+	}
+	cancel()
+	group.Wait()
+}
@@ -0,0 +1,97 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"huawei.com/npu-exporter/v5/common-utils/limiter"
+	"huawei.com/npu-exporter/v5/devmanager/hccn"
+)
+
+// the collection stages whose duration is observed
+const (
+	stageNPUInfo        = "npu_info"
+	stageNetworkInfo    = "network_info"
+	stageContainerParse = "container_parse"
+)
+
+// the caches whose misses are counted
+const (
+	cacheNPUList          = "npu_list"
+	cacheNetworkInfo      = "network_info"
+	cacheContainerDevices = "container_devices"
+)
+
+// selfMetrics the metrics of the exporter itself, the stages and caches are shared by all the collectors
+var selfMetrics = newExporterMetrics()
+
+// exporterMetrics describes the health of the exporter, such as the collection latency and the failures
+type exporterMetrics struct {
+	stageDuration     *prometheus.HistogramVec
+	cacheMisses       *prometheus.CounterVec
+	hccnToolFailures  *prometheus.Desc
+	limiterRejections *prometheus.Desc
+}
+
+func newExporterMetrics() *exporterMetrics {
+	return &exporterMetrics{
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "npu_exporter_collect_duration_seconds",
+			Help:    "the duration of the collection stages, in seconds",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"stage"}),
+		cacheMisses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "npu_exporter_cache_misses_total",
+			Help: "the number of the scrapes which do not find the info in the cache",
+		}, []string{"cache"}),
+		hccnToolFailures: prometheus.NewDesc("npu_exporter_hccn_tool_failures_total",
+			"the number of the failed hccn_tool executions", []string{"command"}, nil),
+		limiterRejections: prometheus.NewDesc("npu_exporter_limiter_rejections_total",
+			"the number of the requests and connections which are rejected by the limiter", []string{"reason"}, nil),
+	}
+}
+
+// observeStage observe the duration of the stage which starts at the start time, used with defer
+func (m *exporterMetrics) observeStage(stage string, start time.Time) {
+	m.stageDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+}
+
+func (m *exporterMetrics) cacheMissed(cache string) {
+	m.cacheMisses.WithLabelValues(cache).Inc()
+}
+
+// Describe implements prometheus.Collector
+func (m *exporterMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.stageDuration.Describe(ch)
+	m.cacheMisses.Describe(ch)
+	ch <- m.hccnToolFailures
+	ch <- m.limiterRejections
+}
+
+// Collect implements prometheus.Collector
+func (m *exporterMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.stageDuration.Collect(ch)
+	m.cacheMisses.Collect(ch)
+	for command, count := range hccn.ExecFailures() {
+		ch <- prometheus.MustNewConstMetric(m.hccnToolFailures, prometheus.CounterValue, float64(count), command)
+	}
+	for reason, count := range limiter.Rejections() {
+		ch <- prometheus.MustNewConstMetric(m.limiterRejections, prometheus.CounterValue, float64(count), reason)
+	}
+}
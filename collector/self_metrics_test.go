@@ -0,0 +1,38 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExporterMetrics test the stage durations and cache misses are exported
+func TestExporterMetrics(t *testing.T) {
+	m := newExporterMetrics()
+	m.observeStage(stageNPUInfo, time.Now().Add(-time.Second))
+	m.observeStage(stageNetworkInfo, time.Now())
+	m.cacheMissed(cacheNPUList)
+	m.cacheMissed(cacheNPUList)
+	assert.Equal(t, 2, testutil.CollectAndCount(m, "npu_exporter_collect_duration_seconds"))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.cacheMisses.WithLabelValues(cacheNPUList)))
+	problems, err := testutil.CollectAndLint(m)
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,73 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+type statSample struct {
+	info StatInfo
+	at   time.Time
+}
+
+// StatRateTracker computes the per-second rates of the error statistics of each chip between two queries
+type StatRateTracker struct {
+	lock    sync.Mutex
+	stats   []statField
+	samples map[int32]statSample
+}
+
+// NewStatRateTracker create the tracker of the statistic rates
+func NewStatRateTracker() *StatRateTracker {
+	return &StatRateTracker{stats: statFields, samples: make(map[int32]statSample, initSize)}
+}
+
+// Track set the rates of the queried statistics since the last query of the chip. No rate is set at the first
+// query or when the statistics are not queried, and the decreased statistic is taken as a counter reset
+func (t *StatRateTracker) Track(phyID int32, netInfo *NpuNetInfo, at time.Time) {
+	if t == nil || netInfo == nil || !netInfo.StatQueried {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	last, ok := t.samples[phyID]
+	t.samples[phyID] = statSample{info: netInfo.StatInfo, at: at}
+	seconds := at.Sub(last.at).Seconds()
+	if !ok || seconds <= 0 {
+		return
+	}
+	rates := make(map[string]float64, len(t.stats))
+	for _, stat := range t.stats {
+		if stat.rateName == "" {
+			continue
+		}
+		delta := stat.value(&netInfo.StatInfo) - stat.value(&last.info)
+		if delta < 0 {
+			delta = stat.value(&netInfo.StatInfo)
+		}
+		rates[stat.rateName] = delta / seconds
+	}
+	netInfo.StatRates = rates
+}
+
+// forget drop the last statistics of the removed chip
+func (t *StatRateTracker) forget(phyID int32) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.samples, phyID)
+}
@@ -0,0 +1,93 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package collector for Prometheus
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+const (
+	roceRxErrRate   = "npu_chip_roce_rx_err_packets_per_second"
+	scrapeTimestamp = 1000
+)
+
+// TestStatRateTracker test the rates of the error statistics between two queries
+func TestStatRateTracker(t *testing.T) {
+	tracker := NewStatRateTracker()
+	now := time.Now()
+	first := &NpuNetInfo{StatQueried: true, StatInfo: StatInfo{RoceRxErrPktNum: 10, RoceRxAllPktNum: 100}}
+	tracker.Track(0, first, now)
+	assert.Nil(t, first.StatRates)
+
+	second := &NpuNetInfo{StatQueried: true, StatInfo: StatInfo{RoceRxErrPktNum: 30, RoceRxAllPktNum: 200}}
+	tracker.Track(0, second, now.Add(10*time.Second))
+	assert.Equal(t, 2.0, second.StatRates[roceRxErrRate])
+	_, ok := second.StatRates["npu_chip_roce_rx_packets_per_second"]
+	assert.False(t, ok)
+
+	failed := &NpuNetInfo{}
+	tracker.Track(0, failed, now.Add(15*time.Second))
+	assert.Nil(t, failed.StatRates)
+
+	// the statistics are cleared by the driver, which is taken as a counter reset
+	reset := &NpuNetInfo{StatQueried: true, StatInfo: StatInfo{RoceRxErrPktNum: 5}}
+	tracker.Track(0, reset, now.Add(20*time.Second))
+	assert.Equal(t, 0.5, reset.StatRates[roceRxErrRate])
+
+	tracker.forget(0)
+	again := &NpuNetInfo{StatQueried: true}
+	tracker.Track(0, again, now.Add(30*time.Second))
+	assert.Nil(t, again.StatRates)
+}
+
+// TestStatCounters test the statistics are exported as counters, and as the legacy gauges when it is enabled
+func TestStatCounters(t *testing.T) {
+	defer SetLegacyStatGauges(true)
+	groups, err := ParseMetricGroups(NetworkGroup)
+	assert.Nil(t, err)
+	chip := &HuaWeiAIChip{ChipIfo: &common.ChipInfo{Name: "910"}}
+	netInfo := NpuNetInfo{StatQueried: true, StatInfo: StatInfo{RoceRxErrPktNum: 10},
+		StatRates: map[string]float64{roceRxErrRate: 1}}
+	for _, legacy := range []bool{true, false} {
+		SetLegacyStatGauges(legacy)
+		n := newNpuCollector(nil, NpuCollectorOpts{MetricGroups: groups})
+		assert.Nil(t, n.cache.Set(npuListCacheKey, []HuaWeiNPUCard{{DeviceList: []*HuaWeiAIChip{chip},
+			Timestamp: time.UnixMilli(scrapeTimestamp)}}, time.Minute))
+		assert.Nil(t, n.cache.Set(npuNetworkCacheKey, map[int32]NpuNetInfo{0: netInfo}, time.Minute))
+		expected := `
+# HELP npu_chip_roce_rx_err_packets_total the total number of bad packets received by the roce network card
+# TYPE npu_chip_roce_rx_err_packets_total counter
+npu_chip_roce_rx_err_packets_total{id="0",model_name="910--",pcie_bus_info="",vdie_id=""} 10 1000
+# HELP npu_chip_roce_rx_err_packets_per_second the per-second rate of bad packets received by the roce network card since the last query
+# TYPE npu_chip_roce_rx_err_packets_per_second gauge
+npu_chip_roce_rx_err_packets_per_second{id="0",model_name="910--",pcie_bus_info="",vdie_id=""} 1 1000
+`
+		assert.Nil(t, testutil.CollectAndCompare(n, strings.NewReader(expected),
+			"npu_chip_roce_rx_err_packets_total", roceRxErrRate))
+		legacyCount := 0
+		if legacy {
+			legacyCount = 1
+		}
+		assert.Equal(t, legacyCount, testutil.CollectAndCount(n, "npu_chip_roce_rx_err_pkt_num"))
+	}
+}
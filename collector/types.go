@@ -34,6 +34,9 @@ const (
 
 	// convert base
 	base             = 10
+	bitSize8         = 8
+	bitSize32        = 32
+	bitSize64        = 64
 	containerNameLen = 3
 	// cache key
 	npuListCacheKey = "npu-exporter-npu-list"
@@ -59,8 +62,12 @@ type HuaWeiAIChip struct {
 	HealthStatus string `json:"health_status"`
 	// the error code of the chip
 	ErrorCode int64 `json:"error_code"`
+	// all the active error codes of the chip
+	ErrorCodes []int64 `json:"error_codes"`
 	// the utilization of the chip
 	Utilization int `json:"utilization"`
+	// the hbm utilization of the chip
+	HbmUtilization int `json:"hbm_utilization"`
 	// the temperature of the chip
 	Temperature int `json:"temperature"`
 	// the work power of the chip
@@ -71,6 +78,8 @@ type HuaWeiAIChip struct {
 	AICoreCurrentFreq uint32 `json:"aicore_current_freq"`
 	// the chip physic ID
 	DeviceID int `json:"device_id"`
+	// the chip logic ID
+	LogicID int32 `json:"logic_id"`
 	// the vdie id
 	VDieID string `json:"vdie_id"`
 	// the interface status
@@ -78,13 +87,15 @@ type HuaWeiAIChip struct {
 	// NetHealthStatus chip network health status
 	NetHealthStatus string `json:"net_health_status"`
 	// DevProcessInfo chip process info
-	DevProcessInfo *common.DevProcessInfo
+	DevProcessInfo *common.DevProcessInfo `json:"dev_process_info"`
 	// PCIeBusInfo bus info
-	PCIeBusInfo string
+	PCIeBusInfo string `json:"pcie_bus_info"`
 	// BoardInfo board info of device, but not display
-	BoardInfo common.BoardInfo
+	BoardInfo common.BoardInfo `json:"board_info"`
 	// NetInfo network info of device, only support training card
-	NetInfo *NpuNetInfo
+	NetInfo *NpuNetInfo `json:"net_info"`
+	// QueryErrors the fields whose query failed, the value is the failed method of the device manager
+	QueryErrors map[string]string `json:"query_errors,omitempty"`
 }
 
 // BandwidthInfo contains network port real-time bandwidth
@@ -98,95 +109,99 @@ type BandwidthInfo struct {
 // StatInfo the statistics about packets
 type StatInfo struct {
 	// Total number of pause frames received by the MAC
-	MacRxPauseNum float64
+	MacRxPauseNum float64 `json:"mac_rx_pause_num"`
 	// Total number of pause frames sent by MAC
-	MacTxPauseNum float64
+	MacTxPauseNum float64 `json:"mac_tx_pause_num"`
 	// Total number of PFC frames received by MAC
-	MacRxPfcPktNum float64
+	MacRxPfcPktNum float64 `json:"mac_rx_pfc_pkt_num"`
 	// Total number of PFC frames sent by MAC
-	MacTxPfcPktNum float64
+	MacTxPfcPktNum float64 `json:"mac_tx_pfc_pkt_num"`
 	// Total number of bad packets received by MAC
-	MacRxBadPktNum float64
+	MacRxBadPktNum float64 `json:"mac_rx_bad_pkt_num"`
 	// Total number of bad packets sent by MAC
-	MacTxBadPktNum float64
+	MacTxBadPktNum float64 `json:"mac_tx_bad_pkt_num"`
 	// The total number of packets received by the RoCE network card
-	RoceRxAllPktNum float64
+	RoceRxAllPktNum float64 `json:"roce_rx_all_pkt_num"`
 	// The total number of packets sent by the RoCE network card
-	RoceTxAllPktNum float64
+	RoceTxAllPktNum float64 `json:"roce_tx_all_pkt_num"`
 	// The number of bad packets received by the RoCE network card
-	RoceRxErrPktNum float64
+	RoceRxErrPktNum float64 `json:"roce_rx_err_pkt_num"`
 	// The number of bad packets sent by the RoCE network card
-	RoceTxErrPktNum float64
+	RoceTxErrPktNum float64 `json:"roce_tx_err_pkt_num"`
 	// The number of CNP type packets received by the RoCE network card
-	RoceRxCnpPktNum float64
+	RoceRxCnpPktNum float64 `json:"roce_rx_cnp_pkt_num"`
 	// The number of CNP type packets sent by the RoCE network card
-	RoceTxCnpPktNum float64
+	RoceTxCnpPktNum float64 `json:"roce_tx_cnp_pkt_num"`
 	// Number of RoCE network card retry messages
-	RoceNewPktRtyNum float64
+	RoceNewPktRtyNum float64 `json:"roce_new_pkt_rty_num"`
 	// Total number of bytes of bad packets sent by MAC
-	MacTxBadOctNum float64
+	MacTxBadOctNum float64 `json:"mac_tx_bad_oct_num"`
 	// Total number of bytes of bad packets received by MAC
-	MacRxBadOctNum float64
+	MacRxBadOctNum float64 `json:"mac_rx_bad_oct_num"`
 	// The number of unexpected ACK messages received by the RoCE network card
-	RoceUnexpectedAckNum float64
+	RoceUnexpectedAckNum float64 `json:"roce_unexpected_ack_num"`
 	// The number of out-of-order packets received by the RoCE network card
-	RoceOutOfOrderNum float64
+	RoceOutOfOrderNum float64 `json:"roce_out_of_order_num"`
 	// The number of packets with domain segment verification errors received by the RoCE network card
-	RoceVerificationErrNum float64
+	RoceVerificationErrNum float64 `json:"roce_verification_err_num"`
 	// The number of messages generated by abnormal QP connection status received by the RoCE network card
-	RoceQpStatusErrNum float64
+	RoceQpStatusErrNum float64 `json:"roce_qp_status_err_num"`
 }
 
 // LinkStatInfo refers to the historical link statistics, including the times of link-up
 type LinkStatInfo struct {
 	// The times of link-up
-	LinkUPNum float64
+	LinkUPNum float64 `json:"link_up_num"`
 }
 
 // LinkSpeedInfo the transfer rate of network port
 type LinkSpeedInfo struct {
 	// The rate of network port
-	Speed float64
+	Speed float64 `json:"speed"`
 }
 
 // OpticalInfo indicates the optical module information
 type OpticalInfo struct {
 	// Optical module status, indicating whether it is in place (present)
-	OpticalState float64
+	OpticalState float64 `json:"optical_state"`
 	// Power sent by No.0 optical module
-	OpticalTxPower0 float64
+	OpticalTxPower0 float64 `json:"optical_tx_power0"`
 	// Power sent by No.1 optical module
-	OpticalTxPower1 float64
+	OpticalTxPower1 float64 `json:"optical_tx_power1"`
 	// Power sent by No.2 optical module
-	OpticalTxPower2 float64
+	OpticalTxPower2 float64 `json:"optical_tx_power2"`
 	// Power sent by No.3 optical module
-	OpticalTxPower3 float64
+	OpticalTxPower3 float64 `json:"optical_tx_power3"`
 	// Reception power of No.0 optical module
-	OpticalRxPower0 float64
+	OpticalRxPower0 float64 `json:"optical_rx_power0"`
 	// Reception power of No.1 optical module
-	OpticalRxPower1 float64
+	OpticalRxPower1 float64 `json:"optical_rx_power1"`
 	// Reception power of No.2 optical module
-	OpticalRxPower2 float64
+	OpticalRxPower2 float64 `json:"optical_rx_power2"`
 	// Reception power of No.3 optical module
-	OpticalRxPower3 float64
+	OpticalRxPower3 float64 `json:"optical_rx_power3"`
 	// Optical module voltage
-	OpticalVcc float64
+	OpticalVcc float64 `json:"optical_vcc"`
 	// Optical module temperature
-	OpticalTemp float64
+	OpticalTemp float64 `json:"optical_temp"`
 }
 
 // NpuNetInfo network info of npu
 type NpuNetInfo struct {
 	// The optical info
-	OpticalInfo OpticalInfo
+	OpticalInfo OpticalInfo `json:"optical_info"`
 	// The transfer rate of network port
-	LinkSpeedInfo LinkSpeedInfo
+	LinkSpeedInfo LinkSpeedInfo `json:"link_speed_info"`
 	// Historical link statistics of network ports
-	LinkStatInfo LinkStatInfo
+	LinkStatInfo LinkStatInfo `json:"link_stat_info"`
 	// Statistics about packets
-	StatInfo StatInfo
+	StatInfo StatInfo `json:"stat_info"`
 	// Network port real-time bandwidth
-	BandwidthInfo BandwidthInfo
+	BandwidthInfo BandwidthInfo `json:"bandwidth_info"`
+	// Whether the statistics are queried, the counters are not exported when the query failed
+	StatQueried bool `json:"-"`
+	// The per-second rates of the error statistics since the last query, keyed by the rate metric name
+	StatRates map[string]float64 `json:"-"`
 }
 
 // HuaWeiNPUCard device
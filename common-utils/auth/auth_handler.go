@@ -0,0 +1,273 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package auth provides the basic auth and bearer token auth of the http server
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"huawei.com/npu-exporter/v5/common-utils/cache"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+const (
+	// DefaultMaxFailures the default number of the failures of a client ip before it is locked
+	DefaultMaxFailures = 5
+	// DefaultLockTime the default time of locking the client ip
+	DefaultLockTime = 5 * time.Minute
+	// MinTokenLength the min length of the bearer token
+	MinTokenLength = 32
+
+	// maxCredentialFileSize the max size of the credential file, in megabytes
+	maxCredentialFileSize = 1
+	oneMegabytes          = 1024 * 1024
+	defaultCacheSize      = 1024 * 10
+	basicPrefix           = "Basic "
+	bearerPrefix          = "Bearer "
+	authenticateHeader    = `Basic realm="npu-exporter"`
+	tokenHashLength       = 64
+)
+
+// User the user of the basic auth
+type User struct {
+	Name string `json:"name"`
+	// PasswordHash the bcrypt hash of the password, which is generated by HashPassword
+	PasswordHash string `json:"passwordHash"`
+}
+
+// Credentials the users and tokens which are allowed to access the server, only the hashes are stored
+type Credentials struct {
+	Users []User `json:"users,omitempty"`
+	// Tokens the sha256 hex of the bearer tokens, which is generated by HashToken
+	Tokens []string `json:"tokens,omitempty"`
+}
+
+// HashPassword check the password policy and hash the password by bcrypt
+func HashPassword(name string, password []byte) (string, error) {
+	if name == "" || strings.Contains(name, ":") {
+		return "", errors.New("the user name is empty or contains ':'")
+	}
+	if err := utils.ValidatePassWord(name, password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash the password failed: %v", err)
+	}
+	return string(hash), nil
+}
+
+// HashToken check the token length and complexity and hash the token by sha256
+func HashToken(token []byte) (string, error) {
+	if len(token) < MinTokenLength {
+		return "", fmt.Errorf("the token should contain at least %d characters", MinTokenLength)
+	}
+	if err := utils.CheckPassWordComplexity(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(utils.GetSha256Code(token)), nil
+}
+
+// LoadCredentials load the credentials from the permission checked json file
+func LoadCredentials(path string) (*Credentials, error) {
+	realPath, err := utils.RealFileChecker(path, true, false, maxCredentialFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("check credential file failed: %v", err)
+	}
+	data, err := utils.ReadLimitBytes(realPath, maxCredentialFileSize*oneMegabytes)
+	if err != nil {
+		return nil, fmt.Errorf("read credential file failed: %v", err)
+	}
+	var credentials Credentials
+	if err = json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("unmarshal credential file failed: %v", err)
+	}
+	if err = credentials.validate(); err != nil {
+		return nil, err
+	}
+	return &credentials, nil
+}
+
+func (c *Credentials) validate() error {
+	if len(c.Users) == 0 && len(c.Tokens) == 0 {
+		return errors.New("no user or token is found in the credential file")
+	}
+	for _, user := range c.Users {
+		if user.Name == "" {
+			return errors.New("the user name is empty")
+		}
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return fmt.Errorf("the password hash of user %s is invalid: %v", user.Name, err)
+		}
+	}
+	for _, token := range c.Tokens {
+		if _, err := hex.DecodeString(token); err != nil || len(token) != tokenHashLength {
+			return errors.New("the token hash is not a sha256 hex")
+		}
+	}
+	return nil
+}
+
+// HandlerConfig the configuration of the auth handler
+type HandlerConfig struct {
+	Credentials *Credentials
+	// MaxFailures the client ip is locked after the failures, 0 means DefaultMaxFailures. The client ip is the ip of
+	// the tcp peer, the forwarded headers are not trusted
+	MaxFailures int
+	// LockTime the time of locking the client ip, the failures are counted within the lock time, 0 means
+	// DefaultLockTime
+	LockTime time.Duration
+}
+
+type authHandler struct {
+	httpHandler http.Handler
+	users       map[string][]byte
+	tokens      [][]byte
+	// dummyHash is compared when the user does not exist, so that the response time does not reveal the users
+	dummyHash   []byte
+	maxFailures int64
+	lockTime    time.Duration
+	failures    *cache.ConcurrencyLRUCache
+}
+
+// NewAuthHandler create the handler which authenticates the request by the basic auth or bearer token before it is
+// passed to the handler
+func NewAuthHandler(handler http.Handler, conf *HandlerConfig) (http.Handler, error) {
+	if conf == nil || conf.Credentials == nil {
+		return nil, errors.New("parameter error")
+	}
+	if err := conf.Credentials.validate(); err != nil {
+		return nil, err
+	}
+	h := &authHandler{
+		httpHandler: handler,
+		users:       make(map[string][]byte, len(conf.Credentials.Users)),
+		maxFailures: int64(conf.MaxFailures),
+		lockTime:    conf.LockTime,
+		failures:    cache.New(defaultCacheSize),
+	}
+	if h.maxFailures <= 0 {
+		h.maxFailures = DefaultMaxFailures
+	}
+	if h.lockTime <= 0 {
+		h.lockTime = DefaultLockTime
+	}
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte(authenticateHeader), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash the dummy password failed: %v", err)
+	}
+	h.dummyHash = dummyHash
+	for _, user := range conf.Credentials.Users {
+		h.users[user.Name] = []byte(user.PasswordHash)
+	}
+	for _, token := range conf.Credentials.Tokens {
+		h.tokens = append(h.tokens, []byte(strings.ToLower(token)))
+	}
+	return h, nil
+}
+
+// ServeHTTP implement http.Handler
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	clientIP := peerIP(req)
+	failureKey := fmt.Sprintf("auth-%s", clientIP)
+	if h.locked(failureKey) {
+		secWarnf("authentication locked: %s: %s <%3d> |%15s |%s", req.Method, req.URL.Path,
+			http.StatusTooManyRequests, clientIP, req.UserAgent())
+		http.Error(w, "429 too many authentication failures", http.StatusTooManyRequests)
+		return
+	}
+	user, err := h.authenticate(req)
+	if err != nil {
+		if _, incrErr := h.failures.INCR(failureKey, h.lockTime); incrErr != nil {
+			hwlog.RunLog.Warnf("count the authentication failures failed: %v", incrErr)
+		}
+		secWarnf("authentication failed: %s: %s <%3d> |%15s |%s |%q |%v", req.Method, req.URL.Path,
+			http.StatusUnauthorized, clientIP, req.UserAgent(), user, err)
+		w.Header().Set("WWW-Authenticate", authenticateHeader)
+		http.Error(w, "401 unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.httpHandler.ServeHTTP(w, req)
+}
+
+// peerIP get the ip of the tcp peer. The X-Forwarded-For and X-Real-Ip headers are given by the client, which can
+// not be trusted, otherwise a client escapes the lock by changing them or locks the other clients by spoofing them
+func peerIP(req *http.Request) string {
+	remoteAddr := strings.TrimSpace(req.RemoteAddr)
+	if ip, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return ip
+	}
+	return remoteAddr
+}
+
+func (h *authHandler) locked(failureKey string) bool {
+	value, err := h.failures.Get(failureKey)
+	if err != nil {
+		return false
+	}
+	failures, ok := value.(int64)
+	return ok && failures >= h.maxFailures
+}
+
+// authenticate get the user of the basic auth or bearer token, the user of the bearer token is empty
+func (h *authHandler) authenticate(req *http.Request) (string, error) {
+	header := req.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(header, basicPrefix):
+		name, password, ok := req.BasicAuth()
+		if !ok {
+			return "", errors.New("invalid basic auth")
+		}
+		hash, exist := h.users[name]
+		if !exist {
+			hash = h.dummyHash
+		}
+		if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !exist {
+			return name, errors.New("invalid user name or password")
+		}
+		return name, nil
+	case strings.HasPrefix(header, bearerPrefix):
+		hash := []byte(hex.EncodeToString(utils.GetSha256Code([]byte(strings.TrimPrefix(header, bearerPrefix)))))
+		for _, token := range h.tokens {
+			if subtle.ConstantTimeCompare(hash, token) == 1 {
+				return "", nil
+			}
+		}
+		return "", errors.New("invalid bearer token")
+	default:
+		return "", errors.New("no credential is given")
+	}
+}
+
+// secWarnf record the warning in the security logger, the run logger is used when the security logger is not
+// initialized
+func secWarnf(format string, args ...interface{}) {
+	if hwlog.SecLog != nil {
+		hwlog.SecLog.Warnf(format, args...)
+		return
+	}
+	hwlog.RunLog.Warnf(format, args...)
+}
@@ -0,0 +1,172 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package auth provides the basic auth and bearer token auth of the http server
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/smartystreets/goconvey/convey"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+const (
+	testUser     = "prometheus"
+	testPassword = "Scrape@2023"
+	testToken    = "0123456789abcdefghijABCDEFGHIJ-token"
+	// testMaxFailures the client ip is locked after the failures in the test
+	testMaxFailures = 2
+)
+
+func init() {
+	config := hwlog.LogConfig{
+		OnlyToStdout: true,
+	}
+	hwlog.InitRunLogger(&config, context.TODO())
+}
+
+func newTestCredentials() (*Credentials, error) {
+	passwordHash, err := HashPassword(testUser, []byte(testPassword))
+	if err != nil {
+		return nil, err
+	}
+	tokenHash, err := HashToken([]byte(testToken))
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{Users: []User{{Name: testUser, PasswordHash: passwordHash}}, Tokens: []string{tokenHash}},
+		nil
+}
+
+func serveWithAuth(h http.Handler, clientIP string, setAuth func(req *http.Request)) int {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = clientIP + ":12345"
+	if setAuth != nil {
+		setAuth(req)
+	}
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestHashCredentials(t *testing.T) {
+	convey.Convey("test hash the credentials", t, func() {
+		convey.Convey("the password which does not meet the policy is refused", func() {
+			_, err := HashPassword(testUser, []byte("password"))
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = HashPassword(testUser, []byte(utils.ReverseString(testUser)))
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = HashPassword("user:name", []byte(testPassword))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+		convey.Convey("the short token is refused", func() {
+			_, err := HashToken([]byte("Short-token-1"))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
+
+func TestLoadCredentials(t *testing.T) {
+	// the parents of the temp dir are writable by others, which is refused by the real file checker
+	patch := gomonkey.ApplyFunc(utils.RealFileChecker, func(path string, _, _ bool, _ int64) (string, error) {
+		return filepath.Abs(path)
+	})
+	defer patch.Reset()
+	credentials, err := newTestCredentials()
+	if err != nil {
+		t.Fatalf("create credentials failed: %v", err)
+	}
+	convey.Convey("test load the credential file", t, func() {
+		path := filepath.Join(t.TempDir(), "credentials.json")
+		convey.Convey("the hashed credentials are loaded", func() {
+			data, err := json.Marshal(credentials)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(os.WriteFile(path, data, utils.FileMode), convey.ShouldBeNil)
+			loaded, err := LoadCredentials(path)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(loaded, convey.ShouldResemble, credentials)
+		})
+		convey.Convey("the plain text password is refused", func() {
+			data := `{"users": [{"name": "prometheus", "passwordHash": "Scrape@2023"}]}`
+			convey.So(os.WriteFile(path, []byte(data), utils.FileMode), convey.ShouldBeNil)
+			_, err := LoadCredentials(path)
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
+
+func TestAuthHandler(t *testing.T) {
+	credentials, err := newTestCredentials()
+	if err != nil {
+		t.Fatalf("create credentials failed: %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+	convey.Convey("test the auth handler", t, func() {
+		h, err := NewAuthHandler(next, &HandlerConfig{Credentials: credentials, MaxFailures: testMaxFailures})
+		convey.So(err, convey.ShouldBeNil)
+		convey.Convey("the request with valid credentials is passed", func() {
+			convey.So(serveWithAuth(h, "127.0.0.1", func(req *http.Request) {
+				req.SetBasicAuth(testUser, testPassword)
+			}), convey.ShouldEqual, http.StatusOK)
+			convey.So(serveWithAuth(h, "127.0.0.1", func(req *http.Request) {
+				req.Header.Set("Authorization", bearerPrefix+testToken)
+			}), convey.ShouldEqual, http.StatusOK)
+		})
+		convey.Convey("the client ip is locked after the failures", func() {
+			convey.So(serveWithAuth(h, "127.0.0.2", nil), convey.ShouldEqual, http.StatusUnauthorized)
+			convey.So(serveWithAuth(h, "127.0.0.2", func(req *http.Request) {
+				req.SetBasicAuth(testUser, "Wrong@2023")
+			}), convey.ShouldEqual, http.StatusUnauthorized)
+			convey.So(serveWithAuth(h, "127.0.0.2", func(req *http.Request) {
+				req.SetBasicAuth(testUser, testPassword)
+			}), convey.ShouldEqual, http.StatusTooManyRequests)
+			convey.So(serveWithAuth(h, "127.0.0.3", func(req *http.Request) {
+				req.Header.Set("Authorization", bearerPrefix+testToken)
+			}), convey.ShouldEqual, http.StatusOK)
+		})
+		convey.Convey("the client is locked by its peer ip even if the forwarded headers are changed", func() {
+			for i, forwarded := range []string{"10.0.0.1", "10.0.0.2, 127.0.0.4"} {
+				convey.So(serveWithAuth(h, "127.0.0.4", func(req *http.Request) {
+					req.Header.Set("X-Forwarded-For", forwarded)
+					req.Header.Set("X-Real-Ip", fmt.Sprintf("10.0.1.%d", i))
+				}), convey.ShouldEqual, http.StatusUnauthorized)
+			}
+			convey.So(serveWithAuth(h, "127.0.0.4", func(req *http.Request) {
+				req.Header.Set("X-Forwarded-For", "10.0.0.3")
+				req.SetBasicAuth(testUser, testPassword)
+			}), convey.ShouldEqual, http.StatusTooManyRequests)
+		})
+		convey.Convey("the client which spoofs the ip of the others does not lock them", func() {
+			for i := 0; i < testMaxFailures; i++ {
+				convey.So(serveWithAuth(h, "127.0.0.5", func(req *http.Request) {
+					req.Header.Set("X-Forwarded-For", "127.0.0.6")
+				}), convey.ShouldEqual, http.StatusUnauthorized)
+			}
+			convey.So(serveWithAuth(h, "127.0.0.6", func(req *http.Request) {
+				req.SetBasicAuth(testUser, testPassword)
+			}), convey.ShouldEqual, http.StatusOK)
+		})
+	})
+}
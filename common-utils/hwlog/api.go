@@ -17,11 +17,13 @@ package hwlog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path"
+	"sync/atomic"
 )
 
 const (
@@ -39,7 +41,7 @@ type logger struct {
 	lgError    *log.Logger
 	lgCritical *log.Logger
 	lgCtrl     *LogLimiter
-	lgLevel    int
+	lgLevel    int32
 	lgMaxLine  int
 }
 
@@ -53,10 +55,26 @@ func (lg *logger) initLogWriter(w io.Writer) {
 
 func (lg *logger) setLoggerLevel(lv int) {
 	if lv < minLogLevel || lv > maxLogLevel {
-		lg.lgLevel = 0
+		atomic.StoreInt32(&lg.lgLevel, 0)
 		return
 	}
-	lg.lgLevel = lv
+	atomic.StoreInt32(&lg.lgLevel, int32(lv))
+}
+
+func (lg *logger) level() int32 {
+	return atomic.LoadInt32(&lg.lgLevel)
+}
+
+// SetLevel change the log level at runtime, the range is [-1, 3]
+func (lg *logger) SetLevel(lv int) error {
+	if lg == nil {
+		return errors.New("the logger is nil")
+	}
+	if lv < minLogLevel || lv > maxLogLevel {
+		return fmt.Errorf("the log level range is [%d, %d]", minLogLevel, maxLogLevel)
+	}
+	lg.setLoggerLevel(lv)
+	return nil
 }
 
 func (lg *logger) setLoggerMaxLine(lml int) {
@@ -129,7 +147,7 @@ func (lg *logger) Debugf(format string, args ...interface{}) {
 
 // DebugWithCtx record Debug not format
 func (lg *logger) DebugWithCtx(ctx context.Context, args ...interface{}) {
-	if lg.lgLevel > logDebugLv {
+	if lg.level() > logDebugLv {
 		return
 	}
 	if lg.validate() {
@@ -139,7 +157,7 @@ func (lg *logger) DebugWithCtx(ctx context.Context, args ...interface{}) {
 
 // DebugfWithCtx record Debug  format
 func (lg *logger) DebugfWithCtx(ctx context.Context, format string, args ...interface{}) {
-	if lg.lgLevel > logDebugLv {
+	if lg.level() > logDebugLv {
 		return
 	}
 	if lg.validate() {
@@ -159,7 +177,7 @@ func (lg *logger) Infof(format string, args ...interface{}) {
 
 // InfoWithCtx record Info not format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) InfoWithCtx(ctx context.Context, args ...interface{}) {
-	if lg.lgLevel > logInfoLv {
+	if lg.level() > logInfoLv {
 		return
 	}
 	if lg.validate() {
@@ -169,7 +187,7 @@ func (lg *logger) InfoWithCtx(ctx context.Context, args ...interface{}) {
 
 // InfofWithCtx record Info  format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) InfofWithCtx(ctx context.Context, format string, args ...interface{}) {
-	if lg.lgLevel > logInfoLv {
+	if lg.level() > logInfoLv {
 		return
 	}
 	if lg.validate() {
@@ -189,7 +207,7 @@ func (lg *logger) Warnf(format string, args ...interface{}) {
 
 // WarnWithCtx record Warn not format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) WarnWithCtx(ctx context.Context, args ...interface{}) {
-	if lg.lgLevel > logWarnLv {
+	if lg.level() > logWarnLv {
 		return
 	}
 	if lg.validate() {
@@ -199,7 +217,7 @@ func (lg *logger) WarnWithCtx(ctx context.Context, args ...interface{}) {
 
 // WarnfWithCtx record Warn  format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) WarnfWithCtx(ctx context.Context, format string, args ...interface{}) {
-	if lg.lgLevel > logWarnLv {
+	if lg.level() > logWarnLv {
 		return
 	}
 	if lg.validate() {
@@ -219,7 +237,7 @@ func (lg *logger) Errorf(format string, args ...interface{}) {
 
 // ErrorWithCtx record Error not format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) ErrorWithCtx(ctx context.Context, args ...interface{}) {
-	if lg.lgLevel > logErrorLv {
+	if lg.level() > logErrorLv {
 		return
 	}
 	if lg.validate() {
@@ -229,7 +247,7 @@ func (lg *logger) ErrorWithCtx(ctx context.Context, args ...interface{}) {
 
 // ErrorfWithCtx record Error  format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) ErrorfWithCtx(ctx context.Context, format string, args ...interface{}) {
-	if lg.lgLevel > logErrorLv {
+	if lg.level() > logErrorLv {
 		return
 	}
 	if lg.validate() {
@@ -249,7 +267,7 @@ func (lg *logger) Criticalf(format string, args ...interface{}) {
 
 // CriticalWithCtx record Critical not format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) CriticalWithCtx(ctx context.Context, args ...interface{}) {
-	if lg.lgLevel > logCriticalLv {
+	if lg.level() > logCriticalLv {
 		return
 	}
 	if lg.validate() {
@@ -259,7 +277,7 @@ func (lg *logger) CriticalWithCtx(ctx context.Context, args ...interface{}) {
 
 // CriticalfWithCtx record Critical format with context, if you have no ctx, please use the method with not ctx
 func (lg *logger) CriticalfWithCtx(ctx context.Context, format string, args ...interface{}) {
-	if lg.lgLevel > logCriticalLv {
+	if lg.level() > logCriticalLv {
 		return
 	}
 	if lg.validate() {
@@ -275,7 +293,10 @@ func (lg *logger) validate() bool {
 	return true
 }
 
-// FlushMem writes the contents of the memory to the disk
+// FlushMem writes the contents of the memory to the disk, nothing to do when the logger only writes to stdout
 func (lg *logger) FlushMem() error {
+	if lg == nil || lg.lgCtrl == nil {
+		return nil
+	}
 	return lg.lgCtrl.Flush()
 }
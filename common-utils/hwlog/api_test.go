@@ -111,3 +111,15 @@ func TestValidate(t *testing.T) {
 		})
 	})
 }
+
+func TestSetLevel(t *testing.T) {
+	convey.Convey("test change the log level at runtime", t, func() {
+		lg := new(logger)
+		convey.So(lg.setLogger(&LogConfig{OnlyToStdout: true}), convey.ShouldBeNil)
+		convey.So(lg.level(), convey.ShouldEqual, logInfoLv)
+		convey.So(lg.SetLevel(logErrorLv), convey.ShouldBeNil)
+		convey.So(lg.level(), convey.ShouldEqual, logErrorLv)
+		convey.So(lg.SetLevel(maxLogLevel+1), convey.ShouldNotBeNil)
+		convey.So(lg.level(), convey.ShouldEqual, logErrorLv)
+	})
+}
@@ -170,6 +170,27 @@ func (l *Logs) Flush() error {
 	return l.file.Sync()
 }
 
+// FileList returns the current log file and the backup log files,
+// the newer file comes first.
+func (l *Logs) FileList() ([]string, error) {
+	if l == nil {
+		return nil, fmt.Errorf("logs pointer does not exist")
+	}
+
+	var fileList []string
+	if _, err := os.Stat(l.fileName()); err == nil {
+		fileList = append(fileList, l.fileName())
+	}
+	oldFiles, err := l.oldFilesList()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range oldFiles {
+		fileList = append(fileList, filepath.Join(l.getDir(), f.fileInfo.Name()))
+	}
+	return fileList, nil
+}
+
 // maxLenth return the number of bytes of the maximum log size
 // before rotating.
 func (l *Logs) maxLenth() int64 {
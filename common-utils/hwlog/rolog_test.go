@@ -583,6 +583,30 @@ func TestRoll(t *testing.T) {
 	})
 }
 
+// TestFileList for test listing the current log file and the backup log files
+func TestFileList(t *testing.T) {
+	convey.Convey("TestFileList", t, func() {
+		dir := makeTempDir("TestFileList")
+		defer os.RemoveAll(dir)
+
+		fileName := getLogFile(dir)
+		l := &Logs{
+			FileName: fileName,
+		}
+		defer l.Close()
+
+		fileWrite([]byte("boo!"), l)
+		err := l.Roll()
+		convey.So(err, convey.ShouldBeNil)
+		fileWrite([]byte("foo!"), l)
+
+		fileList, err := l.FileList()
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(fileList), convey.ShouldEqual, fileCountTwo)
+		convey.So(fileList[0], convey.ShouldEqual, fileName)
+	})
+}
+
 // TestJson for test JSON conversion
 func TestJson(t *testing.T) {
 	convey.Convey("TestJson", t, func() {
@@ -24,6 +24,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -52,7 +53,7 @@ type limitHandler struct {
 	log           bool
 	method        string
 	limitBytes    int64
-	ipExpiredTime time.Duration
+	ipExpiredTime int64
 	ipCache       *cache.ConcurrencyLRUCache
 }
 
@@ -93,9 +94,10 @@ func (h *limitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	clientUserAgent := req.UserAgent()
 	clientIP := utils.ClientIP(req)
 	if clientIP != "" && h.ipCache != nil {
-		if !h.ipCache.SetIfNX(fmt.Sprintf("key-%s", clientIP), "v", h.ipExpiredTime) {
+		if !h.ipCache.SetIfNX(fmt.Sprintf("key-%s", clientIP), "v", h.getIPExpiredTime()) {
 			hwlog.RunLog.WarnfWithCtx(ctx, "Single IP request reject:%s: %s <%3d> |%15s |%s |%d ", req.Method,
 				path, http.StatusServiceUnavailable, clientIP, clientUserAgent, syscall.Getuid())
+			recordRejection(RejectIPRequest)
 			http.Error(w, "503 too busy", http.StatusServiceUnavailable)
 			return
 		}
@@ -131,6 +133,7 @@ func (h *limitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	default:
 		hwlog.RunLog.WarnfWithCtx(ctx, "Total reject request:%s: %s <%3d> |%15s |%s |%d ", req.Method, path,
 			http.StatusServiceUnavailable, clientIP, clientUserAgent, syscall.Getuid())
+		recordRejection(RejectTotalRequest)
 		http.Error(w, "503 too busy", http.StatusServiceUnavailable)
 	}
 }
@@ -214,7 +217,7 @@ func createHandler(ch chan struct{}, handler http.Handler, printLog bool,
 		log:           printLog,
 		method:        httpMethod,
 		limitBytes:    bodySizeLimit,
-		ipExpiredTime: time.Duration(-1),
+		ipExpiredTime: int64(time.Duration(-1)),
 	}
 	for i := 0; i < cap(ch); i++ {
 		h.concurrency <- struct{}{}
@@ -237,26 +240,62 @@ func NewLimitHandlerV2(handler http.Handler, conf *HandlerConfig) (http.Handler,
 		hwlog.RunLog.Info("use default cache size")
 		conf.CacheSize = DefaultCacheSize
 	}
-	reg := regexp.MustCompile(IPReqLimitReg)
-	if !reg.Match([]byte(conf.IPConCurrency)) {
-		return nil, errors.New("IPConCurrency parameter error")
+	ipExpiredTime, err := parseIPConCurrency(conf.IPConCurrency)
+	if err != nil {
+		return nil, err
 	}
 	conchan := make(chan struct{}, conf.TotalConCurrency)
 	h := createHandler(conchan, handler, conf.PrintLog, conf.Method, conf.LimitBytes)
-	arr := strings.Split(conf.IPConCurrency, "/")
+	h.setIPExpiredTime(ipExpiredTime)
+	h.ipCache = cache.New(DefaultCacheSize)
+	return h, nil
+
+}
+
+// ReloadableHandler the limiter whose request limit of each IP can be changed at runtime, the handler created by
+// NewLimitHandlerV2 implements it
+type ReloadableHandler interface {
+	http.Handler
+	// SetIPConCurrency change the request limit of each IP, eg: "20/1" means allow 20 requests in 1 second
+	SetIPConCurrency(ipConCurrency string) error
+}
+
+// SetIPConCurrency implement ReloadableHandler
+func (h *limitHandler) SetIPConCurrency(ipConCurrency string) error {
+	ipExpiredTime, err := parseIPConCurrency(ipConCurrency)
+	if err != nil {
+		return err
+	}
+	h.setIPExpiredTime(ipExpiredTime)
+	return nil
+}
+
+// setIPExpiredTime set the min interval of the requests of an IP, which is accessed atomically
+func (h *limitHandler) setIPExpiredTime(ipExpiredTime time.Duration) {
+	atomic.StoreInt64(&h.ipExpiredTime, int64(ipExpiredTime))
+}
+
+func (h *limitHandler) getIPExpiredTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.ipExpiredTime))
+}
+
+// parseIPConCurrency get the min interval of the requests of an IP
+func parseIPConCurrency(ipConCurrency string) (time.Duration, error) {
+	reg := regexp.MustCompile(IPReqLimitReg)
+	if !reg.Match([]byte(ipConCurrency)) {
+		return 0, errors.New("IPConCurrency parameter error")
+	}
+	arr := strings.Split(ipConCurrency, "/")
 	if len(arr) != arrLen || arr[0] == "0" {
-		return nil, errors.New("IPConCurrency parameter error")
+		return 0, errors.New("IPConCurrency parameter error")
 	}
 	arr1, err := strconv.ParseInt(arr[1], 0, 0)
 	if err != nil {
-		return nil, fmt.Errorf("IPConCurrency parameter(%s) error, parse to int failed: %v", arr[1], err)
+		return 0, fmt.Errorf("IPConCurrency parameter(%s) error, parse to int failed: %v", arr[1], err)
 	}
 	arr0, err := strconv.ParseInt(arr[0], 0, 0)
 	if err != nil || arr0 == 0 {
-		return nil, fmt.Errorf("IPConCurrency parameter(%s) error,parse to int failed: %v", arr[0], err)
+		return 0, fmt.Errorf("IPConCurrency parameter(%s) error,parse to int failed: %v", arr[0], err)
 	}
-	h.ipExpiredTime = time.Duration(arr1 * int64(time.Second) / arr0)
-	h.ipCache = cache.New(DefaultCacheSize)
-	return h, nil
-
+	return time.Duration(arr1 * int64(time.Second) / arr0), nil
 }
@@ -55,8 +55,10 @@ func TestServeHTTP(t *testing.T) {
 			if !ok {
 				return
 			}
+			rejected := Rejections()[RejectTotalRequest]
 			h.ServeHTTP(w.ResponseWriter, r)
 			convey.So(len(h.concurrency), convey.ShouldEqual, 0)
+			convey.So(Rejections()[RejectTotalRequest], convey.ShouldEqual, rejected+1)
 		})
 	})
 }
@@ -135,3 +137,22 @@ func TestNewLimitHandlerV2(t *testing.T) {
 		convey.So(err, convey.ShouldNotEqual, nil)
 	})
 }
+
+func TestSetIPConCurrency(t *testing.T) {
+	conf := &HandlerConfig{
+		LimitBytes:       DefaultDataLimit,
+		TotalConCurrency: defaultMaxConcurrency,
+		IPConCurrency:    "2/1",
+		CacheSize:        DefaultCacheSize,
+	}
+	convey.Convey("the request limit of each IP can be changed at runtime", t, func() {
+		h, err := NewLimitHandlerV2(http.DefaultServeMux, conf)
+		convey.So(err, convey.ShouldBeNil)
+		reloadable, ok := h.(ReloadableHandler)
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(reloadable.SetIPConCurrency("10/2"), convey.ShouldBeNil)
+		convey.So(h.(*limitHandler).getIPExpiredTime(), convey.ShouldEqual, 200*time.Millisecond)
+		convey.So(reloadable.SetIPConCurrency("0/1"), convey.ShouldNotBeNil)
+		convey.So(h.(*limitHandler).getIPExpiredTime(), convey.ShouldEqual, 200*time.Millisecond)
+	})
+}
@@ -84,6 +84,7 @@ func (l *localLimitListener) Accept() (net.Conn, error) {
 	if ip != "" && l.ipCache != nil {
 		if counts, err := l.ipCache.INCR(cacheKey, -1); err == nil && counts > l.ipConnLimit {
 			hwlog.RunLog.Warn("ip connections reach max limit, connection will to force closed")
+			recordRejection(RejectIPConnection)
 			return closeImmediately(c, l.ipCache), nil
 		}
 	}
@@ -92,6 +93,7 @@ func (l *localLimitListener) Accept() (net.Conn, error) {
 		return &limitListenerConn{Conn: c, release: l.release, ipCache: l.ipCache}, nil
 	}
 	hwlog.RunLog.Warn("limit forbidden, connection will to force closed")
+	recordRejection(RejectTotalConnection)
 	return closeImmediately(c, l.ipCache), nil
 
 }
@@ -0,0 +1,51 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package limiter implement a token bucket limiter
+package limiter
+
+import "sync"
+
+const (
+	// RejectTotalRequest the request is rejected because the total concurrency reaches the limit
+	RejectTotalRequest = "total_request"
+	// RejectIPRequest the request is rejected because the request rate of the single ip reaches the limit
+	RejectIPRequest = "ip_request"
+	// RejectTotalConnection the connection is closed because the total connections reach the limit
+	RejectTotalConnection = "total_connection"
+	// RejectIPConnection the connection is closed because the connections of the single ip reach the limit
+	RejectIPConnection = "ip_connection"
+)
+
+var (
+	rejections     = make(map[string]uint64)
+	rejectionsLock sync.Mutex
+)
+
+func recordRejection(reason string) {
+	rejectionsLock.Lock()
+	rejections[reason]++
+	rejectionsLock.Unlock()
+}
+
+// Rejections get the rejected times of each reason since the start
+func Rejections() map[string]uint64 {
+	rejectionsLock.Lock()
+	defer rejectionsLock.Unlock()
+	result := make(map[string]uint64, len(rejections))
+	for reason, count := range rejections {
+		result[reason] = count
+	}
+	return result
+}
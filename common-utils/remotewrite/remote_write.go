@@ -0,0 +1,335 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package remotewrite pushes the gathered metrics to a prometheus remote write endpoint, the requests which fail
+// to be pushed are buffered on the disk and replayed later
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+const (
+	// DefaultTimeout the default deadline of each push
+	DefaultTimeout = 10 * time.Second
+
+	contentType        = "application/x-protobuf"
+	contentEncoding    = "snappy"
+	remoteWriteVersion = "0.1.0"
+	userAgent          = "npu-exporter"
+	// maxErrBodyLen the max length of the response body which is logged when the push is rejected
+	maxErrBodyLen = 256
+
+	bucketSuffix  = "_bucket"
+	sumSuffix     = "_sum"
+	countSuffix   = "_count"
+	bucketLabel   = "le"
+	quantileLabel = "quantile"
+)
+
+var metricTypes = map[dto.MetricType]prompb.MetricMetadata_MetricType{
+	dto.MetricType_COUNTER:         prompb.MetricMetadata_COUNTER,
+	dto.MetricType_GAUGE:           prompb.MetricMetadata_GAUGE,
+	dto.MetricType_SUMMARY:         prompb.MetricMetadata_SUMMARY,
+	dto.MetricType_HISTOGRAM:       prompb.MetricMetadata_HISTOGRAM,
+	dto.MetricType_GAUGE_HISTOGRAM: prompb.MetricMetadata_GAUGEHISTOGRAM,
+	dto.MetricType_UNTYPED:         prompb.MetricMetadata_UNKNOWN,
+}
+
+// Options the options of the remote write
+type Options struct {
+	// URL the http or https url of the remote write endpoint
+	URL      string
+	Interval time.Duration
+	// Timeout the deadline of each push, 0 means the DefaultTimeout
+	Timeout time.Duration
+	// WALDir the directory which buffers the requests failed to be pushed, empty means to drop them
+	WALDir string
+	// WALSize the max total size of the buffered requests in bytes, the oldest ones are dropped when it is exceeded
+	WALSize int64
+}
+
+// Writer pushes the metrics of the gatherer to the remote write endpoint periodically
+type Writer struct {
+	lock     sync.Mutex
+	gatherer prometheus.Gatherer
+	opts     Options
+	client   *http.Client
+	wal      *wal
+}
+
+// recoverableError the push failed because the endpoint is unreachable or overloaded, it is worth retrying
+type recoverableError struct {
+	err error
+}
+
+func (e *recoverableError) Error() string {
+	return e.err.Error()
+}
+
+func isRecoverable(err error) bool {
+	var recoverable *recoverableError
+	return errors.As(err, &recoverable)
+}
+
+// NewWriter create the writer which pushes the metrics of the gatherer, the requests buffered in the WALDir by
+// the last run are replayed at the first push
+func NewWriter(gatherer prometheus.Gatherer, opts Options) (*Writer, error) {
+	if gatherer == nil {
+		return nil, errors.New("the gatherer is nil")
+	}
+	if err := CheckURL(opts.URL); err != nil {
+		return nil, err
+	}
+	if opts.Interval <= 0 {
+		return nil, errors.New("the push interval should be positive")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	w := &Writer{gatherer: gatherer, opts: opts, client: &http.Client{Timeout: opts.Timeout}}
+	if opts.WALDir == "" {
+		return w, nil
+	}
+	var err error
+	if w.wal, err = openWAL(opts.WALDir, opts.WALSize); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// CheckURL check the url of the remote write endpoint, only http and https are supported
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse the url failed: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("only support the http and https url")
+	}
+	if u.Host == "" {
+		return errors.New("the host of the url is empty")
+	}
+	return nil
+}
+
+// Run push the metrics every interval until the ctx is done
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Push(ctx); err != nil {
+			hwlog.RunLog.Warnf("push the metrics to the remote write endpoint failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push gather the metrics and push them. The buffered requests are replayed before, so that the samples of each
+// series arrive in order, and the request is buffered when the endpoint is unreachable or overloaded
+func (w *Writer) Push(ctx context.Context) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	body, err := w.encode(time.Now())
+	if err != nil {
+		return err
+	}
+	if err = w.replay(ctx); err == nil {
+		if err = w.send(ctx, body); err == nil {
+			return nil
+		}
+	}
+	if w.wal == nil || !isRecoverable(err) {
+		return err
+	}
+	if walErr := w.wal.append(body); walErr != nil {
+		return fmt.Errorf("%v, and buffer the request failed: %v", err, walErr)
+	}
+	return fmt.Errorf("%v, the request is buffered", err)
+}
+
+// replay push the buffered requests from the oldest one, until all of them are pushed or the endpoint is still
+// unreachable. The request which is rejected by the endpoint is dropped
+func (w *Writer) replay(ctx context.Context) error {
+	if w.wal == nil {
+		return nil
+	}
+	replayed := 0
+	defer func() {
+		if replayed > 0 {
+			hwlog.RunLog.Infof("replayed %d buffered requests, %d requests remain", replayed, w.wal.len())
+		}
+	}()
+	for w.wal.len() > 0 {
+		name, body, err := w.wal.oldest()
+		if err != nil {
+			hwlog.RunLog.Warnf("drop the unreadable buffered request %s: %v", name, err)
+			w.wal.removeOldest()
+			continue
+		}
+		if err = w.send(ctx, body); err != nil && isRecoverable(err) {
+			return err
+		}
+		if err != nil {
+			hwlog.RunLog.Warnf("drop the buffered request %s: %v", name, err)
+		}
+		w.wal.removeOldest()
+		replayed++
+	}
+	return nil
+}
+
+func (w *Writer) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Encoding", contentEncoding)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return &recoverableError{err: err}
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			hwlog.RunLog.Warnf("close the response body failed: %v", err)
+		}
+	}()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, err := io.ReadAll(io.LimitReader(resp.Body, maxErrBodyLen))
+	if err != nil {
+		hwlog.RunLog.Warnf("read the response body failed: %v", err)
+	}
+	err = fmt.Errorf("the endpoint responded %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &recoverableError{err: err}
+	}
+	return err
+}
+
+// encode gather the metrics and encode them as the snappy compressed remote write request, the samples without
+// the timestamp are stamped with now
+func (w *Writer) encode(now time.Time) ([]byte, error) {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		if len(families) == 0 {
+			return nil, fmt.Errorf("gather the metrics failed: %v", err)
+		}
+		hwlog.RunLog.Warnf("gather the metrics partially failed: %v", err)
+	}
+	data, err := toWriteRequest(families, now.UnixMilli()).Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("marshal the remote write request failed: %v", err)
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+func toWriteRequest(families []*dto.MetricFamily, timestamp int64) *prompb.WriteRequest {
+	req := &prompb.WriteRequest{Metadata: make([]prompb.MetricMetadata, 0, len(families))}
+	for _, family := range families {
+		name := family.GetName()
+		req.Metadata = append(req.Metadata, prompb.MetricMetadata{Type: metricTypes[family.GetType()],
+			MetricFamilyName: name, Help: family.GetHelp()})
+		for _, m := range family.GetMetric() {
+			ts := timestamp
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			req.Timeseries = append(req.Timeseries, toTimeSeries(name, family.GetType(), m, ts)...)
+		}
+	}
+	return req
+}
+
+// toTimeSeries convert the metric to the series, the summary and histogram are expanded as the text format does
+func toTimeSeries(name string, metricType dto.MetricType, m *dto.Metric, ts int64) []prompb.TimeSeries {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return []prompb.TimeSeries{newSeries(name, m.GetLabel(), m.GetCounter().GetValue(), ts)}
+	case dto.MetricType_GAUGE:
+		return []prompb.TimeSeries{newSeries(name, m.GetLabel(), m.GetGauge().GetValue(), ts)}
+	case dto.MetricType_SUMMARY:
+		summary := m.GetSummary()
+		series := make([]prompb.TimeSeries, 0, len(summary.GetQuantile())+2)
+		for _, q := range summary.GetQuantile() {
+			series = append(series, newSeries(name, m.GetLabel(), q.GetValue(), ts,
+				prompb.Label{Name: quantileLabel, Value: formatFloat(q.GetQuantile())}))
+		}
+		return append(series, newSeries(name+sumSuffix, m.GetLabel(), summary.GetSampleSum(), ts),
+			newSeries(name+countSuffix, m.GetLabel(), float64(summary.GetSampleCount()), ts))
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		histogram := m.GetHistogram()
+		series := make([]prompb.TimeSeries, 0, len(histogram.GetBucket())+3)
+		hasInf := false
+		for _, b := range histogram.GetBucket() {
+			hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+			series = append(series, newSeries(name+bucketSuffix, m.GetLabel(), float64(b.GetCumulativeCount()), ts,
+				prompb.Label{Name: bucketLabel, Value: formatFloat(b.GetUpperBound())}))
+		}
+		if !hasInf {
+			series = append(series, newSeries(name+bucketSuffix, m.GetLabel(), float64(histogram.GetSampleCount()),
+				ts, prompb.Label{Name: bucketLabel, Value: formatFloat(math.Inf(1))}))
+		}
+		return append(series, newSeries(name+sumSuffix, m.GetLabel(), histogram.GetSampleSum(), ts),
+			newSeries(name+countSuffix, m.GetLabel(), float64(histogram.GetSampleCount()), ts))
+	default:
+		return []prompb.TimeSeries{newSeries(name, m.GetLabel(), m.GetUntyped().GetValue(), ts)}
+	}
+}
+
+// newSeries create the series of one sample, the labels are sorted by the name as the protocol requires
+func newSeries(name string, pairs []*dto.LabelPair, value float64, ts int64,
+	extra ...prompb.Label) prompb.TimeSeries {
+	labels := make([]prompb.Label, 0, len(pairs)+len(extra)+1)
+	labels = append(labels, prompb.Label{Name: model.MetricNameLabel, Value: name})
+	for _, pair := range pairs {
+		labels = append(labels, prompb.Label{Name: pair.GetName(), Value: pair.GetValue()})
+	}
+	labels = append(labels, extra...)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return prompb.TimeSeries{Labels: labels, Samples: []prompb.Sample{{Value: value, Timestamp: ts}}}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
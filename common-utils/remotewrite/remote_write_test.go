@@ -0,0 +1,202 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package remotewrite pushes the gathered metrics to a prometheus remote write endpoint, the requests which fail
+// to be pushed are buffered on the disk and replayed later
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/smartystreets/goconvey/convey"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+func init() {
+	config := hwlog.LogConfig{
+		OnlyToStdout: true,
+	}
+	hwlog.InitRunLogger(&config, context.TODO())
+}
+
+// receiver the stand-in remote write endpoint which responds the status and records the accepted requests
+type receiver struct {
+	lock     sync.Mutex
+	status   int
+	requests []*prompb.WriteRequest
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.status != http.StatusNoContent {
+		w.WriteHeader(r.status)
+		return
+	}
+	compressed, err := io.ReadAll(req.Body)
+	if err != nil || req.Header.Get("Content-Encoding") != contentEncoding {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeReq := &prompb.WriteRequest{}
+	if err = writeReq.Unmarshal(data); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.requests = append(r.requests, writeReq)
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) setStatus(status int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.status = status
+}
+
+// counterValues get the values of the counter in the received requests
+func (r *receiver) counterValues(name string) []float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var values []float64
+	for _, req := range r.requests {
+		for _, series := range req.Timeseries {
+			if series.Labels[0].Value == name {
+				values = append(values, series.Samples[0].Value)
+			}
+		}
+	}
+	return values
+}
+
+func patchRealDirChecker() *gomonkey.Patches {
+	// the temporary directory is world writable, which is rejected by the parent check
+	return gomonkey.ApplyFunc(utils.RealDirChecker, func(path string, _, _ bool) (string, error) {
+		return path, nil
+	})
+}
+
+// TestToWriteRequest test the gathered metrics are converted to the remote write series
+func TestToWriteRequest(t *testing.T) {
+	convey.Convey("test toWriteRequest", t, func() {
+		reg := prometheus.NewRegistry()
+		gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "npu_chip_info_power", Help: "power"},
+			[]string{"model_name", "id"})
+		gauge.WithLabelValues("910", "0").Set(1)
+		histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency", Help: "latency",
+			Buckets: []float64{1}})
+		histogram.Observe(0.5)
+		reg.MustRegister(gauge, histogram)
+		families, err := reg.Gather()
+		convey.So(err, convey.ShouldBeNil)
+
+		req := toWriteRequest(families, 1000)
+		convey.So(len(req.Metadata), convey.ShouldEqual, 2)
+		convey.So(len(req.Timeseries), convey.ShouldEqual, 5)
+		power := req.Timeseries[len(req.Timeseries)-1]
+		convey.So(power.Labels, convey.ShouldResemble, []prompb.Label{{Name: "__name__", Value: "npu_chip_info_power"},
+			{Name: "id", Value: "0"}, {Name: "model_name", Value: "910"}})
+		convey.So(power.Samples, convey.ShouldResemble, []prompb.Sample{{Value: 1, Timestamp: 1000}})
+		infBucket := req.Timeseries[1]
+		convey.So(infBucket.Labels, convey.ShouldResemble, []prompb.Label{{Name: "__name__", Value: "latency_bucket"},
+			{Name: "le", Value: "+Inf"}})
+		convey.So(infBucket.Samples[0].Value, convey.ShouldEqual, 1)
+	})
+}
+
+// TestWriterPush test the requests are buffered when the endpoint is unreachable and replayed in order
+func TestWriterPush(t *testing.T) {
+	patch := patchRealDirChecker()
+	defer patch.Reset()
+	recv := &receiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "pushes_total", Help: "pushes"})
+	reg.MustRegister(counter)
+	dir := t.TempDir()
+	opts := Options{URL: server.URL, Interval: time.Second, WALDir: dir, WALSize: utils.Size10M}
+	convey.Convey("test Writer Push", t, func() {
+		w, err := NewWriter(reg, opts)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(w.Push(context.Background()), convey.ShouldNotBeNil)
+		counter.Inc()
+		convey.So(w.Push(context.Background()), convey.ShouldNotBeNil)
+		convey.So(w.wal.len(), convey.ShouldEqual, 2)
+
+		// the buffered requests are found after restart, and replayed before the current one
+		w, err = NewWriter(reg, opts)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(w.wal.len(), convey.ShouldEqual, 2)
+		recv.setStatus(http.StatusNoContent)
+		counter.Inc()
+		convey.So(w.Push(context.Background()), convey.ShouldBeNil)
+		convey.So(w.wal.len(), convey.ShouldEqual, 0)
+		convey.So(recv.counterValues("pushes_total"), convey.ShouldResemble, []float64{0, 1, 2})
+
+		// the request rejected by the endpoint is not buffered
+		recv.setStatus(http.StatusBadRequest)
+		convey.So(w.Push(context.Background()), convey.ShouldNotBeNil)
+		convey.So(w.wal.len(), convey.ShouldEqual, 0)
+	})
+}
+
+// TestWALBounded test the oldest segments are dropped when the wal exceeds the max size
+func TestWALBounded(t *testing.T) {
+	patch := patchRealDirChecker()
+	defer patch.Reset()
+	convey.Convey("test wal bounded", t, func() {
+		w, err := openWAL(t.TempDir(), 10)
+		convey.So(err, convey.ShouldBeNil)
+		for _, data := range []string{"aaaa", "bbbb", "cccc"} {
+			convey.So(w.append([]byte(data)), convey.ShouldBeNil)
+		}
+		convey.So(w.len(), convey.ShouldEqual, 2)
+		_, data, err := w.oldest()
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(string(data), convey.ShouldEqual, "bbbb")
+		_, err = openWAL(t.TempDir(), 0)
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+// TestNewWriter test the options are checked
+func TestNewWriter(t *testing.T) {
+	convey.Convey("test NewWriter", t, func() {
+		reg := prometheus.NewRegistry()
+		_, err := NewWriter(reg, Options{URL: "ftp://localhost", Interval: time.Second})
+		convey.So(err, convey.ShouldNotBeNil)
+		_, err = NewWriter(reg, Options{URL: "http://localhost"})
+		convey.So(err, convey.ShouldNotBeNil)
+		w, err := NewWriter(reg, Options{URL: "http://localhost", Interval: time.Second})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(w.client.Timeout, convey.ShouldEqual, DefaultTimeout)
+	})
+}
@@ -0,0 +1,167 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package remotewrite pushes the gathered metrics to a prometheus remote write endpoint, the requests which fail
+// to be pushed are buffered on the disk and replayed later
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+const (
+	segmentSuffix = ".snappy"
+	tmpSuffix     = ".tmp"
+	// segmentNameLen the length of the zero padded sequence, so that the names are sorted as the sequences
+	segmentNameLen = 20
+	walDirMode     = 0700
+)
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// wal buffers each request which fails to be pushed in a segment file named by its sequence. The total size is
+// bounded by dropping the oldest segments, and the segments survive restarts
+type wal struct {
+	dir      string
+	maxSize  int64
+	size     int64
+	nextSeq  uint64
+	segments []segment
+}
+
+func openWAL(dir string, maxSize int64) (*wal, error) {
+	if maxSize <= 0 {
+		return nil, errors.New("the max size of the wal should be positive")
+	}
+	if err := os.MkdirAll(dir, walDirMode); err != nil {
+		return nil, fmt.Errorf("create the wal directory failed: %v", err)
+	}
+	realDir, err := utils.RealDirChecker(dir, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("check the wal directory failed: %v", err)
+	}
+	entries, err := os.ReadDir(realDir)
+	if err != nil {
+		return nil, fmt.Errorf("read the wal directory failed: %v", err)
+	}
+	w := &wal{dir: realDir, maxSize: maxSize}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, tmpSuffix) {
+			// the segment which is not completely written before the last exit
+			w.removeFile(name)
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if !entry.Type().IsRegular() || !strings.HasSuffix(name, segmentSuffix) || err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("stat the wal segment %s failed: %v", name, err)
+		}
+		w.segments = append(w.segments, segment{seq: seq, size: info.Size()})
+		w.size += info.Size()
+	}
+	sort.Slice(w.segments, func(i, j int) bool {
+		return w.segments[i].seq < w.segments[j].seq
+	})
+	if len(w.segments) > 0 {
+		w.nextSeq = w.segments[len(w.segments)-1].seq + 1
+		hwlog.RunLog.Infof("found %d buffered requests in %s", len(w.segments), realDir)
+	}
+	w.trim()
+	return w, nil
+}
+
+func (w *wal) segmentName(seq uint64) string {
+	return fmt.Sprintf("%0*d%s", segmentNameLen, seq, segmentSuffix)
+}
+
+func (w *wal) len() int {
+	return len(w.segments)
+}
+
+// append write the request to a new segment, the temporary file is renamed so that no partial segment is replayed
+func (w *wal) append(data []byte) error {
+	name := w.segmentName(w.nextSeq)
+	tmpPath := filepath.Join(w.dir, name+tmpSuffix)
+	if err := os.WriteFile(tmpPath, data, utils.FileMode); err != nil {
+		w.removeFile(name + tmpSuffix)
+		return fmt.Errorf("write the wal segment failed: %v", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(w.dir, name)); err != nil {
+		w.removeFile(name + tmpSuffix)
+		return fmt.Errorf("rename the wal segment failed: %v", err)
+	}
+	w.segments = append(w.segments, segment{seq: w.nextSeq, size: int64(len(data))})
+	w.size += int64(len(data))
+	w.nextSeq++
+	w.trim()
+	return nil
+}
+
+// oldest read the oldest segment, the name is returned for logging
+func (w *wal) oldest() (string, []byte, error) {
+	if len(w.segments) == 0 {
+		return "", nil, errors.New("the wal is empty")
+	}
+	oldest := w.segments[0]
+	name := w.segmentName(oldest.seq)
+	if oldest.size <= 0 {
+		return name, nil, errors.New("the segment is empty")
+	}
+	data, err := utils.ReadLimitBytes(filepath.Join(w.dir, name), int(oldest.size))
+	return name, data, err
+}
+
+func (w *wal) removeOldest() {
+	if len(w.segments) == 0 {
+		return
+	}
+	oldest := w.segments[0]
+	w.removeFile(w.segmentName(oldest.seq))
+	w.segments = w.segments[1:]
+	w.size -= oldest.size
+}
+
+// trim drop the oldest segments until the total size is within the max size
+func (w *wal) trim() {
+	dropped := 0
+	for w.size > w.maxSize && len(w.segments) > 0 {
+		w.removeOldest()
+		dropped++
+	}
+	if dropped > 0 {
+		hwlog.RunLog.Warnf("the wal exceeds %d bytes, dropped the oldest %d buffered requests", w.maxSize, dropped)
+	}
+}
+
+func (w *wal) removeFile(name string) {
+	if err := os.Remove(filepath.Join(w.dir, name)); err != nil && !os.IsNotExist(err) {
+		hwlog.RunLog.Warnf("remove the wal file %s failed: %v", name, err)
+	}
+}
@@ -0,0 +1,179 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package textfile writes the gathered metrics in the prometheus text format to a file, which is exposed by the
+// textfile collector of node_exporter
+package textfile
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+const (
+	// FileSuffix the suffix of the files which are read by the textfile collector
+	FileSuffix = ".prom"
+	// fileMode the file is read by node_exporter, which may run as another user
+	fileMode  = 0644
+	tmpSuffix = ".tmp"
+)
+
+// Writer writes the metrics of the gatherer to the file, the file is replaced atomically so that node_exporter
+// never reads a partial one
+type Writer struct {
+	gatherer prometheus.Gatherer
+	dir      string
+	path     string
+}
+
+// CheckPath check the path of the file and return the one whose directory is resolved, the directory should
+// exist and pass the permission check
+func CheckPath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("the path is empty")
+	}
+	if !filepath.IsAbs(path) {
+		return "", errors.New("the path should be absolute")
+	}
+	if !strings.HasSuffix(path, FileSuffix) {
+		return "", fmt.Errorf("the file name should end with %s, otherwise it is ignored by node_exporter",
+			FileSuffix)
+	}
+	if utils.IsDir(path) {
+		return "", errors.New("the path is a directory")
+	}
+	dir, err := utils.RealDirChecker(filepath.Dir(path), true, false)
+	if err != nil {
+		return "", fmt.Errorf("check the directory failed: %v", err)
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
+
+// NewWriter create the writer of the file
+func NewWriter(gatherer prometheus.Gatherer, path string) (*Writer, error) {
+	if gatherer == nil {
+		return nil, errors.New("the gatherer is nil")
+	}
+	realPath, err := CheckPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{gatherer: gatherer, dir: filepath.Dir(realPath), path: realPath}, nil
+}
+
+// Run write the file on the interval until the ctx is done, the file is removed then so that node_exporter does
+// not expose the stale metrics. The interval is got before each wait, so the changed one takes effect at once
+func (w *Writer) Run(ctx context.Context, interval func() time.Duration) {
+	defer w.remove()
+	current := interval()
+	ticker := time.NewTicker(current)
+	defer ticker.Stop()
+	for {
+		if err := w.Write(); err != nil {
+			hwlog.RunLog.Warnf("write the metrics to %s failed: %v", w.path, err)
+		}
+		if next := interval(); next != current && next > 0 {
+			hwlog.RunLog.Infof("the interval of the textfile is changed from %v to %v", current, next)
+			current = next
+			ticker.Reset(current)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Write gather the metrics and replace the file with them. The families which are gathered are written even if
+// the others fail, as the prometheus handler does
+func (w *Writer) Write() error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		hwlog.RunLog.Warnf("gather the metrics failed: %v", err)
+	}
+	tmp, err := os.CreateTemp(w.dir, "."+filepath.Base(w.path)+".*"+tmpSuffix)
+	if err != nil {
+		return fmt.Errorf("create the temporary file failed: %v", err)
+	}
+	tmpPath := tmp.Name()
+	if err = writeFamilies(tmp, families); err != nil {
+		closeAndRemove(tmp)
+		return err
+	}
+	if err = tmp.Chmod(fileMode); err != nil {
+		closeAndRemove(tmp)
+		return fmt.Errorf("change the mode of the temporary file failed: %v", err)
+	}
+	if err = tmp.Close(); err != nil {
+		removeFile(tmpPath)
+		return fmt.Errorf("close the temporary file failed: %v", err)
+	}
+	if err = os.Rename(tmpPath, w.path); err != nil {
+		removeFile(tmpPath)
+		return fmt.Errorf("rename the temporary file failed: %v", err)
+	}
+	return nil
+}
+
+// writeFamilies write the families in the text format and sync the file. The timestamps are dropped, since the
+// textfile collector rejects the whole file which has any
+func writeFamilies(file *os.File, families []*dto.MetricFamily) error {
+	buf := bufio.NewWriter(file)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.TimestampMs = nil
+		}
+		if _, err := expfmt.MetricFamilyToText(buf, family); err != nil {
+			return fmt.Errorf("encode the metric family %s failed: %v", family.GetName(), err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("write the temporary file failed: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync the temporary file failed: %v", err)
+	}
+	return nil
+}
+
+func (w *Writer) remove() {
+	removeFile(w.path)
+}
+
+func closeAndRemove(file *os.File) {
+	if err := file.Close(); err != nil {
+		hwlog.RunLog.Warnf("close the file %s failed: %v", file.Name(), err)
+	}
+	removeFile(file.Name())
+}
+
+func removeFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		hwlog.RunLog.Warnf("remove the file %s failed: %v", path, err)
+	}
+}
@@ -0,0 +1,143 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package textfile writes the gathered metrics in the prometheus text format to a file, which is exposed by the
+// textfile collector of node_exporter
+package textfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/smartystreets/goconvey/convey"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+func init() {
+	config := hwlog.LogConfig{
+		OnlyToStdout: true,
+	}
+	hwlog.InitRunLogger(&config, context.TODO())
+}
+
+func patchRealDirChecker() *gomonkey.Patches {
+	// the temporary directory is world writable, which is rejected by the parent check
+	return gomonkey.ApplyFunc(utils.RealDirChecker, func(path string, _, _ bool) (string, error) {
+		return path, nil
+	})
+}
+
+// timestampCollector the collector whose metric has the timestamp, as the npu collector
+type timestampCollector struct {
+	desc *prometheus.Desc
+}
+
+func (c *timestampCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *timestampCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.NewMetricWithTimestamp(time.UnixMilli(1000),
+		prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1, "0"))
+}
+
+// TestWrite test the metrics are written without the timestamps and no temporary file is left
+func TestWrite(t *testing.T) {
+	patch := patchRealDirChecker()
+	defer patch.Reset()
+	convey.Convey("test Writer Write", t, func() {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(&timestampCollector{desc: prometheus.NewDesc("npu_chip_info_power", "power",
+			[]string{"id"}, nil)})
+		dir := t.TempDir()
+		path := filepath.Join(dir, "npu"+FileSuffix)
+		w, err := NewWriter(reg, path)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(w.Write(), convey.ShouldBeNil)
+		data, err := os.ReadFile(path)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(string(data), convey.ShouldEqual, "# HELP npu_chip_info_power power\n"+
+			"# TYPE npu_chip_info_power gauge\nnpu_chip_info_power{id=\"0\"} 1\n")
+		info, err := os.Stat(path)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(info.Mode().Perm(), convey.ShouldEqual, os.FileMode(fileMode))
+		entries, err := os.ReadDir(dir)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(entries), convey.ShouldEqual, 1)
+	})
+}
+
+// TestRun test the file is written at once and removed when the ctx is done
+func TestRun(t *testing.T) {
+	patch := patchRealDirChecker()
+	defer patch.Reset()
+	convey.Convey("test Writer Run", t, func() {
+		path := filepath.Join(t.TempDir(), "npu"+FileSuffix)
+		w, err := NewWriter(prometheus.NewRegistry(), path)
+		convey.So(err, convey.ShouldBeNil)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			w.Run(ctx, func() time.Duration {
+				return time.Hour
+			})
+			close(done)
+		}()
+		convey.So(waitFile(path), convey.ShouldBeTrue)
+		cancel()
+		<-done
+		convey.So(utils.IsExist(path), convey.ShouldBeFalse)
+	})
+}
+
+func waitFile(path string) bool {
+	const retry, interval = 100, 10 * time.Millisecond
+	for i := 0; i < retry; i++ {
+		if utils.IsExist(path) {
+			return true
+		}
+		time.Sleep(interval)
+	}
+	return false
+}
+
+// TestCheckPath test the path is checked
+func TestCheckPath(t *testing.T) {
+	patch := patchRealDirChecker()
+	defer patch.Reset()
+	convey.Convey("test CheckPath", t, func() {
+		const dirMode os.FileMode = 0700
+		dir := t.TempDir()
+		promDir := filepath.Join(dir, "dir"+FileSuffix)
+		convey.So(os.Mkdir(promDir, dirMode), convey.ShouldBeNil)
+		for _, path := range []string{"", "npu" + FileSuffix, filepath.Join(dir, "npu.txt"), promDir} {
+			_, err := CheckPath(path)
+			convey.So(err, convey.ShouldNotBeNil)
+		}
+		realPath, err := CheckPath(filepath.Join(dir, "npu"+FileSuffix))
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(realPath, convey.ShouldEqual, filepath.Join(dir, "npu"+FileSuffix))
+		// the world writable parent of the temporary directory is rejected
+		patch.Reset()
+		_, err = CheckPath(filepath.Join(dir, "npu"+FileSuffix))
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,254 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package tlsconfig provides the tls config of the server, whose certificates are reloaded when the files change
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+const (
+	// maxCertFileSize the max size of the certificate, key and CA files, in megabytes
+	maxCertFileSize = 1
+	oneMegabytes    = 1024 * 1024
+
+	// TLS12 the name of TLS 1.2
+	TLS12 = "1.2"
+	// TLS13 the name of TLS 1.3
+	TLS13 = "1.3"
+)
+
+// defaultCipherSuites the forward secrecy AEAD cipher suites of TLS 1.2, the suites of TLS 1.3 are not configurable
+var defaultCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Options the options of the server tls config
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile the CA which verifies the client certificates, empty means the client is not verified
+	ClientCAFile string
+	MinVersion   uint16
+	// CipherSuites the cipher suites of TLS 1.2, nil means the default forward secrecy AEAD suites
+	CipherSuites []uint16
+}
+
+// ParseMinVersion parse the min tls version, only TLS 1.2 and TLS 1.3 are supported
+func ParseMinVersion(version string) (uint16, error) {
+	switch strings.TrimSpace(version) {
+	case TLS12:
+		return tls.VersionTLS12, nil
+	case TLS13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version %s, only support %s and %s", version, TLS12, TLS13)
+	}
+}
+
+// ParseCipherSuites parse the comma separated cipher suite names, eg: TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384.
+// Only the secure suites of crypto/tls are supported, empty means the default suites
+func ParseCipherSuites(names string) ([]uint16, error) {
+	if strings.TrimSpace(names) == "" {
+		return nil, nil
+	}
+	secureSuites := make(map[string]uint16, len(tls.CipherSuites()))
+	for _, suite := range tls.CipherSuites() {
+		secureSuites[suite.Name] = suite.ID
+	}
+	var suites []uint16
+	for _, name := range strings.Split(names, ",") {
+		id, ok := secureSuites[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported or insecure cipher suite %s", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// Reloader holds the tls config of the server, the certificates are reloaded when the files change, so that the
+// renewed certificates take effect without restart
+type Reloader struct {
+	opts   Options
+	lock   sync.RWMutex
+	config *tls.Config
+}
+
+// NewReloader load the certificates and create the reloader
+func NewReloader(opts Options) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("the certificate and key files are required")
+	}
+	if opts.MinVersion < tls.VersionTLS12 {
+		opts.MinVersion = tls.VersionTLS12
+	}
+	if len(opts.CipherSuites) == 0 {
+		opts.CipherSuites = defaultCipherSuites
+	}
+	r := &Reloader{opts: opts}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig get the tls config of the server, the latest certificates are used by each handshake
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   r.opts.MinVersion,
+		CipherSuites: r.opts.CipherSuites,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.lock.RLock()
+			defer r.lock.RUnlock()
+			return r.config, nil
+		},
+	}
+}
+
+func (r *Reloader) reload() error {
+	certPEM, err := readCertFile(r.opts.CertFile)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := readCertFile(r.opts.KeyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("load the certificate and key failed: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   r.opts.MinVersion,
+		CipherSuites: r.opts.CipherSuites,
+		ClientAuth:   tls.NoClientCert,
+	}
+	if r.opts.ClientCAFile != "" {
+		caPEM, err := readCertFile(r.opts.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no valid certificate is found in the client CA file")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	r.lock.Lock()
+	r.config = config
+	r.lock.Unlock()
+	return nil
+}
+
+// readCertFile read the file after the permission check, the symlinks are allowed because the files mounted from
+// the kubernetes secret are symlinks
+func readCertFile(path string) ([]byte, error) {
+	absPath, err := utils.RealFileChecker(path, true, true, maxCertFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("check file %s failed: %v", path, err)
+	}
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve file %s failed: %v", path, err)
+	}
+	data, err := utils.ReadLimitBytes(realPath, maxCertFileSize*oneMegabytes)
+	if err != nil {
+		return nil, fmt.Errorf("read file %s failed: %v", path, err)
+	}
+	return data, nil
+}
+
+func (r *Reloader) watchedDirs() []string {
+	dirs := make(map[string]struct{}, 1)
+	for _, file := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = struct{}{}
+		}
+	}
+	watched := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		watched = append(watched, dir)
+	}
+	return watched
+}
+
+// Watch reload the certificates when the files change, until the ctx is done. The directories of the files are
+// watched, so that the files which are replaced by rename, eg: the kubernetes secret, are reloaded as well. The
+// current certificates are kept when the reload failed
+func (r *Reloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create the certificate watcher failed: %v", err)
+	}
+	for _, dir := range r.watchedDirs() {
+		if err = watcher.Add(dir); err != nil {
+			closeWatcher(watcher)
+			return fmt.Errorf("watch the certificate directory %s failed: %v", dir, err)
+		}
+	}
+	go func() {
+		defer closeWatcher(watcher)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				if err := r.reload(); err != nil {
+					hwlog.RunLog.Warnf("reload the certificates failed, keep the current ones: %v", err)
+					continue
+				}
+				hwlog.RunLog.Infof("the certificates are reloaded after %s", event)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				hwlog.RunLog.Warnf("the certificate watcher failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func closeWatcher(watcher *fsnotify.Watcher) {
+	if err := watcher.Close(); err != nil {
+		hwlog.RunLog.Warnf("close the certificate watcher failed: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package tlsconfig provides the tls config of the server, whose certificates are reloaded when the files change
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/smartystreets/goconvey/convey"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+func init() {
+	config := hwlog.LogConfig{
+		OnlyToStdout: true,
+	}
+	hwlog.InitRunLogger(&config, context.TODO())
+}
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(commonName string, parent *testCert) (*testCert, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &testCert{cert: cert, key: key, certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})}, nil
+}
+
+func writeTestCert(dir string, cert *testCert) error {
+	if err := os.WriteFile(filepath.Join(dir, "server.crt"), cert.certPEM, utils.FileMode); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "server.key"), cert.keyPEM, utils.FileMode)
+}
+
+func newTestClient(ca, client *testCert) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	if client != nil {
+		config.Certificates = []tls.Certificate{{Certificate: [][]byte{client.cert.Raw}, PrivateKey: client.key}}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: time.Second}
+}
+
+func TestParseOptions(t *testing.T) {
+	convey.Convey("test parse the tls options", t, func() {
+		convey.Convey("only TLS 1.2 and TLS 1.3 are supported", func() {
+			version, err := ParseMinVersion(TLS13)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(version, convey.ShouldEqual, tls.VersionTLS13)
+			_, err = ParseMinVersion("1.1")
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+		convey.Convey("only the secure cipher suites are supported", func() {
+			suites, err := ParseCipherSuites("TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, " +
+				"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(suites, convey.ShouldResemble, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256})
+			_, err = ParseCipherSuites("TLS_RSA_WITH_RC4_128_SHA")
+			convey.So(err, convey.ShouldNotBeNil)
+			suites, err = ParseCipherSuites("")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(suites, convey.ShouldBeNil)
+		})
+	})
+}
+
+func TestReloader(t *testing.T) {
+	// the parents of the temp dir are writable by others, which is refused by the real file checker
+	patch := gomonkey.ApplyFunc(utils.RealFileChecker, func(path string, _, _ bool, _ int64) (string, error) {
+		return filepath.Abs(path)
+	})
+	defer patch.Reset()
+	dir := t.TempDir()
+	ca, err := newTestCert("ca", nil)
+	if err != nil {
+		t.Fatalf("create ca failed: %v", err)
+	}
+	server, err := newTestCert("server", ca)
+	if err != nil {
+		t.Fatalf("create server certificate failed: %v", err)
+	}
+	client, err := newTestCert("client", ca)
+	if err != nil {
+		t.Fatalf("create client certificate failed: %v", err)
+	}
+	if err = writeTestCert(dir, server); err != nil {
+		t.Fatalf("write server certificate failed: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "ca.crt"), ca.certPEM, utils.FileMode); err != nil {
+		t.Fatalf("write ca failed: %v", err)
+	}
+	reloader, err := NewReloader(Options{CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile: filepath.Join(dir, "server.key"), ClientCAFile: filepath.Join(dir, "ca.crt")})
+	if err != nil {
+		t.Fatalf("create reloader failed: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	ts.TLS = reloader.TLSConfig()
+	ts.StartTLS()
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	convey.Convey("test the reloader of the tls config", t, func() {
+		convey.Convey("the client without certificate is refused", func() {
+			_, err := newTestClient(ca, nil).Get(ts.URL)
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+		convey.Convey("the client with certificate is accepted", func() {
+			resp, err := newTestClient(ca, client).Get(ts.URL)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(resp.TLS.PeerCertificates[0].Subject.CommonName, convey.ShouldEqual, "server")
+			convey.So(resp.Body.Close(), convey.ShouldBeNil)
+		})
+		convey.Convey("the renewed certificate is used without restart", func() {
+			convey.So(reloader.Watch(ctx), convey.ShouldBeNil)
+			renewed, err := newTestCert("renewed", ca)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(writeTestCert(dir, renewed), convey.ShouldBeNil)
+			var commonName string
+			for i := 0; i < 100 && commonName != "renewed"; i++ {
+				time.Sleep(10 * time.Millisecond)
+				// a new connection is used by each request, so that the handshake is done again
+				resp, err := newTestClient(ca, client).Get(ts.URL)
+				if err != nil {
+					continue
+				}
+				commonName = resp.TLS.PeerCertificates[0].Subject.CommonName
+				convey.So(resp.Body.Close(), convey.ShouldBeNil)
+			}
+			convey.So(commonName, convey.ShouldEqual, "renewed")
+		})
+	})
+}
@@ -23,6 +23,8 @@ type DeviceType int32
 const (
 	// AICore Ascend310 & Ascend910
 	AICore DeviceType = 2
+	// HBM Ascend910 & Ascend910B
+	HBM DeviceType = 6
 
 	// MemoryFreq Ascend310 & Ascend310P
 	MemoryFreq DeviceType = 1
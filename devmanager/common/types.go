@@ -197,3 +197,78 @@ type VDevActivityInfo struct {
 	VDevAiCore     float64
 	IsVirtualDev   bool
 }
+
+// SnapshotField the telemetry field of the chip snapshot, the fields can be combined by bitwise or
+type SnapshotField uint32
+
+const (
+	// SnapshotHealth the health code of the chip
+	SnapshotHealth SnapshotField = 1 << iota
+	// SnapshotNetworkHealth the network health code of the chip
+	SnapshotNetworkHealth
+	// SnapshotAICoreFreq the current frequency of the ai core
+	SnapshotAICoreFreq
+	// SnapshotPower the power of the chip
+	SnapshotPower
+	// SnapshotTemperature the temperature of the chip
+	SnapshotTemperature
+	// SnapshotVoltage the voltage of the chip
+	SnapshotVoltage
+	// SnapshotUtilization the utilization of the ai core
+	SnapshotUtilization
+	// SnapshotHbmUtilization the utilization of the hbm
+	SnapshotHbmUtilization
+	// SnapshotMemory the ddr memory info
+	SnapshotMemory
+	// SnapshotHbm the hbm info
+	SnapshotHbm
+	// SnapshotErrorCodes all the active error codes
+	SnapshotErrorCodes
+	// SnapshotProcess the processes on the chip
+	SnapshotProcess
+
+	// SnapshotAll all the telemetry fields
+	SnapshotAll = SnapshotProcess<<1 - 1
+)
+
+// ChipSnapshot the telemetry of a chip which is queried in one batch, the value of the failed or not requested
+// field is invalid
+type ChipSnapshot struct {
+	LogicID  int32
+	CardID   int32
+	DeviceID int32
+	PhyID    int32
+
+	Health         uint32
+	NetworkHealth  uint32
+	AICoreFreq     uint32
+	Power          float32
+	Temperature    int32
+	Voltage        float32
+	Utilization    uint32
+	HbmUtilization uint32
+	Memory         *MemoryInfo
+	Hbm            *HbmInfo
+	ErrorCodes     []int64
+	Process        *DevProcessInfo
+	// Errors the errors of the failed fields
+	Errors map[SnapshotField]error
+}
+
+// Has whether the field is one of the fields
+func (f SnapshotField) Has(field SnapshotField) bool {
+	return f&field != 0
+}
+
+// Err get the error of the field, nil means the field is queried successfully
+func (s *ChipSnapshot) Err(field SnapshotField) error {
+	return s.Errors[field]
+}
+
+// SetErr record the field is failed
+func (s *ChipSnapshot) SetErr(field SnapshotField, err error) {
+	if s.Errors == nil {
+		s.Errors = make(map[SnapshotField]error)
+	}
+	s.Errors[field] = err
+}
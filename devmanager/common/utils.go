@@ -153,3 +153,8 @@ func GetNpuName(chipInfo ChipInfo) string {
 	}
 	return fmt.Sprintf("%s-%s-%s", chipInfo.Name, chipInfo.Type, chipInfo.Version)
 }
+
+// GetErrorCodeName get the hex name of the error code, eg: 0x80E18402
+func GetErrorCodeName(errCode int64) string {
+	return fmt.Sprintf("0x%X", errCode)
+}
@@ -70,6 +70,7 @@ type DeviceInterface interface {
 	GetBoardInfo(logicID int32) (common.BoardInfo, error)
 	SetIsTrainingCard() error
 	IsTrainingCard() bool
+	GetChipSnapshot(logicID int32, fields common.SnapshotField) (*common.ChipSnapshot, error)
 }
 
 var (
@@ -108,6 +109,9 @@ type DeviceManager struct {
 	ProductTypes []string
 	// isTrainingCard whether the device is used for training
 	isTrainingCard bool
+	// chipIDs the cached ids of the chips, the key is the logic id
+	chipIDs     map[int32]chipIDs
+	chipIDsLock sync.RWMutex
 }
 
 // GetProductTypeArray return product types
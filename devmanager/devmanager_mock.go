@@ -249,3 +249,8 @@ func (d *DeviceManagerMock) SetIsTrainingCard() error {
 func (d *DeviceManagerMock) IsTrainingCard() bool {
 	return true
 }
+
+// GetChipSnapshot compose the chip snapshot with the mock methods
+func (d *DeviceManagerMock) GetChipSnapshot(logicID int32, fields common.SnapshotField) (*common.ChipSnapshot, error) {
+	return ComposeChipSnapshot(d, logicID, fields)
+}
@@ -250,3 +250,8 @@ func (d *DeviceManagerMockErr) SetIsTrainingCard() error {
 func (d *DeviceManagerMockErr) IsTrainingCard() bool {
 	return false
 }
+
+// GetChipSnapshot compose the chip snapshot with the mock methods
+func (d *DeviceManagerMockErr) GetChipSnapshot(logicID int32, fields common.SnapshotField) (*common.ChipSnapshot, error) {
+	return ComposeChipSnapshot(d, logicID, fields)
+}
@@ -0,0 +1,205 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package faultcode decode the npu error codes with the fault code knowledge base
+package faultcode
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+const (
+	// Unknown is the module and severity of the error code which is not in the knowledge base
+	Unknown = "unknown"
+
+	// maxFaultCodeFileSize the max size of the fault code file, in megabytes
+	maxFaultCodeFileSize = 10
+	hexPrefix            = "0x"
+	hexBase              = 16
+	bitSize              = 64
+)
+
+//go:embed default_fault_code.json
+var defaultFaultCodeTable []byte
+
+var (
+	decoder     *Decoder
+	decoderLock sync.RWMutex
+)
+
+// FaultCode the decoded information of an npu error code
+type FaultCode struct {
+	Code        string `json:"code" yaml:"code"`
+	Module      string `json:"module" yaml:"module"`
+	Description string `json:"description" yaml:"description"`
+	Severity    string `json:"severity" yaml:"severity"`
+	Suggestion  string `json:"suggestion" yaml:"suggestion"`
+}
+
+// FaultCodeTable the versioned fault code knowledge base
+type FaultCodeTable struct {
+	Version    string      `json:"version" yaml:"version"`
+	FaultCodes []FaultCode `json:"faultCodes" yaml:"faultCodes"`
+}
+
+// Decoder decode the npu error codes
+type Decoder struct {
+	version string
+	codes   map[int64]FaultCode
+}
+
+// NewDecoder create a decoder with the built-in knowledge base, the entries of the override file take
+// precedence over the built-in ones when the override file is given
+func NewDecoder(overrideFile string) (*Decoder, error) {
+	var table FaultCodeTable
+	if err := json.Unmarshal(defaultFaultCodeTable, &table); err != nil {
+		return nil, fmt.Errorf("unmarshal default fault code table failed: %v", err)
+	}
+	d := &Decoder{codes: make(map[int64]FaultCode, len(table.FaultCodes))}
+	if err := d.load(table); err != nil {
+		return nil, err
+	}
+	if overrideFile == "" {
+		return d, nil
+	}
+	overrideTable, err := loadFaultCodeFile(overrideFile)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.load(overrideTable); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func loadFaultCodeFile(path string) (FaultCodeTable, error) {
+	var table FaultCodeTable
+	realPath, err := utils.RealFileChecker(path, true, false, maxFaultCodeFileSize)
+	if err != nil {
+		return table, fmt.Errorf("check fault code file failed: %v", err)
+	}
+	data, err := utils.ReadLimitBytes(realPath, utils.Size10M)
+	if err != nil {
+		return table, fmt.Errorf("read fault code file failed: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(realPath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &table)
+	default:
+		err = json.Unmarshal(data, &table)
+	}
+	if err != nil {
+		return table, fmt.Errorf("unmarshal fault code file failed: %v", err)
+	}
+	return table, nil
+}
+
+// parse check the table and index its entries by the error code, the whole table is rejected when any entry is
+// invalid, so that a malformed file never replaces the built-in entries partly
+func (t FaultCodeTable) parse() (map[int64]FaultCode, error) {
+	if t.Version == "" {
+		return nil, errors.New("the version of fault code table is empty")
+	}
+	codes := make(map[int64]FaultCode, len(t.FaultCodes))
+	for _, faultCode := range t.FaultCodes {
+		code, err := ParseCode(faultCode.Code)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(faultCode.Severity) == "" {
+			return nil, fmt.Errorf("the severity of fault code %s is empty", faultCode.Code)
+		}
+		codes[code] = faultCode
+	}
+	return codes, nil
+}
+
+func (d *Decoder) load(table FaultCodeTable) error {
+	codes, err := table.parse()
+	if err != nil {
+		return err
+	}
+	for code, faultCode := range codes {
+		d.codes[code] = faultCode
+	}
+	d.version = table.Version
+	return nil
+}
+
+// Version get the version of the knowledge base
+func (d *Decoder) Version() string {
+	return d.version
+}
+
+// Decode get the information of the error code, module and severity are Unknown when it is not in the
+// knowledge base
+func (d *Decoder) Decode(code int64) FaultCode {
+	faultCode, ok := d.codes[code]
+	if !ok {
+		return FaultCode{Code: common.GetErrorCodeName(code), Module: Unknown, Severity: Unknown}
+	}
+	faultCode.Code = common.GetErrorCodeName(code)
+	return faultCode
+}
+
+// ParseCode parse the hex error code, eg: 0x80E18402 or 80E18402
+func ParseCode(code string) (int64, error) {
+	hexCode := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(code)), hexPrefix)
+	errCode, err := strconv.ParseInt(hexCode, hexBase, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fault code %s: %v", code, err)
+	}
+	return errCode, nil
+}
+
+// InitDecoder init the global decoder which is used by Decode
+func InitDecoder(overrideFile string) error {
+	d, err := NewDecoder(overrideFile)
+	if err != nil {
+		return err
+	}
+	decoderLock.Lock()
+	decoder = d
+	decoderLock.Unlock()
+	hwlog.RunLog.Infof("load fault code knowledge base successfully, version: %s", d.Version())
+	return nil
+}
+
+// Decode get the information of the error code with the global decoder, the built-in knowledge base is used when
+// the global decoder is not initialized
+func Decode(code int64) FaultCode {
+	decoderLock.RLock()
+	d := decoder
+	decoderLock.RUnlock()
+	if d == nil {
+		if err := InitDecoder(""); err != nil {
+			return FaultCode{Code: common.GetErrorCodeName(code), Module: Unknown, Severity: Unknown}
+		}
+		return Decode(code)
+	}
+	return d.Decode(code)
+}
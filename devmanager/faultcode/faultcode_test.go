@@ -0,0 +1,133 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package faultcode decode the npu error codes with the fault code knowledge base
+package faultcode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+)
+
+const (
+	fileMode    = 0600
+	hbmECCError = 0x80E01801
+	// unknownCode the code which is not in the built-in knowledge base
+	unknownCode = 0x8FFFFFFF
+)
+
+func init() {
+	config := hwlog.LogConfig{
+		OnlyToStdout: true,
+	}
+	hwlog.InitRunLogger(&config, context.TODO())
+}
+
+func writeFaultCodeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), fileMode))
+	return path
+}
+
+func patchRealFileChecker() *gomonkey.Patches {
+	// the temporary directory is world writable, which is rejected by the parent check
+	return gomonkey.ApplyFunc(utils.RealFileChecker, func(path string, _, _ bool, _ int64) (string, error) {
+		return path, nil
+	})
+}
+
+// TestParseCode test the hex error code is parsed with or without the prefix
+func TestParseCode(t *testing.T) {
+	for _, code := range []string{"0x80E01801", "0X80e01801", "80E01801", " 0x80E01801 "} {
+		errCode, err := ParseCode(code)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(hbmECCError), errCode)
+	}
+	for _, code := range []string{"", "0x", "0xZZ", "80E0-1801"} {
+		_, err := ParseCode(code)
+		assert.NotNil(t, err)
+	}
+}
+
+// TestNewDecoder test the built-in knowledge base is loaded and the unknown code is decoded as Unknown
+func TestNewDecoder(t *testing.T) {
+	d, err := NewDecoder("")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.0", d.Version())
+	faultCode := d.Decode(hbmECCError)
+	assert.Equal(t, "0x80E01801", faultCode.Code)
+	assert.Equal(t, "HBM", faultCode.Module)
+	assert.Equal(t, "SeparateNPU", faultCode.Severity)
+	assert.Equal(t, FaultCode{Code: "0x8FFFFFFF", Module: Unknown, Severity: Unknown}, d.Decode(unknownCode))
+}
+
+// TestNewDecoderWithOverride test the entries of the json and yaml override files take precedence over the
+// built-in ones, and the others are kept
+func TestNewDecoderWithOverride(t *testing.T) {
+	patch := patchRealFileChecker()
+	defer patch.Reset()
+	files := map[string]string{
+		"override.json": `{"version": "2.0-json", "faultCodes": [
+			{"code": "0x80E01801", "module": "HBM", "severity": "RestartNPU"},
+			{"code": "8FFFFFFF", "module": "Network", "severity": "NotHandleFault"}]}`,
+		"override.yaml": `version: 2.0-yaml
+faultCodes:
+  - code: "0x80E01801"
+    module: HBM
+    severity: RestartNPU
+  - code: "8FFFFFFF"
+    module: Network
+    severity: NotHandleFault
+`,
+	}
+	for name, content := range files {
+		d, err := NewDecoder(writeFaultCodeFile(t, name, content))
+		assert.Nil(t, err, name)
+		assert.Equal(t, "2.0-"+filepath.Ext(name)[1:], d.Version())
+		assert.Equal(t, "RestartNPU", d.Decode(hbmECCError).Severity, name)
+		assert.Equal(t, "Network", d.Decode(unknownCode).Module, name)
+		assert.Equal(t, "0x8FFFFFFF", d.Decode(unknownCode).Code, name)
+		// the built-in entry which is not overridden is kept
+		assert.Equal(t, "AI Core", d.Decode(0x80C98008).Module, name)
+	}
+}
+
+// TestNewDecoderWithInvalidOverride test the override file is rejected when it is malformed, has no version or
+// has an invalid entry
+func TestNewDecoderWithInvalidOverride(t *testing.T) {
+	patch := patchRealFileChecker()
+	defer patch.Reset()
+	files := map[string]string{
+		"malformed.json":     `{"version": "2.0", "faultCodes": [`,
+		"malformed.yaml":     "version: [2.0\n",
+		"no_version.json":    `{"faultCodes": [{"code": "0x80E01801", "severity": "RestartNPU"}]}`,
+		"empty_version.yaml": "version: \"\"\nfaultCodes: []\n",
+		"invalid_code.json":  `{"version": "2.0", "faultCodes": [{"code": "0xZZ", "severity": "RestartNPU"}]}`,
+		"no_severity.json":   `{"version": "2.0", "faultCodes": [{"code": "0x80E01801", "module": "HBM"}]}`,
+	}
+	for name, content := range files {
+		_, err := NewDecoder(writeFaultCodeFile(t, name, content))
+		assert.NotNil(t, err, name)
+	}
+	_, err := NewDecoder(filepath.Join(t.TempDir(), "not_exist.json"))
+	assert.NotNil(t, err)
+}
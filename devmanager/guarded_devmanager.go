@@ -0,0 +1,474 @@
+/* Copyright(C) 2021-2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package devmanager this for device driver manager
+package devmanager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+	"huawei.com/npu-exporter/v5/devmanager/dcmi"
+)
+
+const (
+	defaultCallTimeout      = 5 * time.Second
+	defaultFailureThreshold = 3
+	defaultOpenTimeout      = 30 * time.Second
+	defaultMaxOpenTimeout   = 5 * time.Minute
+	backoffMultiple         = 2
+)
+
+var (
+	// ErrCallTimeout the driver call does not return within the call timeout
+	ErrCallTimeout = errors.New("driver call timeout")
+	// ErrBreakerOpen the driver call is rejected because the circuit breaker of the chip is open
+	ErrBreakerOpen = errors.New("circuit breaker of the chip is open")
+)
+
+// BreakerState the state of the circuit breaker of a chip
+type BreakerState int
+
+const (
+	// BreakerClosed the calls of the chip are passed to the driver
+	BreakerClosed BreakerState = iota
+	// BreakerOpen the calls of the chip are rejected until the backoff expires
+	BreakerOpen
+	// BreakerHalfOpen a probe call of the chip is passed to the driver to check whether the chip recovers
+	BreakerHalfOpen
+)
+
+// String get the name of the breaker state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// GuardOpts the options of the guarded device manager, the zero values fall back to the defaults
+type GuardOpts struct {
+	// CallTimeout the deadline of each driver call
+	CallTimeout time.Duration
+	// FailureThreshold the consecutive timeouts of a chip which open its breaker
+	FailureThreshold int
+	// OpenTimeout the first backoff of the open breaker, it is doubled each time the probe call fails
+	OpenTimeout time.Duration
+	// MaxOpenTimeout the max backoff of the open breaker
+	MaxOpenTimeout time.Duration
+}
+
+func (o GuardOpts) withDefault() GuardOpts {
+	if o.CallTimeout <= 0 {
+		o.CallTimeout = defaultCallTimeout
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = defaultFailureThreshold
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = defaultOpenTimeout
+	}
+	if o.MaxOpenTimeout < o.OpenTimeout {
+		o.MaxOpenTimeout = defaultMaxOpenTimeout
+		if o.MaxOpenTimeout < o.OpenTimeout {
+			o.MaxOpenTimeout = o.OpenTimeout
+		}
+	}
+	return o
+}
+
+type chipBreaker struct {
+	state    BreakerState
+	timeouts int
+	backoff  time.Duration
+	openTill time.Time
+}
+
+// GuardedDeviceManager wraps the per-chip driver calls with a deadline and a per-chip circuit breaker, so that a
+// wedged chip does not stall the queries of the healthy chips. The calls which are not chip specific are passed
+// to the wrapped device manager directly
+type GuardedDeviceManager struct {
+	DeviceInterface
+	opts     GuardOpts
+	lock     sync.Mutex
+	breakers map[int32]*chipBreaker
+	// now the clock of the breakers, which is replaced in the tests
+	now func() time.Time
+}
+
+// NewGuardedDeviceManager wrap the device manager with the guarded call layer
+func NewGuardedDeviceManager(dmgr DeviceInterface, opts GuardOpts) *GuardedDeviceManager {
+	return &GuardedDeviceManager{
+		DeviceInterface: dmgr,
+		opts:            opts.withDefault(),
+		breakers:        make(map[int32]*chipBreaker),
+		now:             time.Now,
+	}
+}
+
+// BreakerStates get the breaker state of the chips which have been queried, the key is the logic id
+func (g *GuardedDeviceManager) BreakerStates() map[int32]BreakerState {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	states := make(map[int32]BreakerState, len(g.breakers))
+	for logicID, breaker := range g.breakers {
+		states[logicID] = breaker.state
+	}
+	return states
+}
+
+func (g *GuardedDeviceManager) allow(logicID int32) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	breaker, ok := g.breakers[logicID]
+	if !ok {
+		breaker = &chipBreaker{state: BreakerClosed, backoff: g.opts.OpenTimeout}
+		g.breakers[logicID] = breaker
+	}
+	switch breaker.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if g.now().Before(breaker.openTill) {
+			return false
+		}
+		// only one probe call is passed until it returns
+		breaker.state = BreakerHalfOpen
+		hwlog.RunLog.Infof("circuit breaker of chip(logicID %d) is half open, probe the driver", logicID)
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *GuardedDeviceManager) done(logicID int32, timeout bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	breaker, ok := g.breakers[logicID]
+	if !ok {
+		return
+	}
+	if !timeout {
+		if breaker.state != BreakerClosed {
+			hwlog.RunLog.Infof("chip(logicID %d) recovers, circuit breaker is closed", logicID)
+		}
+		breaker.state, breaker.timeouts, breaker.backoff = BreakerClosed, 0, g.opts.OpenTimeout
+		return
+	}
+	breaker.timeouts++
+	if breaker.state == BreakerClosed && breaker.timeouts < g.opts.FailureThreshold {
+		return
+	}
+	if breaker.state == BreakerHalfOpen {
+		breaker.backoff *= backoffMultiple
+		if breaker.backoff > g.opts.MaxOpenTimeout {
+			breaker.backoff = g.opts.MaxOpenTimeout
+		}
+	}
+	breaker.state = BreakerOpen
+	breaker.openTill = g.now().Add(breaker.backoff)
+	hwlog.RunLog.Warnf("chip(logicID %d) driver calls timeout %d times, circuit breaker is open for %v", logicID,
+		breaker.timeouts, breaker.backoff)
+}
+
+// guard run the driver call of the chip with the deadline, the results written by the call can be read only when
+// the returned error is not ErrCallTimeout or ErrBreakerOpen
+func (g *GuardedDeviceManager) guard(logicID int32, api string, call func() error) error {
+	if !g.allow(logicID) {
+		return fmt.Errorf("%s of chip(logicID %d) failed: %w", api, logicID, ErrBreakerOpen)
+	}
+	err := g.callWithDeadline(api, call)
+	g.done(logicID, errors.Is(err, ErrCallTimeout))
+	if errors.Is(err, ErrCallTimeout) {
+		return fmt.Errorf("%s of chip(logicID %d) failed: %w", api, logicID, err)
+	}
+	return err
+}
+
+// callWithDeadline run the call in a new goroutine and return ErrCallTimeout when it does not return in time. A cgo
+// call can not be cancelled, so the goroutine of a timed-out call is kept until the driver returns; only the open
+// breaker bounds how many of them pile up on a wedged chip, the calls which are not chip specific are not bounded
+func (g *GuardedDeviceManager) callWithDeadline(api string, call func() error) error {
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				result <- fmt.Errorf("%s panic: %v", api, err)
+			}
+		}()
+		result <- call()
+	}()
+	timer := time.NewTimer(g.opts.CallTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return ErrCallTimeout
+	}
+}
+
+// GetDeviceHealth query npu device health status
+func (g *GuardedDeviceManager) GetDeviceHealth(logicID int32) (uint32, error) {
+	var health uint32
+	if err := g.guard(logicID, "GetDeviceHealth", func() (err error) {
+		health, err = g.DeviceInterface.GetDeviceHealth(logicID)
+		return err
+	}); err != nil {
+		return common.UnRetError, err
+	}
+	return health, nil
+}
+
+// GetDeviceNetWorkHealth query npu device network health status
+func (g *GuardedDeviceManager) GetDeviceNetWorkHealth(logicID int32) (uint32, error) {
+	var health uint32
+	if err := g.guard(logicID, "GetDeviceNetWorkHealth", func() (err error) {
+		health, err = g.DeviceInterface.GetDeviceNetWorkHealth(logicID)
+		return err
+	}); err != nil {
+		return common.UnRetError, err
+	}
+	return health, nil
+}
+
+// GetDeviceUtilizationRate get npu device utilization
+func (g *GuardedDeviceManager) GetDeviceUtilizationRate(logicID int32, deviceType common.DeviceType) (uint32,
+	error) {
+	var rate uint32
+	if err := g.guard(logicID, "GetDeviceUtilizationRate", func() (err error) {
+		rate, err = g.DeviceInterface.GetDeviceUtilizationRate(logicID, deviceType)
+		return err
+	}); err != nil {
+		return common.UnRetError, err
+	}
+	return rate, nil
+}
+
+// GetDeviceTemperature get npu device temperature
+func (g *GuardedDeviceManager) GetDeviceTemperature(logicID int32) (int32, error) {
+	var temp int32
+	if err := g.guard(logicID, "GetDeviceTemperature", func() (err error) {
+		temp, err = g.DeviceInterface.GetDeviceTemperature(logicID)
+		return err
+	}); err != nil {
+		return common.RetError, err
+	}
+	return temp, nil
+}
+
+// GetDeviceVoltage get npu device voltage
+func (g *GuardedDeviceManager) GetDeviceVoltage(logicID int32) (float32, error) {
+	var voltage float32
+	if err := g.guard(logicID, "GetDeviceVoltage", func() (err error) {
+		voltage, err = g.DeviceInterface.GetDeviceVoltage(logicID)
+		return err
+	}); err != nil {
+		return common.UnRetError, err
+	}
+	return voltage, nil
+}
+
+// GetDevicePowerInfo get npu device power info
+func (g *GuardedDeviceManager) GetDevicePowerInfo(logicID int32) (float32, error) {
+	var power float32
+	if err := g.guard(logicID, "GetDevicePowerInfo", func() (err error) {
+		power, err = g.DeviceInterface.GetDevicePowerInfo(logicID)
+		return err
+	}); err != nil {
+		return common.UnRetError, err
+	}
+	return power, nil
+}
+
+// GetDeviceFrequency get npu device work frequency
+func (g *GuardedDeviceManager) GetDeviceFrequency(logicID int32, deviceType common.DeviceType) (uint32, error) {
+	var frequency uint32
+	if err := g.guard(logicID, "GetDeviceFrequency", func() (err error) {
+		frequency, err = g.DeviceInterface.GetDeviceFrequency(logicID, deviceType)
+		return err
+	}); err != nil {
+		return common.InvalidVal, err
+	}
+	return frequency, nil
+}
+
+// GetDeviceMemoryInfo get npu memory information
+func (g *GuardedDeviceManager) GetDeviceMemoryInfo(logicID int32) (*common.MemoryInfo, error) {
+	var memoryInfo *common.MemoryInfo
+	if err := g.guard(logicID, "GetDeviceMemoryInfo", func() (err error) {
+		memoryInfo, err = g.DeviceInterface.GetDeviceMemoryInfo(logicID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return memoryInfo, nil
+}
+
+// GetDeviceHbmInfo get npu HBM module memory and frequency information
+func (g *GuardedDeviceManager) GetDeviceHbmInfo(logicID int32) (*common.HbmInfo, error) {
+	var hbmInfo *common.HbmInfo
+	if err := g.guard(logicID, "GetDeviceHbmInfo", func() (err error) {
+		hbmInfo, err = g.DeviceInterface.GetDeviceHbmInfo(logicID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return hbmInfo, nil
+}
+
+// GetDeviceErrorCode get npu device error code
+func (g *GuardedDeviceManager) GetDeviceErrorCode(logicID int32) (int32, int64, error) {
+	var errCount int32
+	var errCode int64
+	if err := g.guard(logicID, "GetDeviceErrorCode", func() (err error) {
+		errCount, errCode, err = g.DeviceInterface.GetDeviceErrorCode(logicID)
+		return err
+	}); err != nil {
+		return common.RetError, common.RetError, err
+	}
+	return errCount, errCode, nil
+}
+
+// GetDeviceAllErrorCode get npu device all error code
+func (g *GuardedDeviceManager) GetDeviceAllErrorCode(logicID int32) (int32, []int64, error) {
+	var errCount int32
+	var errCodes []int64
+	if err := g.guard(logicID, "GetDeviceAllErrorCode", func() (err error) {
+		errCount, errCodes, err = g.DeviceInterface.GetDeviceAllErrorCode(logicID)
+		return err
+	}); err != nil {
+		return common.RetError, nil, err
+	}
+	return errCount, errCodes, nil
+}
+
+// GetChipInfo get npu device error code
+func (g *GuardedDeviceManager) GetChipInfo(logicID int32) (*common.ChipInfo, error) {
+	var chipInfo *common.ChipInfo
+	if err := g.guard(logicID, "GetChipInfo", func() (err error) {
+		chipInfo, err = g.DeviceInterface.GetChipInfo(logicID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return chipInfo, nil
+}
+
+// GetPhysicIDFromLogicID get device physic id from logic id
+func (g *GuardedDeviceManager) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	var physicID int32
+	if err := g.guard(logicID, "GetPhysicIDFromLogicID", func() (err error) {
+		physicID, err = g.DeviceInterface.GetPhysicIDFromLogicID(logicID)
+		return err
+	}); err != nil {
+		return common.RetError, err
+	}
+	return physicID, nil
+}
+
+// GetVirtualDeviceInfo get virtual device info
+func (g *GuardedDeviceManager) GetVirtualDeviceInfo(logicID int32) (common.VirtualDevInfo, error) {
+	var vDevInfo common.VirtualDevInfo
+	if err := g.guard(logicID, "GetVirtualDeviceInfo", func() (err error) {
+		vDevInfo, err = g.DeviceInterface.GetVirtualDeviceInfo(logicID)
+		return err
+	}); err != nil {
+		return common.VirtualDevInfo{}, err
+	}
+	return vDevInfo, nil
+}
+
+// GetDieID return die id by dcmi die type, vdie id or ndie id
+func (g *GuardedDeviceManager) GetDieID(logicID int32, dcmiDieType dcmi.DcmiDieType) (string, error) {
+	var dieID string
+	if err := g.guard(logicID, "GetDieID", func() (err error) {
+		dieID, err = g.DeviceInterface.GetDieID(logicID, dcmiDieType)
+		return err
+	}); err != nil {
+		return "", err
+	}
+	return dieID, nil
+}
+
+// GetDevProcessInfo get process and process memory in device side
+func (g *GuardedDeviceManager) GetDevProcessInfo(logicID int32) (*common.DevProcessInfo, error) {
+	var processInfo *common.DevProcessInfo
+	if err := g.guard(logicID, "GetDevProcessInfo", func() (err error) {
+		processInfo, err = g.DeviceInterface.GetDevProcessInfo(logicID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return processInfo, nil
+}
+
+// GetPCIeBusInfo pcie bus info
+func (g *GuardedDeviceManager) GetPCIeBusInfo(logicID int32) (string, error) {
+	var busInfo string
+	if err := g.guard(logicID, "GetPCIeBusInfo", func() (err error) {
+		busInfo, err = g.DeviceInterface.GetPCIeBusInfo(logicID)
+		return err
+	}); err != nil {
+		return "", err
+	}
+	return busInfo, nil
+}
+
+// GetBoardInfo return board info of device
+func (g *GuardedDeviceManager) GetBoardInfo(logicID int32) (common.BoardInfo, error) {
+	var boardInfo common.BoardInfo
+	if err := g.guard(logicID, "GetBoardInfo", func() (err error) {
+		boardInfo, err = g.DeviceInterface.GetBoardInfo(logicID)
+		return err
+	}); err != nil {
+		return common.BoardInfo{}, err
+	}
+	return boardInfo, nil
+}
+
+// GetMcuPowerInfo get mcu power info for cardID, the call is not chip specific so only the deadline is applied
+func (g *GuardedDeviceManager) GetMcuPowerInfo(cardID int32) (float32, error) {
+	var power float32
+	if err := g.callWithDeadline("GetMcuPowerInfo", func() (err error) {
+		power, err = g.DeviceInterface.GetMcuPowerInfo(cardID)
+		return err
+	}); err != nil {
+		return common.UnRetError, err
+	}
+	return power, nil
+}
+
+// GetChipSnapshot query the telemetry fields of the chip in one batch, the deadline applies to the whole batch
+func (g *GuardedDeviceManager) GetChipSnapshot(logicID int32, fields common.SnapshotField) (*common.ChipSnapshot,
+	error) {
+	var snapshot *common.ChipSnapshot
+	if err := g.guard(logicID, "GetChipSnapshot", func() (err error) {
+		snapshot, err = g.DeviceInterface.GetChipSnapshot(logicID, fields)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,212 @@
+/* Copyright(C) 2021-2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package devmanager this for device driver manager
+package devmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+const (
+	testCallTimeout = 20 * time.Millisecond
+	testThreshold   = 2
+	testTemperature = 40
+)
+
+func init() {
+	config := hwlog.LogConfig{
+		OnlyToStdout: true,
+	}
+	hwlog.InitRunLogger(&config, context.TODO())
+}
+
+// fakeDriver the driver whose calls block while the chip is wedged
+type fakeDriver struct {
+	DeviceManagerMock
+	calls   int32
+	lock    sync.Mutex
+	release chan struct{}
+}
+
+func (d *fakeDriver) wedge() {
+	d.lock.Lock()
+	d.release = make(chan struct{})
+	d.lock.Unlock()
+}
+
+func (d *fakeDriver) unwedge() {
+	d.lock.Lock()
+	if d.release != nil {
+		close(d.release)
+		d.release = nil
+	}
+	d.lock.Unlock()
+}
+
+func (d *fakeDriver) GetDeviceTemperature(_ int32) (int32, error) {
+	atomic.AddInt32(&d.calls, 1)
+	d.lock.Lock()
+	release := d.release
+	d.lock.Unlock()
+	if release != nil {
+		<-release
+	}
+	return testTemperature, nil
+}
+
+func (d *fakeDriver) callCount() int32 {
+	return atomic.LoadInt32(&d.calls)
+}
+
+// fakeClock the clock of the breakers which is moved by the tests
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	c.lock.Unlock()
+}
+
+func newTestGuard(driver DeviceInterface) (*GuardedDeviceManager, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	g := NewGuardedDeviceManager(driver, GuardOpts{CallTimeout: testCallTimeout, FailureThreshold: testThreshold,
+		OpenTimeout: time.Minute, MaxOpenTimeout: 3 * time.Minute})
+	g.now = clock.Now
+	return g, clock
+}
+
+func (g *GuardedDeviceManager) backoff(logicID int32) time.Duration {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.breakers[logicID].backoff
+}
+
+// TestBreakerOpen test the breaker is opened after the consecutive timeouts reach the threshold, and the calls
+// are rejected without reaching the driver then
+func TestBreakerOpen(t *testing.T) {
+	driver := &fakeDriver{}
+	driver.wedge()
+	defer driver.unwedge()
+	g, _ := newTestGuard(driver)
+
+	_, err := g.GetDeviceTemperature(0)
+	assert.True(t, errors.Is(err, ErrCallTimeout))
+	assert.Equal(t, BreakerClosed, g.BreakerStates()[0])
+	_, err = g.GetDeviceTemperature(0)
+	assert.True(t, errors.Is(err, ErrCallTimeout))
+	assert.Equal(t, BreakerOpen, g.BreakerStates()[0])
+
+	_, err = g.GetDeviceTemperature(0)
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+	assert.Equal(t, int32(testThreshold), driver.callCount())
+	// the breakers of the other chips are not affected
+	driver.unwedge()
+	temp, err := g.GetDeviceTemperature(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(testTemperature), temp)
+	assert.Equal(t, BreakerClosed, g.BreakerStates()[1])
+}
+
+// TestBreakerHalfOpen test only one probe call is passed after the backoff expires, the breaker is closed when the
+// probe succeeds
+func TestBreakerHalfOpen(t *testing.T) {
+	driver := &fakeDriver{}
+	driver.wedge()
+	defer driver.unwedge()
+	g, clock := newTestGuard(driver)
+	for i := 0; i < testThreshold; i++ {
+		_, err := g.GetDeviceTemperature(0)
+		assert.True(t, errors.Is(err, ErrCallTimeout))
+	}
+	clock.advance(time.Minute - time.Second)
+	_, err := g.GetDeviceTemperature(0)
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+
+	clock.advance(time.Second)
+	probe := make(chan error, 1)
+	go func() {
+		_, err := g.GetDeviceTemperature(0)
+		probe <- err
+	}()
+	for driver.callCount() != testThreshold+1 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, BreakerHalfOpen, g.BreakerStates()[0])
+	_, err = g.GetDeviceTemperature(0)
+	assert.True(t, errors.Is(err, ErrBreakerOpen))
+	driver.unwedge()
+	assert.Nil(t, <-probe)
+	assert.Equal(t, BreakerClosed, g.BreakerStates()[0])
+	assert.Equal(t, time.Minute, g.backoff(0))
+}
+
+// TestBreakerBackoff test the backoff is doubled each time the probe call fails and capped by MaxOpenTimeout
+func TestBreakerBackoff(t *testing.T) {
+	driver := &fakeDriver{}
+	driver.wedge()
+	defer driver.unwedge()
+	g, clock := newTestGuard(driver)
+	for i := 0; i < testThreshold; i++ {
+		_, err := g.GetDeviceTemperature(0)
+		assert.True(t, errors.Is(err, ErrCallTimeout))
+	}
+	assert.Equal(t, time.Minute, g.backoff(0))
+	for _, backoff := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		clock.advance(g.backoff(0))
+		_, err := g.GetDeviceTemperature(0)
+		assert.True(t, errors.Is(err, ErrCallTimeout))
+		assert.Equal(t, BreakerOpen, g.BreakerStates()[0])
+		assert.Equal(t, backoff, g.backoff(0))
+	}
+}
+
+// TestCallWithDeadline test the call which does not return in time gets ErrCallTimeout, and the panic of the call
+// is returned as the error
+func TestCallWithDeadline(t *testing.T) {
+	g, _ := newTestGuard(&DeviceManagerMock{})
+	release := make(chan struct{})
+	defer close(release)
+	err := g.callWithDeadline("blocked", func() error {
+		<-release
+		return nil
+	})
+	assert.Equal(t, ErrCallTimeout, err)
+
+	callErr := errors.New("call failed")
+	assert.Equal(t, callErr, g.callWithDeadline("failed", func() error {
+		return callErr
+	}))
+	assert.NotNil(t, g.callWithDeadline("panic", func() error {
+		panic("driver panic")
+	}))
+}
@@ -17,9 +17,11 @@ package hccn
 
 import (
 	"bytes"
+	"fmt"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 
 	"huawei.com/npu-exporter/v5/common-utils/hwlog"
 	"huawei.com/npu-exporter/v5/common-utils/utils"
@@ -45,25 +47,65 @@ const (
 
 	normalCode   = 1
 	abnormalCode = 0
+
+	commandIndex = 2
+
+	hccnTool = "/usr/local/Ascend/driver/tools/hccn_tool"
+)
+
+var (
+	execFailures     = make(map[string]uint64)
+	execFailuresLock sync.Mutex
 )
 
+// CheckTool check the hccn_tool exists and is not a symlink
+func CheckTool() error {
+	if !utils.IsFile(hccnTool) {
+		return fmt.Errorf("%s is not found", hccnTool)
+	}
+	_, err := utils.CheckPath(hccnTool)
+	return err
+}
+
 func hccnToolGetInfo(args ...string) (string, error) {
-	const hccn_tool = "/usr/local/Ascend/driver/tools/hccn_tool"
-	if _, err := utils.CheckPath(hccn_tool); err != nil {
+	if _, err := utils.CheckPath(hccnTool); err != nil {
+		recordExecFailure(args)
 		return "", err
 	}
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command(hccn_tool, args...)
+	cmd := exec.Command(hccnTool, args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	err := cmd.Run()
 	if err != nil {
+		recordExecFailure(args)
 		return "", err
 	}
 
 	return string(stdout.Bytes()), nil
 }
 
+func recordExecFailure(args []string) {
+	command := "unknown"
+	if len(args) > commandIndex {
+		command = strings.TrimPrefix(args[commandIndex], "-")
+	}
+	execFailuresLock.Lock()
+	execFailures[command]++
+	execFailuresLock.Unlock()
+}
+
+// ExecFailures get the failed times of each hccn_tool command since the start, eg: link, optical
+func ExecFailures() map[string]uint64 {
+	execFailuresLock.Lock()
+	defer execFailuresLock.Unlock()
+	failures := make(map[string]uint64, len(execFailures))
+	for command, count := range execFailures {
+		failures[command] = count
+	}
+	return failures
+}
+
 // GetNPULinkStatus exec "hccn_tool -i * -link -g" to get link status
 func GetNPULinkStatus(phyID int32) string {
 	args := []string{"-i", strconv.Itoa(int(phyID)), "-link", "-g"}
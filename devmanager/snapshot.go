@@ -0,0 +1,277 @@
+/* Copyright(C) 2021-2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package devmanager this for device driver manager
+package devmanager
+
+import (
+	"fmt"
+	"math/bits"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// chipIDs the ids of a chip which are resolved from the logic id
+type chipIDs struct {
+	cardID   int32
+	deviceID int32
+	phyID    int32
+}
+
+func (d *DeviceManager) getChipIDs(logicID int32) (chipIDs, error) {
+	d.chipIDsLock.RLock()
+	ids, ok := d.chipIDs[logicID]
+	d.chipIDsLock.RUnlock()
+	if ok {
+		return ids, nil
+	}
+	cardID, deviceID, err := d.DcMgr.DcGetCardIDDeviceID(logicID)
+	if err != nil {
+		return chipIDs{}, fmt.Errorf("failed to get cardID and deviceID by logicID(%d): %v", logicID, err)
+	}
+	phyID, err := d.DcMgr.DcGetPhysicIDFromLogicID(logicID)
+	if err != nil {
+		return chipIDs{}, fmt.Errorf("failed to get physicID by logicID(%d): %v", logicID, err)
+	}
+	ids = chipIDs{cardID: cardID, deviceID: deviceID, phyID: phyID}
+	d.chipIDsLock.Lock()
+	if d.chipIDs == nil {
+		d.chipIDs = make(map[int32]chipIDs)
+	}
+	d.chipIDs[logicID] = ids
+	d.chipIDsLock.Unlock()
+	return ids, nil
+}
+
+// forgetChipIDs the ids are resolved again by the next snapshot, in case the chip is reset or replaced
+func (d *DeviceManager) forgetChipIDs(logicID int32) {
+	d.chipIDsLock.Lock()
+	delete(d.chipIDs, logicID)
+	d.chipIDsLock.Unlock()
+}
+
+// GetChipSnapshot query the telemetry fields of the chip in one batch, the ids of the chip are resolved once and
+// cached until a card-level error. The error is returned only when the ids cannot be resolved, the failed fields
+// are recorded in the snapshot
+func (d *DeviceManager) GetChipSnapshot(logicID int32, fields common.SnapshotField) (*common.ChipSnapshot, error) {
+	ids, err := d.getChipIDs(logicID)
+	if err != nil {
+		hwlog.RunLog.Error(err)
+		return nil, err
+	}
+	s := &common.ChipSnapshot{LogicID: logicID, CardID: ids.cardID, DeviceID: ids.deviceID, PhyID: ids.phyID}
+	d.snapshotStatus(s, fields)
+	d.snapshotPower(s, fields)
+	d.snapshotUtilization(s, fields)
+	d.snapshotMemory(s, fields)
+	if fields.Has(common.SnapshotErrorCodes) {
+		var errCodes []int64
+		_, errCodes, err = d.DcMgr.DcGetDeviceAllErrorCode(ids.cardID, ids.deviceID)
+		s.ErrorCodes = errCodes
+		setSnapshotErr(s, common.SnapshotErrorCodes, "error code", err)
+	}
+	if fields.Has(common.SnapshotProcess) {
+		s.Process, err = d.DcMgr.DcGetDevProcessInfo(ids.cardID, ids.deviceID)
+		setSnapshotErr(s, common.SnapshotProcess, "process info", err)
+	}
+	queried := fields
+	if d.DevType == common.Ascend910B {
+		queried &^= common.SnapshotMemory
+	}
+	if isCardLevelErr(s, queried) {
+		d.forgetChipIDs(logicID)
+	}
+	return s, nil
+}
+
+// isCardLevelErr all the queried fields fail, which happens when the cached ids are stale, for example the chip is
+// reset. A single field may fail on every query, such as the process info on the SoCs which do not support it, so
+// it is kept in the errors of the snapshot only
+func isCardLevelErr(s *common.ChipSnapshot, queried common.SnapshotField) bool {
+	count := bits.OnesCount32(uint32(queried & common.SnapshotAll))
+	return count > 0 && len(s.Errors) == count
+}
+
+func (d *DeviceManager) snapshotStatus(s *common.ChipSnapshot, fields common.SnapshotField) {
+	if fields.Has(common.SnapshotHealth) {
+		health, err := d.DcMgr.DcGetDeviceHealth(s.CardID, s.DeviceID)
+		s.Health = uint32(health)
+		if setSnapshotErr(s, common.SnapshotHealth, "health code", err) {
+			s.Health = common.UnRetError
+		}
+	}
+	if fields.Has(common.SnapshotNetworkHealth) {
+		health, err := d.DcMgr.DcGetDeviceNetWorkHealth(s.CardID, s.DeviceID)
+		s.NetworkHealth = health
+		if setSnapshotErr(s, common.SnapshotNetworkHealth, "network health code", err) {
+			s.NetworkHealth = common.UnRetError
+		}
+	}
+	if fields.Has(common.SnapshotTemperature) {
+		temp, err := d.DcMgr.DcGetDeviceTemperature(s.CardID, s.DeviceID)
+		s.Temperature = temp
+		if setSnapshotErr(s, common.SnapshotTemperature, "temperature", err) {
+			s.Temperature = common.RetError
+		}
+	}
+}
+
+func (d *DeviceManager) snapshotPower(s *common.ChipSnapshot, fields common.SnapshotField) {
+	if fields.Has(common.SnapshotAICoreFreq) {
+		freq, err := d.DcMgr.DcGetDeviceFrequency(s.CardID, s.DeviceID, common.AICoreCurrentFreq)
+		s.AICoreFreq = freq
+		if setSnapshotErr(s, common.SnapshotAICoreFreq, "frequency", err) {
+			s.AICoreFreq = common.InvalidVal
+		}
+	}
+	if fields.Has(common.SnapshotPower) {
+		power, err := d.DcMgr.DcGetDevicePowerInfo(s.CardID, s.DeviceID)
+		s.Power = power
+		if setSnapshotErr(s, common.SnapshotPower, "power", err) {
+			s.Power = common.UnRetError
+		}
+	}
+	if fields.Has(common.SnapshotVoltage) {
+		voltage, err := d.DcMgr.DcGetDeviceVoltage(s.CardID, s.DeviceID)
+		s.Voltage = voltage
+		if setSnapshotErr(s, common.SnapshotVoltage, "voltage", err) {
+			s.Voltage = common.UnRetError
+		}
+	}
+}
+
+func (d *DeviceManager) snapshotUtilization(s *common.ChipSnapshot, fields common.SnapshotField) {
+	if fields.Has(common.SnapshotUtilization) {
+		rate, err := d.DcMgr.DcGetDeviceUtilizationRate(s.CardID, s.DeviceID, common.AICore)
+		s.Utilization = uint32(rate)
+		if setSnapshotErr(s, common.SnapshotUtilization, "utilization", err) {
+			s.Utilization = common.UnRetError
+		}
+	}
+	if fields.Has(common.SnapshotHbmUtilization) {
+		rate, err := d.DcMgr.DcGetDeviceUtilizationRate(s.CardID, s.DeviceID, common.HBM)
+		s.HbmUtilization = uint32(rate)
+		if setSnapshotErr(s, common.SnapshotHbmUtilization, "hbm utilization", err) {
+			s.HbmUtilization = common.UnRetError
+		}
+	}
+}
+
+func (d *DeviceManager) snapshotMemory(s *common.ChipSnapshot, fields common.SnapshotField) {
+	var err error
+	if fields.Has(common.SnapshotMemory) {
+		// 910B does not support query info of DDR
+		if d.DevType == common.Ascend910B {
+			s.Memory = &common.MemoryInfo{}
+		} else {
+			s.Memory, err = d.DcMgr.DcGetMemoryInfo(s.CardID, s.DeviceID)
+			setSnapshotErr(s, common.SnapshotMemory, "memory info", err)
+		}
+	}
+	if fields.Has(common.SnapshotHbm) {
+		s.Hbm, err = d.DcMgr.DcGetHbmInfo(s.CardID, s.DeviceID)
+		setSnapshotErr(s, common.SnapshotHbm, "hbm info", err)
+	}
+}
+
+// setSnapshotErr record the error of the field when the query fails, return whether the query fails
+func setSnapshotErr(s *common.ChipSnapshot, field common.SnapshotField, name string, err error) bool {
+	if err == nil {
+		return false
+	}
+	hwlog.RunLog.Error(err)
+	s.SetErr(field, fmt.Errorf("failed to get %s by logicID(%d)", name, s.LogicID))
+	return true
+}
+
+// ComposeChipSnapshot build the chip snapshot with the single-field methods of the device manager, it is used by
+// the device managers which do not query the chip in batch, such as the mocks
+func ComposeChipSnapshot(dmgr DeviceInterface, logicID int32,
+	fields common.SnapshotField) (*common.ChipSnapshot, error) {
+	cardID, deviceID, err := dmgr.GetCardIDDeviceID(logicID)
+	if err != nil {
+		return nil, err
+	}
+	phyID, err := dmgr.GetPhysicIDFromLogicID(logicID)
+	if err != nil {
+		return nil, err
+	}
+	s := &common.ChipSnapshot{LogicID: logicID, CardID: cardID, DeviceID: deviceID, PhyID: phyID}
+	composeStatus(dmgr, s, fields)
+	composeUtilization(dmgr, s, fields)
+	if fields.Has(common.SnapshotMemory) {
+		s.Memory, err = dmgr.GetDeviceMemoryInfo(logicID)
+		setComposedErr(s, common.SnapshotMemory, err)
+	}
+	if fields.Has(common.SnapshotHbm) {
+		s.Hbm, err = dmgr.GetDeviceHbmInfo(logicID)
+		setComposedErr(s, common.SnapshotHbm, err)
+	}
+	if fields.Has(common.SnapshotErrorCodes) {
+		_, s.ErrorCodes, err = dmgr.GetDeviceAllErrorCode(logicID)
+		setComposedErr(s, common.SnapshotErrorCodes, err)
+	}
+	if fields.Has(common.SnapshotProcess) {
+		s.Process, err = dmgr.GetDevProcessInfo(logicID)
+		setComposedErr(s, common.SnapshotProcess, err)
+	}
+	return s, nil
+}
+
+func composeStatus(dmgr DeviceInterface, s *common.ChipSnapshot, fields common.SnapshotField) {
+	var err error
+	if fields.Has(common.SnapshotHealth) {
+		s.Health, err = dmgr.GetDeviceHealth(s.LogicID)
+		setComposedErr(s, common.SnapshotHealth, err)
+	}
+	if fields.Has(common.SnapshotNetworkHealth) {
+		s.NetworkHealth, err = dmgr.GetDeviceNetWorkHealth(s.LogicID)
+		setComposedErr(s, common.SnapshotNetworkHealth, err)
+	}
+	if fields.Has(common.SnapshotTemperature) {
+		s.Temperature, err = dmgr.GetDeviceTemperature(s.LogicID)
+		setComposedErr(s, common.SnapshotTemperature, err)
+	}
+	if fields.Has(common.SnapshotAICoreFreq) {
+		s.AICoreFreq, err = dmgr.GetDeviceFrequency(s.LogicID, common.AICoreCurrentFreq)
+		setComposedErr(s, common.SnapshotAICoreFreq, err)
+	}
+	if fields.Has(common.SnapshotPower) {
+		s.Power, err = dmgr.GetDevicePowerInfo(s.LogicID)
+		setComposedErr(s, common.SnapshotPower, err)
+	}
+	if fields.Has(common.SnapshotVoltage) {
+		s.Voltage, err = dmgr.GetDeviceVoltage(s.LogicID)
+		setComposedErr(s, common.SnapshotVoltage, err)
+	}
+}
+
+func composeUtilization(dmgr DeviceInterface, s *common.ChipSnapshot, fields common.SnapshotField) {
+	var err error
+	if fields.Has(common.SnapshotUtilization) {
+		s.Utilization, err = dmgr.GetDeviceUtilizationRate(s.LogicID, common.AICore)
+		setComposedErr(s, common.SnapshotUtilization, err)
+	}
+	if fields.Has(common.SnapshotHbmUtilization) {
+		s.HbmUtilization, err = dmgr.GetDeviceUtilizationRate(s.LogicID, common.HBM)
+		setComposedErr(s, common.SnapshotHbmUtilization, err)
+	}
+}
+
+func setComposedErr(s *common.ChipSnapshot, field common.SnapshotField, err error) {
+	if err != nil {
+		s.SetErr(field, err)
+	}
+}
@@ -0,0 +1,136 @@
+/* Copyright(C) 2021-2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package devmanager this for device driver manager
+package devmanager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"huawei.com/npu-exporter/v5/devmanager/common"
+	"huawei.com/npu-exporter/v5/devmanager/dcmi"
+)
+
+const (
+	testCardID   = 3
+	testDeviceID = 1
+	testPhyID    = 5
+	testHealth   = 1
+)
+
+// fakeDcManager the dcmi driver whose queries of the chip fail when they are set in failed, only the queries of
+// the snapshot are implemented
+type fakeDcManager struct {
+	dcmi.DcDriverInterface
+	idQueries int
+	failed    common.SnapshotField
+	idErr     error
+}
+
+func (d *fakeDcManager) DcGetCardIDDeviceID(_ int32) (int32, int32, error) {
+	d.idQueries++
+	if d.idErr != nil {
+		return 0, 0, d.idErr
+	}
+	return testCardID, testDeviceID, nil
+}
+
+func (d *fakeDcManager) DcGetPhysicIDFromLogicID(_ int32) (int32, error) {
+	return testPhyID, nil
+}
+
+func (d *fakeDcManager) queryErr(field common.SnapshotField) error {
+	if d.failed.Has(field) {
+		return errors.New("dcmi query failed")
+	}
+	return nil
+}
+
+func (d *fakeDcManager) DcGetDeviceHealth(_, _ int32) (int32, error) {
+	return testHealth, d.queryErr(common.SnapshotHealth)
+}
+
+func (d *fakeDcManager) DcGetDeviceTemperature(_, _ int32) (int32, error) {
+	return testTemperature, d.queryErr(common.SnapshotTemperature)
+}
+
+func (d *fakeDcManager) DcGetDevProcessInfo(_, _ int32) (*common.DevProcessInfo, error) {
+	if err := d.queryErr(common.SnapshotProcess); err != nil {
+		return nil, err
+	}
+	return &common.DevProcessInfo{}, nil
+}
+
+const testFields = common.SnapshotHealth | common.SnapshotTemperature | common.SnapshotProcess
+
+// TestGetChipSnapshot test the ids of the chip are resolved once and cached, and the failed fields are recorded
+// in the snapshot
+func TestGetChipSnapshot(t *testing.T) {
+	dc := &fakeDcManager{failed: common.SnapshotProcess}
+	d := &DeviceManager{DcMgr: dc}
+	for i := 0; i < 3; i++ {
+		s, err := d.GetChipSnapshot(0, testFields)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(testCardID), s.CardID)
+		assert.Equal(t, int32(testDeviceID), s.DeviceID)
+		assert.Equal(t, int32(testPhyID), s.PhyID)
+		assert.Equal(t, uint32(testHealth), s.Health)
+		assert.Equal(t, int32(testTemperature), s.Temperature)
+		assert.Nil(t, s.Process)
+		assert.Len(t, s.Errors, 1)
+		assert.NotNil(t, s.Err(common.SnapshotProcess))
+		assert.Nil(t, s.Err(common.SnapshotHealth))
+	}
+	// the field which fails on every query does not evict the cached ids
+	assert.Equal(t, 1, dc.idQueries)
+
+	dc.failed = common.SnapshotHealth
+	s, err := d.GetChipSnapshot(0, testFields)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(common.UnRetError), s.Health)
+	assert.NotNil(t, s.Process)
+	assert.Equal(t, 1, dc.idQueries)
+}
+
+// TestGetChipSnapshotEvict test the cached ids are evicted only on the card-level error, and the ids which can not
+// be resolved are not cached
+func TestGetChipSnapshotEvict(t *testing.T) {
+	dc := &fakeDcManager{failed: testFields}
+	d := &DeviceManager{DcMgr: dc}
+	s, err := d.GetChipSnapshot(0, testFields)
+	assert.Nil(t, err)
+	assert.Len(t, s.Errors, 3)
+	_, err = d.GetChipSnapshot(0, testFields)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, dc.idQueries)
+
+	dc.failed = 0
+	dc.idErr = errors.New("the chip is resetting")
+	_, err = d.GetChipSnapshot(0, testFields)
+	assert.NotNil(t, err)
+	_, err = d.GetChipSnapshot(0, testFields)
+	assert.NotNil(t, err)
+	assert.Equal(t, 4, dc.idQueries)
+
+	dc.idErr = nil
+	s, err = d.GetChipSnapshot(0, testFields)
+	assert.Nil(t, err)
+	assert.Empty(t, s.Errors)
+	_, err = d.GetChipSnapshot(0, testFields)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, dc.idQueries)
+}